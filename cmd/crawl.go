@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/handler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/warc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var errMissingURI = errors.New("the --uri flag is required")
+
+type crawlFlags struct {
+	uri         string
+	depth       uint
+	profile     string
+	refresh     bool
+	incremental bool
+	warc        bool
+	rules       string
+	export      string
+}
+
+func newCrawlCmd() *cobra.Command {
+	flags := crawlFlags{}
+	command := &cobra.Command{
+		Use:   "crawl",
+		Short: "A command to crawl a URI and print the links found",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if flags.uri == "" {
+				return errMissingURI
+			}
+			if flags.export != "" && flags.export != crawler.CSVExport && flags.export != crawler.JSONLExport {
+				return crawler.ErrUnknownExportFormat
+			}
+
+			return runCrawl(cmd, flags)
+		},
+	}
+
+	command.Flags().StringVar(&flags.uri, "uri", "", "URI to be crawled")
+	command.Flags().UintVar(&flags.depth, "depth", 1, "depth used to limit the pages fetched")
+	command.Flags().StringVar(&flags.profile, "profile", "", "authentication profile used to crawl")
+	command.Flags().BoolVar(&flags.refresh, "refresh", false, "crawl again ignoring the stored result")
+	command.Flags().BoolVar(&flags.incremental, "incremental", false, "crawl again and print the changes since the last crawl")
+	command.Flags().BoolVar(&flags.warc, "warc", false, "record the pages fetched to WARC files")
+	command.Flags().StringVar(&flags.rules, "rules", "", "YAML or JSON file with the extraction rules of this crawl")
+	command.Flags().StringVar(&flags.export, "export", "", "print the fields extracted as csv or jsonl instead of the links")
+
+	return command
+}
+
+func runCrawl(cmd *cobra.Command, flags crawlFlags) error {
+	service, storage := handler.NewCrawlerService()
+	if !storage.Persistent() {
+		cmd.PrintErrf("storage is %s, using %s, the crawl will not be kept after this run\n", storage.Mode, storage.Backend)
+	}
+	ctx := cmd.Context()
+	opts := crawler.Options{AuthProfile: flags.profile}
+	if flags.rules != "" {
+		rules, err := crawler.LoadExtractionRules(flags.rules)
+		if err != nil {
+			return err
+		}
+		ctx = crawler.NewExtractionContext(ctx, rules)
+	}
+	if flags.warc {
+		writer, err := handler.NewWARCWriter()
+		if err != nil {
+			return err
+		}
+		defer printWARCFiles(cmd, writer)
+		ctx = warc.NewContext(ctx, writer)
+	}
+
+	var crawl crawler.Crawl
+	var err error
+	switch {
+	case flags.incremental:
+		var diff crawler.CrawlDiff
+		crawl, diff, err = service.Recraw(ctx, flags.uri, flags.depth, opts)
+		if err == nil {
+			printDiff(cmd, diff)
+		}
+	case flags.refresh:
+		crawl, err = service.Refresh(ctx, flags.uri, flags.depth, opts)
+	default:
+		crawl, err = service.Craw(ctx, flags.uri, flags.depth, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if crawl.FromStorage {
+		now := time.Now().UTC()
+		status := "fresh"
+		if crawl.Stale(now, viper.GetDuration("CRAWL_STALE_AFTER")) {
+			status = "stale"
+		}
+		cmd.PrintErrf("served from storage, crawled at %s (%s), use --refresh to crawl again\n",
+			crawl.CrawledAt.Format(time.RFC3339), status)
+	}
+
+	if flags.export != "" {
+		return crawler.ExportFields(cmd.OutOrStdout(), flags.export, crawl.Pages)
+	}
+
+	for _, link := range crawl.Links {
+		fmt.Fprintln(cmd.OutOrStdout(), link)
+	}
+
+	return nil
+}
+
+func printWARCFiles(cmd *cobra.Command, writer *warc.Writer) {
+	if err := writer.Close(); err != nil {
+		cmd.PrintErrf("error closing WARC file: %v\n", err)
+	}
+	if len(writer.Files()) == 0 {
+		cmd.PrintErrln("nothing was fetched to be recorded to WARC")
+	}
+	for _, file := range writer.Files() {
+		cmd.PrintErrf("recorded to %s\n", file)
+	}
+}
+
+func printDiff(cmd *cobra.Command, diff crawler.CrawlDiff) {
+	if diff.FirstCrawl {
+		cmd.PrintErrln("there was no previous crawl to compare with")
+
+		return
+	}
+
+	for _, change := range []struct {
+		label string
+		uris  []string
+	}{
+		{"page added", diff.AddedPages},
+		{"page removed", diff.RemovedPages},
+		{"page changed", diff.ChangedPages},
+		{"link added", diff.AddedLinks},
+		{"link removed", diff.RemovedLinks},
+	} {
+		for _, uri := range change.uris {
+			cmd.PrintErrf("%s: %s\n", change.label, uri)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/repository/storage"
+	"github.com/spf13/cobra"
+)
+
+type migrateFlags struct {
+	dryRun bool
+	steps  int
+}
+
+func newMigrateCmd() *cobra.Command {
+	flags := migrateFlags{}
+	command := &cobra.Command{
+		Use:   "migrate",
+		Short: "A command to migrate the schema of the stored crawls",
+	}
+	command.PersistentFlags().BoolVar(&flags.dryRun, "dry-run", false, "print the migrations without running them")
+
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply the migrations pending",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			migrator, err := newMigrator(cmd)
+			if err != nil {
+				return err
+			}
+
+			applied, err := migrator.Up(cmd.Context(), flags.dryRun)
+			printMigrations(cmd, "applied", applied, flags.dryRun)
+
+			return err
+		},
+	}
+
+	downCmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the migrations applied, latest first",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			migrator, err := newMigrator(cmd)
+			if err != nil {
+				return err
+			}
+
+			reverted, err := migrator.Down(cmd.Context(), flags.steps, flags.dryRun)
+			printMigrations(cmd, "reverted", reverted, flags.dryRun)
+
+			return err
+		},
+	}
+	downCmd.Flags().IntVar(&flags.steps, "steps", 1, "number of migrations to revert")
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Print the migrations and whether they were applied",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			migrator, err := newMigrator(cmd)
+			if err != nil {
+				return err
+			}
+
+			statuses, err := migrator.Status(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			for _, status := range statuses {
+				state := "pending"
+				if status.Applied {
+					state = fmt.Sprintf("applied at %s", status.AppliedAt.Format(time.RFC3339))
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%d\t%s\t%s\n", status.Version, state, status.Description)
+			}
+
+			return nil
+		},
+	}
+
+	command.AddCommand(upCmd, downCmd, statusCmd)
+
+	return command
+}
+
+func newMigrator(cmd *cobra.Command) (storage.MongodbMigrator, error) {
+	repository, err := storage.NewCrawlerMongodbRepository(cmd.Context())
+	if err != nil {
+		return storage.MongodbMigrator{}, err
+	}
+
+	return storage.NewMongodbMigrator(repository), nil
+}
+
+func printMigrations(cmd *cobra.Command, action string, migrations []storage.Migration, dryRun bool) {
+	if dryRun {
+		action = "would be " + action
+	}
+	if len(migrations) == 0 {
+		cmd.PrintErrf("no migration %s\n", action)
+
+		return
+	}
+
+	for _, migration := range migrations {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %d: %s\n", action, migration.Version, migration.Description)
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/handler"
+	"github.com/spf13/cobra"
+)
+
+var errMissingSnapshot = errors.New("the --snapshot flag is required")
+
+func newReprocessCmd() *cobra.Command {
+	var snapshot string
+	command := &cobra.Command{
+		Use:   "reprocess",
+		Short: "A command to extract again the links of a crawl snapshot from the archived bodies",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if snapshot == "" {
+				return errMissingSnapshot
+			}
+
+			service, _ := handler.NewCrawlerService()
+			crawl, err := service.Reprocess(cmd.Context(), snapshot)
+			if err != nil {
+				return err
+			}
+
+			skipped := 0
+			for _, page := range crawl.Pages {
+				if page.BodyKey == "" {
+					skipped++
+				}
+			}
+			if skipped > 0 {
+				cmd.PrintErrf("%d of %d pages had no archived body and kept the links found when fetched\n", skipped, len(crawl.Pages))
+			}
+
+			for _, link := range crawl.Links {
+				fmt.Fprintln(cmd.OutOrStdout(), link)
+			}
+
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&snapshot, "snapshot", "", "id of the crawl snapshot to reprocess, as listed by /crawler/history")
+
+	return command
+}
@@ -13,6 +13,9 @@ var rootCmd = &cobra.Command{
 func Execute() error {
 	cobra.OnInitialize(config.InitConfigurations)
 	rootCmd.AddCommand(apiCmd)
+	rootCmd.AddCommand(newCrawlCmd())
+	rootCmd.AddCommand(newMigrateCmd())
+	rootCmd.AddCommand(newReprocessCmd())
 
 	return rootCmd.Execute()
 }
@@ -0,0 +1,10 @@
+package config
+
+import "github.com/spf13/viper"
+
+func archiveConfigurations() {
+	viper.SetDefault("BODY_ARCHIVE", "")
+	viper.SetDefault("BODY_ARCHIVE_DIR", "archive")
+	viper.SetDefault("BODY_ARCHIVE_COMPRESSION", "zstd")
+	viper.SetDefault("MONGODB_ARCHIVE_BUCKET", "bodies")
+}
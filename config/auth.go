@@ -0,0 +1,7 @@
+package config
+
+import "github.com/spf13/viper"
+
+func authConfigurations() {
+	viper.SetDefault("AUTH_PROFILES_FILE", "")
+}
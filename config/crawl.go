@@ -0,0 +1,11 @@
+package config
+
+import "github.com/spf13/viper"
+
+func crawlConfigurations() {
+	viper.SetDefault("CRAWL_TTL", "168h")
+	viper.SetDefault("CRAWL_STALE_AFTER", "24h")
+	viper.SetDefault("CRAWL_SNAPSHOT_RETENTION", 10)
+	viper.SetDefault("CRAWL_SNAPSHOT_MAX_AGE", "0")
+	viper.SetDefault("CRAWL_CONCURRENCY", 8)
+}
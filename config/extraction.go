@@ -0,0 +1,7 @@
+package config
+
+import "github.com/spf13/viper"
+
+func extractionConfigurations() {
+	viper.SetDefault("EXTRACTION_RULES_FILE", "")
+}
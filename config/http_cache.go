@@ -0,0 +1,10 @@
+package config
+
+import "github.com/spf13/viper"
+
+func httpCacheConfigurations() {
+	viper.SetDefault("HTTP_CACHE_ENABLED", false)
+	viper.SetDefault("HTTP_CACHE_DIR", ".http-cache")
+	viper.SetDefault("HTTP_CACHE_OFFLINE", false)
+	viper.SetDefault("HTTP_CACHE_MAX_ENTRY_SIZE", 10485760)
+}
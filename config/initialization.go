@@ -9,6 +9,17 @@ func InitConfigurations() {
 	_ = viper.ReadInConfig()
 
 	apiConfigurations()
+	archiveConfigurations()
+	authConfigurations()
+	crawlConfigurations()
+	extractionConfigurations()
+	httpCacheConfigurations()
 	loggerConfigurations()
 	mongoConfigurations()
+	pagerConfigurations()
+	proxyConfigurations()
+	searchConfigurations()
+	storageConfigurations()
+	tlsConfigurations()
+	warcConfigurations()
 }
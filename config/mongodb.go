@@ -4,7 +4,13 @@ import "github.com/spf13/viper"
 
 func mongoConfigurations() {
 	viper.SetDefault("MONGODB_DATABASE", "crawler")
-	viper.SetDefault("MONGODB_COLLECTION", "page")
+	viper.SetDefault("MONGODB_COLLECTION", "crawls")
+	viper.SetDefault("MONGODB_PAGES_COLLECTION", "pages")
+	viper.SetDefault("MONGODB_LINKS_COLLECTION", "links")
+	viper.SetDefault("MONGODB_DIFF_COLLECTION", "crawl_diff")
+	viper.SetDefault("MONGODB_SNAPSHOT_COLLECTION", "crawl_snapshot")
+	viper.SetDefault("MONGODB_LEGACY_COLLECTION", "page")
+	viper.SetDefault("MONGODB_MIGRATIONS_COLLECTION", "schema_migrations")
 	viper.SetDefault("MONGODB_PORT", "27017")
 	viper.SetDefault("MONGODB_HOST", "localhost")
 }
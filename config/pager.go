@@ -0,0 +1,15 @@
+package config
+
+import "github.com/spf13/viper"
+
+func pagerConfigurations() {
+	viper.SetDefault("PAGER_MAX_REDIRECTS", 10)
+	viper.SetDefault("PAGER_ALLOW_CROSS_HOST_REDIRECTS", true)
+	viper.SetDefault("PAGER_MAX_BODY_SIZE", 10485760)
+	viper.SetDefault("PAGER_DECOMPRESSION", true)
+	viper.SetDefault("PAGER_CHARSET_DETECTION", true)
+	viper.SetDefault("PAGER_DIAL_TIMEOUT", "30s")
+	viper.SetDefault("PAGER_DIAL_KEEP_ALIVE", "30s")
+	viper.SetDefault("PAGER_SSRF_PROTECTION", true)
+	viper.SetDefault("PAGER_SSRF_ALLOWLIST", "")
+}
@@ -0,0 +1,10 @@
+package config
+
+import "github.com/spf13/viper"
+
+func proxyConfigurations() {
+	viper.SetDefault("PAGER_PROXY_URLS", "")
+	viper.SetDefault("PAGER_PROXY_RULES", "")
+	viper.SetDefault("PAGER_PROXY_USERNAME", "")
+	viper.SetDefault("PAGER_PROXY_PASSWORD", "")
+}
@@ -0,0 +1,8 @@
+package config
+
+import "github.com/spf13/viper"
+
+func searchConfigurations() {
+	viper.SetDefault("SEARCH_INDEX_DIR", "search-index")
+	viper.SetDefault("SEARCH_DEFAULT_LANGUAGE", "en")
+}
@@ -0,0 +1,11 @@
+package config
+
+import "github.com/spf13/viper"
+
+func storageConfigurations() {
+	viper.SetDefault("STORAGE_BACKEND", "mongodb")
+	viper.SetDefault("STORAGE_FALLBACK", "fail")
+	viper.SetDefault("STORAGE_CONNECT_TIMEOUT", "10s")
+	viper.SetDefault("SQLITE_PATH", "crawler.db")
+	viper.SetDefault("POSTGRES_DSN", "postgres://localhost:5432/crawler?sslmode=disable")
+}
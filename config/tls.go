@@ -0,0 +1,10 @@
+package config
+
+import "github.com/spf13/viper"
+
+func tlsConfigurations() {
+	viper.SetDefault("TLS_CA_FILE", "")
+	viper.SetDefault("TLS_MIN_VERSION", "1.2")
+	viper.SetDefault("TLS_HOSTS_FILE", "")
+	viper.SetDefault("TLS_EXPIRY_WARNING", "720h")
+}
@@ -0,0 +1,9 @@
+package config
+
+import "github.com/spf13/viper"
+
+func warcConfigurations() {
+	viper.SetDefault("WARC_DIR", "warc")
+	viper.SetDefault("WARC_PREFIX", "crawl")
+	viper.SetDefault("WARC_MAX_FILE_SIZE", 1073741824)
+}
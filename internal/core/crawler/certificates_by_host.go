@@ -0,0 +1,26 @@
+package crawler
+
+import "github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+
+// certificatesByHost keeps the first certificate seen for each host, in the order they were seen.
+type certificatesByHost struct {
+	hosts        map[string]bool
+	certificates []pager.Certificate
+}
+
+func newCertificatesByHost() *certificatesByHost {
+	return &certificatesByHost{hosts: make(map[string]bool), certificates: make([]pager.Certificate, 0)}
+}
+
+func (c *certificatesByHost) add(certificate *pager.Certificate) {
+	if certificate == nil || c.hosts[certificate.Host] {
+		return
+	}
+
+	c.hosts[certificate.Host] = true
+	c.certificates = append(c.certificates, *certificate)
+}
+
+func (c *certificatesByHost) list() []pager.Certificate {
+	return c.certificates
+}
@@ -0,0 +1,159 @@
+package crawler
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// wordsPerMinute is the reading speed the reading time of the pages is estimated with.
+const wordsPerMinute = 200
+
+// Content is the readable text of a page, without the navigation, headers, footers, forms, scripts and
+// styles around it, split in paragraphs by blank lines.
+type Content struct {
+	Text           string `json:"text,omitempty"`
+	WordCount      int    `json:"wordCount"`
+	ReadingMinutes int    `json:"readingMinutes"`
+}
+
+// IsZero reports whether the page has no readable text.
+func (c Content) IsZero() bool {
+	return c.Text == "" && c.WordCount == 0 && c.ReadingMinutes == 0
+}
+
+// PagesContaining returns the pages whose content has the text, regardless of case.
+func (c Crawl) PagesContaining(text string) []Page {
+	text = strings.ToLower(strings.TrimSpace(text))
+	pages := make([]Page, 0)
+	for _, page := range c.Pages {
+		if text != "" && strings.Contains(strings.ToLower(page.Content.Text), text) {
+			pages = append(pages, page)
+		}
+	}
+
+	return pages
+}
+
+// boilerplateTags are the elements whose text is never part of the content of the page.
+var boilerplateTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true, "svg": true,
+	"iframe": true, "nav": true, "aside": true, "form": true, "button": true, "select": true, "dialog": true,
+}
+
+// sectionTags are the elements that are boilerplate around the content of the page, but part of it
+// when inside an article or the main content.
+var sectionTags = map[string]bool{"header": true, "footer": true}
+
+// boilerplateRoles are the ARIA landmarks around the content of the page.
+var boilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true, "search": true,
+	"menu": true, "menubar": true, "dialog": true,
+}
+
+// blockTags are the elements whose text is a paragraph of its own.
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "ul": true, "ol": true, "li": true, "dl": true,
+	"dt": true, "dd": true, "table": true, "tr": true, "blockquote": true, "pre": true, "figure": true,
+	"figcaption": true, "hr": true, "br": true, "address": true, "details": true, "summary": true,
+}
+
+// extractContent finds the main content of the page, being the main element or landmark when there is one
+// and the body otherwise, and collects its text leaving the boilerplate out. The headers and footers of
+// articles and of the main content are kept.
+func extractContent(node *html.Node) Content {
+	if node == nil {
+		return Content{}
+	}
+
+	root := findMain(node)
+	if root == nil {
+		root = node
+	}
+
+	paragraphs := make([]string, 0)
+	current := strings.Builder{}
+	flush := func() {
+		if text := strings.Join(strings.Fields(current.String()), " "); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		current.Reset()
+	}
+
+	var walk func(*html.Node, bool)
+	walk = func(node *html.Node, inArticle bool) {
+		switch node.Type {
+		case html.TextNode:
+			current.WriteString(node.Data)
+
+			return
+		case html.ElementNode:
+			if isBoilerplate(node, inArticle) {
+				return
+			}
+			inArticle = inArticle || node.Data == "article"
+		case html.CommentNode, html.DoctypeNode:
+			return
+		}
+
+		block := node.Type == html.ElementNode && blockTags[node.Data]
+		if block {
+			flush()
+		}
+		for next := node.FirstChild; next != nil; next = next.NextSibling {
+			walk(next, inArticle)
+		}
+		if block {
+			flush()
+		}
+		if node.Type == html.ElementNode && (node.Data == "td" || node.Data == "th") {
+			current.WriteString(" ")
+		}
+	}
+	walk(root, root != node)
+	flush()
+
+	text := strings.Join(paragraphs, "\n\n")
+	words := countWords(text)
+
+	return Content{Text: text, WordCount: words, ReadingMinutes: (words + wordsPerMinute - 1) / wordsPerMinute}
+}
+
+// findMain returns the first main element or element with the main role outside of the boilerplate.
+func findMain(node *html.Node) *html.Node {
+	if node.Type == html.ElementNode {
+		if isBoilerplate(node, false) {
+			return nil
+		}
+		if node.Data == "main" || attribute(node, "role") == "main" {
+			return node
+		}
+	}
+
+	for next := node.FirstChild; next != nil; next = next.NextSibling {
+		if found := findMain(next); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+func isBoilerplate(node *html.Node, inArticle bool) bool {
+	return boilerplateTags[node.Data] || (sectionTags[node.Data] && !inArticle) ||
+		boilerplateRoles[attribute(node, "role")] || hasAttribute(node, "hidden") || attribute(node, "aria-hidden") == "true"
+}
+
+// countWords counts the words of the text, being a word a run of characters holding a letter or a digit.
+func countWords(text string) int {
+	words := 0
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			words++
+		}
+	}
+
+	return words
+}
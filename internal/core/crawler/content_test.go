@@ -0,0 +1,65 @@
+package crawler_test
+
+import (
+	"strings"
+	"testing"
+
+	core "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrawlerService_Content(t *testing.T) {
+	URI := "https://anyurl.com/blog/post"
+
+	t.Run("should extract the text of the body without the boilerplate", func(t *testing.T) {
+		page := crawlPage(t, URI, `<html><head><title>Post</title><style>p { color: red }</style></head><body>
+			<header>Site name</header>
+			<nav><a href="/">Home</a> <a href="/blog">Blog</a></nav>
+			<h1>Deprecated API</h1>
+			<p>The old   API is <b>deprecated</b>.</p>
+			<div hidden>Hidden text</div>
+			<ul><li>Use the new one</li><li>Today</li></ul>
+			<table><tr><th>Old</th><th>New</th></tr></table>
+			<script>var tracking = true;</script>
+			<form><label>Search</label><input name="q"></form>
+			<div role="navigation">Breadcrumbs</div>
+			<footer>Copyright 2024</footer>
+		</body></html>`)
+
+		assert.Equal(t, "Deprecated API\n\nThe old API is deprecated.\n\nUse the new one\n\nToday\n\nOld New", page.Content.Text)
+		assert.Equal(t, 14, page.Content.WordCount)
+		assert.Equal(t, 1, page.Content.ReadingMinutes)
+	})
+	t.Run("should extract the text of the main content keeping the header of the article", func(t *testing.T) {
+		words := strings.Repeat("word ", 450)
+		page := crawlPage(t, URI, `<body>
+			<div class="sidebar">Related posts</div>
+			<div role="main"><article><header><h1>Title</h1></header><p>`+words+`</p><footer>By Ada</footer></article></div>
+		</body>`)
+
+		assert.True(t, strings.HasPrefix(page.Content.Text, "Title\n\nword word"))
+		assert.True(t, strings.HasSuffix(page.Content.Text, "word\n\nBy Ada"))
+		assert.NotContains(t, page.Content.Text, "Related posts")
+		assert.Equal(t, 453, page.Content.WordCount)
+		assert.Equal(t, 3, page.Content.ReadingMinutes)
+	})
+	t.Run("should have no content when the page has no text", func(t *testing.T) {
+		page := crawlPage(t, URI, `<html><body><nav>Menu</nav><script>run()</script></body></html>`)
+
+		assert.True(t, page.Content.IsZero())
+	})
+}
+
+func TestCrawl_PagesContaining(t *testing.T) {
+	deprecated := core.Page{URI: "https://anyurl.com/changelog", Content: core.Content{Text: "The old API is Deprecated now"}}
+	crawl := core.Crawl{Pages: []core.Page{
+		{URI: "https://anyurl.com", Content: core.Content{Text: "Welcome"}},
+		deprecated,
+		{URI: "https://anyurl.com/empty"},
+	}}
+
+	assert.Equal(t, []core.Page{deprecated}, crawl.PagesContaining("old api"))
+	assert.Equal(t, []core.Page{deprecated}, crawl.PagesContaining("API is deprecated"))
+	assert.Empty(t, crawl.PagesContaining("removed"))
+	assert.Empty(t, crawl.PagesContaining(" "))
+}
@@ -0,0 +1,241 @@
+package crawler
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"golang.org/x/net/html"
+)
+
+// Crawl is the result of crawling a URI given a depth. The pages fetched are the ones up to depth-1
+// hops away from the URI, so the links found on them are up to depth hops away.
+type Crawl struct {
+	URI          string
+	Depth        uint
+	Options      Options
+	CrawledAt    time.Time
+	FromStorage  bool
+	Links        []string
+	Hops         map[string]uint
+	Pages        []Page
+	Certificates []pager.Certificate
+}
+
+// Options are the settings a crawl is made with, so crawls of the same URI and depth made with
+// different options are kept apart.
+type Options struct {
+	AuthProfile string
+}
+
+// Page holds what was learned when fetching a single URI during the crawl, including the
+// validators and links reused when the page is not modified on the next crawl. The BodyKey
+// links the page to its body in the archive, when archived, which is decoded with the Charset.
+// The Fields are the values found by the extraction rules matching the page.
+type Page struct {
+	URI            string
+	Depth          uint
+	StatusCode     int
+	Redirects      []pager.Redirect
+	RedirectLoop   bool
+	Error          string
+	ETag           string
+	LastModified   string
+	ContentHash    string
+	BodyKey        string
+	Charset        string
+	NotModified    bool
+	Links          []string
+	Metadata       Metadata
+	StructuredData []StructuredData
+	Fields         map[string][]string
+	Content        Content
+}
+
+func (p Page) validators() pager.Validators {
+	return pager.Validators{ETag: p.ETag, LastModified: p.LastModified}
+}
+
+// Expired reports whether the crawl is older than the TTL, where zero means it never expires. A crawl stored
+// without timestamp has an unknown age and never expires, as MongoDB TTL indexes skip it as well, every
+// storage backend serving it until it is replaced.
+func (c Crawl) Expired(now time.Time, ttl time.Duration) bool {
+	return ttl > 0 && !c.CrawledAt.IsZero() && now.Sub(c.CrawledAt) > ttl
+}
+
+// Stale reports whether the crawl is older than the given duration, where zero means it never gets stale.
+// A crawl stored without timestamp is always stale.
+func (c Crawl) Stale(now time.Time, after time.Duration) bool {
+	if after <= 0 {
+		return false
+	}
+
+	return c.CrawledAt.IsZero() || now.Sub(c.CrawledAt) > after
+}
+
+// Truncate returns the crawl as it would be if made with a smaller depth, keeping only the links and
+// pages close enough to the URI. Crawls without hop distances cannot be truncated.
+func (c Crawl) Truncate(depth uint) Crawl {
+	if depth >= c.Depth || c.Hops == nil {
+		return c
+	}
+
+	links := make([]string, 0, len(c.Links))
+	hops := make(map[string]uint, len(c.Hops))
+	for _, link := range c.Links {
+		if hop, found := c.Hops[link]; found && hop <= depth {
+			links = append(links, link)
+			hops[link] = hop
+		}
+	}
+
+	pages := make([]Page, 0, len(c.Pages))
+	hosts := make(map[string]bool)
+	for _, page := range c.Pages {
+		if page.Depth < depth {
+			pages = append(pages, page)
+			page.addHosts(hosts)
+		}
+	}
+
+	certificates := make([]pager.Certificate, 0, len(c.Certificates))
+	for _, certificate := range c.Certificates {
+		if hosts[certificate.Host] {
+			certificates = append(certificates, certificate)
+		}
+	}
+
+	c.Depth = depth
+	c.Links = links
+	c.Hops = hops
+	c.Pages = pages
+	c.Certificates = certificates
+
+	return c
+}
+
+// frontier returns the links found on the deepest pages fetched, which are the next ones to fetch
+// when the crawl is extended to a greater depth.
+func (c Crawl) frontier() []string {
+	frontier := make([]string, 0)
+	for _, link := range c.Links {
+		if c.Hops[link] == c.Depth {
+			frontier = append(frontier, link)
+		}
+	}
+
+	return frontier
+}
+
+// addHosts collects the hosts the page was fetched from, including the ones it was redirected to.
+func (p Page) addHosts(hosts map[string]bool) {
+	if uri, err := url.Parse(p.URI); err == nil {
+		hosts[uri.Hostname()] = true
+	}
+
+	for _, redirect := range p.Redirects {
+		base, err := url.Parse(redirect.URI)
+		if err != nil {
+			continue
+		}
+		if location, err := base.Parse(redirect.Location); err == nil {
+			hosts[location.Hostname()] = true
+		}
+	}
+}
+
+// finalURI returns the URI the page was served from, which is where its last redirect led to.
+func (p Page) finalURI() string {
+	if len(p.Redirects) == 0 {
+		return p.URI
+	}
+
+	last := p.Redirects[len(p.Redirects)-1]
+	base, err := url.Parse(last.URI)
+	if err != nil {
+		return p.URI
+	}
+	location, err := base.Parse(last.Location)
+	if err != nil {
+		return p.URI
+	}
+
+	return location.String()
+}
+
+// RedirectedPages returns the pages that were reached through at least one redirect.
+func (c Crawl) RedirectedPages() []Page {
+	pages := make([]Page, 0)
+	for _, page := range c.Pages {
+		if len(page.Redirects) > 0 {
+			pages = append(pages, page)
+		}
+	}
+
+	return pages
+}
+
+// ExpiringCertificates returns the certificates that are expired or expire before the duration elapses.
+func (c Crawl) ExpiringCertificates(now time.Time, within time.Duration) []pager.Certificate {
+	certificates := make([]pager.Certificate, 0)
+	for _, certificate := range c.Certificates {
+		if certificate.ExpiresWithin(now, within) {
+			certificates = append(certificates, certificate)
+		}
+	}
+
+	return certificates
+}
+
+func newPage(fetched pager.Page, err error, rules []ExtractionRule) Page {
+	page := Page{
+		URI:          fetched.URI,
+		StatusCode:   fetched.StatusCode,
+		Redirects:    fetched.Redirects,
+		RedirectLoop: errors.Is(err, pager.ErrRedirectLoop),
+		ETag:         fetched.ETag,
+		LastModified: fetched.LastModified,
+		ContentHash:  fetched.ContentHash,
+		BodyKey:      fetched.BodyKey,
+		Charset:      fetched.Charset,
+		NotModified:  fetched.NotModified,
+	}.extract(fetched.Node, rules)
+	if err != nil {
+		page.Error = err.Error()
+	}
+
+	return page
+}
+
+// extract fills the page with what is extracted from its parsed body, being used both when the page is
+// fetched and when its archived body is reprocessed.
+func (p Page) extract(node *html.Node, rules []ExtractionRule) Page {
+	p.Links = extractAddresses([]string{}, node)
+	p.Metadata = extractMetadata(p.finalURI(), node)
+	p.StructuredData = extractStructuredData(node)
+	p.Fields = extractFields(p.URI, node, rules)
+	p.Content = extractContent(node)
+
+	return p
+}
+
+// reuse fills the not modified page with what was extracted from its previous version.
+func (p Page) reuse(previous Page) Page {
+	p.ContentHash = previous.ContentHash
+	p.BodyKey = previous.BodyKey
+	p.Charset = previous.Charset
+	p.Links = previous.Links
+	p.Metadata = previous.Metadata
+	p.StructuredData = previous.StructuredData
+	p.Fields = previous.Fields
+	p.Content = previous.Content
+	if p.ETag == "" {
+		p.ETag = previous.ETag
+	}
+	if p.LastModified == "" {
+		p.LastModified = previous.LastModified
+	}
+
+	return p
+}
@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"sort"
+	"time"
+)
+
+// CrawlDiff holds the changes found by crawling a URI again compared to its previous crawl.
+// Pages are compared by URI and are considered changed when their content hash differs.
+type CrawlDiff struct {
+	URI          string
+	Depth        uint
+	Options      Options
+	CreatedAt    time.Time
+	FirstCrawl   bool
+	AddedPages   []string
+	RemovedPages []string
+	ChangedPages []string
+	AddedLinks   []string
+	RemovedLinks []string
+}
+
+// HasChanges reports whether anything was added, removed or changed since the previous crawl.
+func (d CrawlDiff) HasChanges() bool {
+	return len(d.AddedPages)+len(d.RemovedPages)+len(d.ChangedPages)+len(d.AddedLinks)+len(d.RemovedLinks) > 0
+}
+
+func newCrawlDiff(previous, current Crawl, firstCrawl bool, now time.Time) CrawlDiff {
+	previousHashes := pageHashes(previous.Pages)
+	currentHashes := pageHashes(current.Pages)
+
+	changed := make([]string, 0)
+	for uri, hash := range currentHashes {
+		previousHash, found := previousHashes[uri]
+		if found && hash != "" && previousHash != "" && hash != previousHash {
+			changed = append(changed, uri)
+		}
+	}
+	sort.Strings(changed)
+
+	return CrawlDiff{
+		URI:          current.URI,
+		Depth:        current.Depth,
+		Options:      current.Options,
+		CreatedAt:    now,
+		FirstCrawl:   firstCrawl,
+		AddedPages:   difference(keys(currentHashes), previousHashes),
+		RemovedPages: difference(keys(previousHashes), currentHashes),
+		ChangedPages: changed,
+		AddedLinks:   difference(current.Links, set(previous.Links)),
+		RemovedLinks: difference(previous.Links, set(current.Links)),
+	}
+}
+
+func pageHashes(pages []Page) map[string]string {
+	hashes := make(map[string]string, len(pages))
+	for _, page := range pages {
+		hashes[page.URI] = page.ContentHash
+	}
+
+	return hashes
+}
+
+func keys(values map[string]string) []string {
+	list := make([]string, 0, len(values))
+	for key := range values {
+		list = append(list, key)
+	}
+
+	return list
+}
+
+func set(values []string) map[string]string {
+	found := make(map[string]string, len(values))
+	for _, value := range values {
+		found[value] = value
+	}
+
+	return found
+}
+
+// difference returns the sorted values missing from the other set.
+func difference(values []string, other map[string]string) []string {
+	missing := make([]string, 0)
+	for _, value := range values {
+		if _, found := other[value]; !found {
+			missing = append(missing, value)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
@@ -3,6 +3,11 @@ package crawler
 import "context"
 
 type CrawlerDatabase interface {
-	Insert(ctx context.Context, uri string, depth uint, uris []string) error
-	Find(ctx context.Context, uri string, depth uint) ([]string, error)
+	Insert(ctx context.Context, crawl Crawl) error
+	Find(ctx context.Context, uri string, depth uint, opts Options) (Crawl, error)
+	FindShallower(ctx context.Context, uri string, depth uint, opts Options) (Crawl, error)
+	FindPage(ctx context.Context, uri string, opts Options) (Page, error)
+	InsertDiff(ctx context.Context, diff CrawlDiff) error
+	History(ctx context.Context, uri string, opts Options) ([]Snapshot, error)
+	FindSnapshot(ctx context.Context, id string) (Crawl, error)
 }
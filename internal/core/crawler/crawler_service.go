@@ -2,6 +2,7 @@ package crawler
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"sync"
 	"time"
@@ -9,7 +10,10 @@ import (
 	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
 	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
 	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/metrics"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/warc"
+	"go.uber.org/zap"
 	"golang.org/x/net/html"
+	"golang.org/x/sync/singleflight"
 )
 
 var log = logger.GetLogger()
@@ -20,81 +24,351 @@ const (
 	patternURI = `((http|https):\/\/)`
 )
 
+// defaultConcurrency is how many pages of the same level are fetched at the same time when not configured.
+const defaultConcurrency = 8
+
 type CrawlerService struct {
-	pagerService pager.PagerUsecase
-	database     CrawlerDatabase
+	pagerService   pager.PagerUsecase
+	database       CrawlerDatabase
+	bodyArchive    pager.BodyArchive
+	rules          []ExtractionRule
+	searchDir      string
+	searchLanguage string
+	concurrency    int
+	inFlight       *singleflight.Group
+}
+
+// ServiceOption configures the CrawlerService.
+type ServiceOption func(*CrawlerService)
+
+// WithBodyArchive sets the archive the bodies of the pages are read from to be reprocessed.
+func WithBodyArchive(archive pager.BodyArchive) ServiceOption {
+	return func(p *CrawlerService) {
+		p.bodyArchive = archive
+	}
+}
+
+// WithExtractionRules sets the rules the fields of the pages are extracted by on every crawl.
+func WithExtractionRules(rules []ExtractionRule) ServiceOption {
+	return func(p *CrawlerService) {
+		p.rules = rules
+	}
+}
+
+// WithConcurrency sets how many pages of the same level are fetched at the same time, zero or less
+// meaning the default.
+func WithConcurrency(concurrency int) ServiceOption {
+	return func(p *CrawlerService) {
+		if concurrency > 0 {
+			p.concurrency = concurrency
+		}
+	}
 }
 
-func NewCrawlerService(pagerService pager.PagerUsecase, database CrawlerDatabase) CrawlerService {
-	return CrawlerService{pagerService: pagerService, database: database}
+func NewCrawlerService(pagerService pager.PagerUsecase, database CrawlerDatabase, opts ...ServiceOption) CrawlerService {
+	crawlerService := CrawlerService{
+		pagerService: pagerService,
+		database:     database,
+		concurrency:  defaultConcurrency,
+		inFlight:     &singleflight.Group{},
+	}
+	for _, opt := range opts {
+		opt(&crawlerService)
+	}
+
+	return crawlerService
 }
 
-func (p CrawlerService) Craw(ctx context.Context, uri string, depth uint) ([]string, error) {
+// Craw returns the stored crawl of the URI when there is one, crawling it otherwise. Crawls with
+// extraction rules of their own or recorded to WARC files are always made again. The depth is the
+// number of hops away from the URI the links are collected: the pages up to depth-1 hops are
+// fetched, a level at a time, and each link is kept with the hop distance it was first found at.
+func (p CrawlerService) Craw(ctx context.Context, uri string, depth uint, opts Options) (Crawl, error) {
 	start := time.Now().UTC()
 	defer func() {
 		metrics.DeltaTimeToProcessLinks.Observe(time.Since(start).Seconds())
 	}()
 
-	if links, err := p.database.Find(ctx, uri, depth); err == nil && len(links) > 0 {
+	if len(ExtractionRulesFromContext(ctx)) > 0 || warc.FromContext(ctx) != nil {
+		return p.crawl(ctx, uri, depth, opts, Crawl{})
+	}
+
+	if crawl, err := p.database.Find(ctx, uri, depth, opts); err == nil && len(crawl.Links) > 0 {
 		log.Info("returning data from database")
+		crawl.FromStorage = true
+
+		return crawl, nil
+	}
+
+	from := Crawl{}
+	if shallower, err := p.database.FindShallower(ctx, uri, depth, opts); err == nil {
+		log.Info("extending crawl from database", zap.Uint("from", shallower.Depth), zap.Uint("to", depth))
+		from = shallower
+	}
+
+	return p.crawl(ctx, uri, depth, opts, from)
+}
+
+// Refresh crawls the URI again without looking for a stored crawl, replacing it as the latest one.
+func (p CrawlerService) Refresh(ctx context.Context, uri string, depth uint, opts Options) (Crawl, error) {
+	start := time.Now().UTC()
+	defer func() {
+		metrics.DeltaTimeToProcessLinks.Observe(time.Since(start).Seconds())
+	}()
+
+	return p.crawl(ctx, uri, depth, opts, Crawl{})
+}
+
+// Recraw crawls the URI again regardless of what is stored and compares the result with the latest
+// stored crawl made with the same depth and options, persisting the changes found unless the crawl
+// has extraction rules of its own, as it is not stored.
+func (p CrawlerService) Recraw(ctx context.Context, uri string, depth uint, opts Options) (Crawl, CrawlDiff, error) {
+	start := time.Now().UTC()
+	defer func() {
+		metrics.DeltaTimeToProcessLinks.Observe(time.Since(start).Seconds())
+	}()
+
+	previous, err := p.database.Find(ctx, uri, depth, opts)
+	firstCrawl := err != nil
 
-		return links, nil
+	crawl, err := p.crawl(ctx, uri, depth, opts, Crawl{})
+	if err != nil {
+		return Crawl{}, CrawlDiff{}, err
 	}
 
-	links := make([]string, 0)
-	ch := make(chan *linkAddress)
-	fetched := sync.Map{}
+	diff := newCrawlDiff(previous, crawl, firstCrawl, time.Now().UTC())
+	if len(ExtractionRulesFromContext(ctx)) > 0 {
+		return crawl, diff, nil
+	}
+	if err := p.database.InsertDiff(ctx, diff); err != nil {
+		log.Error("error inserting crawl diff into database", logger.FieldError(err))
+	}
+
+	return crawl, diff, nil
+}
+
+// History lists the stored snapshots of the crawls of the URI, newest first.
+func (p CrawlerService) History(ctx context.Context, uri string, opts Options) ([]Snapshot, error) {
+	return p.database.History(ctx, uri, opts)
+}
 
-	fetch := func(wg *sync.WaitGroup, uri string) {
-		defer wg.Done()
+// Snapshot returns the crawl as stored by one of its snapshots.
+func (p CrawlerService) Snapshot(ctx context.Context, id string) (Crawl, error) {
+	crawl, err := p.database.FindSnapshot(ctx, id)
+	if err != nil {
+		return Crawl{}, err
+	}
+	crawl.FromStorage = true
 
-		node, err := p.pagerService.GetNode(uri)
-		uris := extractAddresses([]string{}, node)
+	return crawl, nil
+}
 
-		ch <- &linkAddress{uri, uris, err}
+// crawl makes the identical crawls requested at the same time share a single execution, where a crawl
+// extending a stored one is only shared with the ones extending the same depth, so a refresh never joins
+// a crawl made of stored pages. The execution is detached from the cancellation of the callers, so one of
+// them giving up does not fail the others, while each caller still returns once its own context is done.
+// Crawls with extraction rules of their own or recorded to WARC files are never shared.
+func (p CrawlerService) crawl(ctx context.Context, uri string, depth uint, opts Options, from Crawl) (Crawl, error) {
+	if len(ExtractionRulesFromContext(ctx)) > 0 || warc.FromContext(ctx) != nil {
+		return p.crawlLevels(ctx, uri, depth, opts, from)
 	}
 
+	key := fmt.Sprintf("%s|%d|%s|%d", uri, depth, opts.AuthProfile, from.Depth)
+	executed := false
+	results := p.inFlight.DoChan(key, func() (any, error) {
+		executed = true
+
+		return p.crawlLevels(context.WithoutCancel(ctx), uri, depth, opts, from)
+	})
+
+	select {
+	case <-ctx.Done():
+		return Crawl{}, ctx.Err()
+	case result := <-results:
+		if result.Shared && !executed {
+			log.Info("joining crawl in progress", zap.String("uri", uri), zap.Uint("depth", depth))
+			metrics.DeduplicatedCrawlsCounter.Inc()
+		}
+		if result.Err != nil {
+			return Crawl{}, result.Err
+		}
+
+		return result.Val.(Crawl), nil
+	}
+}
+
+// crawlLevels fetches the pages level by level, starting from the URI or from the frontier of a stored crawl
+// being extended, until the depth is reached, storing the result.
+func (p CrawlerService) crawlLevels(ctx context.Context, uri string, depth uint, opts Options, from Crawl) (Crawl, error) {
+	pagerService := p.pagerService
+	if opts.AuthProfile != "" {
+		authenticated, err := p.pagerService.Authenticate(ctx, opts.AuthProfile)
+		if err != nil {
+			log.Error("error authenticating crawl", logger.FieldError(err))
+
+			return Crawl{}, err
+		}
+		pagerService = authenticated
+	}
+
+	links := append(make([]string, 0, len(from.Links)), from.Links...)
+	hops := make(map[string]uint, len(from.Hops))
+	seen := map[string]bool{uri: true}
+	for _, link := range links {
+		hops[link] = from.Hops[link]
+		seen[link] = true
+	}
+	pages := append(make([]Page, 0, len(from.Pages)), from.Pages...)
+	certificates := newCertificatesByHost()
+	for i := range from.Certificates {
+		certificates.add(&from.Certificates[i])
+	}
+
+	level, frontier := uint(0), []string{uri}
+	if from.Depth > 0 {
+		level, frontier = from.Depth, from.frontier()
+	}
+
+	for ; level < depth && len(frontier) > 0; level++ {
+		fetched, err := p.fetchLevel(ctx, pagerService, frontier, opts)
+		if err != nil {
+			return Crawl{}, err
+		}
+
+		frontier = make([]string, 0)
+		for _, linkAddress := range fetched {
+			linkAddress.page.Depth = level
+			reportRedirects(linkAddress.page)
+			pages = append(pages, linkAddress.page)
+			certificates.add(linkAddress.certificate)
+
+			for _, uri := range linkAddress.uris {
+				metrics.LinksCounter.Inc()
+
+				if !seen[uri] {
+					seen[uri] = true
+					links = append(links, uri)
+					hops[uri] = level + 1
+					frontier = append(frontier, uri)
+				}
+			}
+		}
+	}
+
+	crawl := Crawl{
+		URI:          uri,
+		Depth:        depth,
+		Options:      opts,
+		CrawledAt:    time.Now().UTC(),
+		Links:        links,
+		Hops:         hops,
+		Pages:        pages,
+		Certificates: certificates.list(),
+	}
+	// The crawl stored is the one of the configured rules, the fields of rules of its own are only returned.
+	if len(ExtractionRulesFromContext(ctx)) > 0 {
+		return crawl, nil
+	}
+	if err := p.database.Insert(ctx, crawl); err != nil {
+		log.Error("error inserting data into database", logger.FieldError(err))
+
+		return crawl, nil
+	}
+	p.pruneSearchIndexes(ctx, uri, opts)
+
+	return crawl, nil
+}
+
+// fetchLevel fetches the pages of the same level concurrently, up to the configured concurrency, keeping
+// the order they were found. A failure other than the redirect policy aborts the crawl.
+func (p CrawlerService) fetchLevel(
+	ctx context.Context,
+	pagerService pager.PagerUsecase,
+	uris []string,
+	opts Options,
+) ([]linkAddress, error) {
+	fetched := make([]linkAddress, len(uris))
+	workers := make(chan struct{}, p.concurrency)
 	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go fetch(&wg, uri)
-	fetched.Store(uri, true)
+	for i, uri := range uris {
+		wg.Add(1)
+		workers <- struct{}{}
+
+		go func(i int, uri string) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
 
-	for fetching := uint(1); fetching <= depth; fetching++ {
-		linkAddress := <-ch
-		if linkAddress.err != nil {
+			page, certificate, err := p.fetchPage(ctx, pagerService, uri, opts)
+			fetched[i] = linkAddress{uri: uri, uris: page.Links, page: page, certificate: certificate, err: err}
+		}(i, uri)
+	}
+	wg.Wait()
+
+	for _, linkAddress := range fetched {
+		if linkAddress.err != nil && !pager.IsRedirectError(linkAddress.err) {
 			log.Error("error to get uri node", logger.FieldError(linkAddress.err))
 			metrics.LinksErrorCounter.Inc()
 
 			return nil, linkAddress.err
 		}
+	}
 
-		if len(linkAddress.uris) == 0 {
-			break
-		}
+	return fetched, nil
+}
 
-		for _, uri := range linkAddress.uris {
-			metrics.LinksCounter.Inc()
+// fetchPage makes a conditional request when the page was stored by a previous crawl, reusing
+// the links extracted back then when the page was not modified. Crawls with extraction rules of
+// their own always fetch the pages, as the fields stored were extracted by other rules.
+func (p CrawlerService) fetchPage(
+	ctx context.Context,
+	pagerService pager.PagerUsecase,
+	uri string,
+	opts Options,
+) (Page, *pager.Certificate, error) {
+	rules := p.extractionRules(ctx)
+	previous, err := p.database.FindPage(ctx, uri, opts)
+	if err != nil || (previous.ETag == "" && previous.LastModified == "") || len(ExtractionRulesFromContext(ctx)) > 0 {
+		fetched, err := pagerService.GetPage(ctx, uri)
 
-			if _, found := fetched.Load(uri); !found {
-				wg.Add(1)
+		return newPage(fetched, err, rules), fetched.Certificate, err
+	}
 
-				go fetch(&wg, uri)
-				fetched.Store(uri, true)
-				links = append(links, uri)
-			}
-		}
+	fetched, err := pagerService.GetPageIfModified(ctx, uri, previous.validators())
+	page := newPage(fetched, err, rules)
+	if err == nil && page.NotModified {
+		metrics.NotModifiedPagesCounter.Inc()
+		page = page.reuse(previous)
 	}
 
-	go func() {
-		wg.Wait()
-		close(ch)
-	}()
+	return page, fetched.Certificate, err
+}
 
-	if err := p.database.Insert(ctx, uri, depth, links); err != nil {
-		log.Error("error inserting data into database", logger.FieldError(err))
+// extractionRules returns the rules the service is configured with followed by the ones of the crawl, so
+// the latter take precedence over fields of the same name.
+func (p CrawlerService) extractionRules(ctx context.Context) []ExtractionRule {
+	return append(append(make([]ExtractionRule, 0, len(p.rules)), p.rules...), ExtractionRulesFromContext(ctx)...)
+}
+
+func reportRedirects(page Page) {
+	if len(page.Redirects) == 0 {
+		return
+	}
+
+	metrics.RedirectsCounter.Add(float64(len(page.Redirects)))
+	fields := []zap.Field{zap.String("uri", page.URI), zap.Int("hops", len(page.Redirects))}
+	if page.Error != "" {
+		fields = append(fields, zap.String("error", page.Error))
+	}
+	if page.RedirectLoop {
+		metrics.RedirectLoopsCounter.Inc()
+		log.Warn("redirect loop detected", fields...)
+
+		return
 	}
 
-	return links, nil
+	log.Info("page reached through redirects", fields...)
 }
 
 func extractAddresses(links []string, node *html.Node) []string {
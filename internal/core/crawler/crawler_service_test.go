@@ -1,15 +1,28 @@
-package crawler
+package crawler_test
 
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	core "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/warc"
 	"github.com/hiago-balbino/web-crawler/v2/test/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"golang.org/x/net/html"
 )
 
+const (
+	linkTag  = "a"
+	hrefProp = "href"
+)
+
 func TestCrawlerService_Craw(t *testing.T) {
 	ctx := context.Background()
 	URI := "https://anyurl.com"
@@ -20,44 +33,50 @@ func TestCrawlerService_Craw(t *testing.T) {
 	unexpectedErr := errors.New("unexpected error")
 
 	testCases := map[string]func(*testing.T, *mocks.PagerUsecaseMock, *mocks.CrawlerDatabaseMock){
-		"should return error to GetNode from pager provider": func(t *testing.T, pagerMock *mocks.PagerUsecaseMock, databaseMock *mocks.CrawlerDatabaseMock) {
+		"should return error to GetPage from pager provider": func(t *testing.T, pagerMock *mocks.PagerUsecaseMock, databaseMock *mocks.CrawlerDatabaseMock) {
 			depth := uint(1)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			node := &html.Node{}
-			pagerMock.On("GetNode", URI).Return(node, unexpectedErr)
-			databaseMock.On("Insert", ctx, URI, depth, []string{}).Return(nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, unexpectedErr)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.EqualError(t, err, unexpectedErr.Error())
-			assert.Empty(t, links)
+			assert.Empty(t, crawl.Links)
 		},
 		"should return empty when node is nil": func(t *testing.T, pagerMock *mocks.PagerUsecaseMock, databaseMock *mocks.CrawlerDatabaseMock) {
 			depth := uint(1)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			var node *html.Node
-			pagerMock.On("GetNode", URI).Return(node, nil)
-			databaseMock.On("Insert", ctx, URI, depth, []string{}).Return(nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.NoError(t, err)
-			assert.Empty(t, links)
+			assert.Empty(t, crawl.Links)
 		},
 		"should return empty when not found link tag attribute": func(t *testing.T, pagerMock *mocks.PagerUsecaseMock, databaseMock *mocks.CrawlerDatabaseMock) {
 			depth := uint(1)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			node := &html.Node{Type: html.ElementNode}
-			pagerMock.On("GetNode", URI).Return(node, nil)
-			databaseMock.On("Insert", ctx, URI, depth, []string{}).Return(nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.NoError(t, err)
-			assert.Empty(t, links)
+			assert.Empty(t, crawl.Links)
 		},
 		"should return link fetched from provider when database returns error": func(t *testing.T, pagerMock *mocks.PagerUsecaseMock, databaseMock *mocks.CrawlerDatabaseMock) {
 			depth := uint(1)
@@ -66,51 +85,56 @@ func TestCrawlerService_Craw(t *testing.T) {
 				Data: linkTag,
 				Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}},
 			}
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
-			pagerMock.On("GetNode", URI).Return(node, nil)
-			pagerMock.On("GetNode", internalURI).Return(&html.Node{}, nil)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: &html.Node{}}, nil)
 			uris := []string{internalURI}
-			databaseMock.On("Insert", ctx, URI, depth, uris).Return(unexpectedErr)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(unexpectedErr)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
-			databaseMock.AssertCalled(t, "Find", ctx, URI, depth)
+			databaseMock.AssertCalled(t, "Find", ctx, URI, depth, core.Options{})
 			assert.NoError(t, err)
-			assert.ElementsMatch(t, uris, links)
+			assert.ElementsMatch(t, uris, crawl.Links)
 		},
 		"should return link from database": func(t *testing.T, pagerMock *mocks.PagerUsecaseMock, databaseMock *mocks.CrawlerDatabaseMock) {
 			depth := uint(1)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{internalURI}, nil)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{URI: URI, Depth: depth, Links: []string{internalURI}}, nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			uris := []string{internalURI}
-			databaseMock.AssertNotCalled(t, "Insert", ctx, URI, depth, uris)
-			databaseMock.AssertCalled(t, "Find", ctx, URI, depth)
-			pagerMock.AssertNotCalled(t, "GetNode", URI)
+			databaseMock.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+			databaseMock.AssertCalled(t, "Find", ctx, URI, depth, core.Options{})
+			pagerMock.AssertNotCalled(t, "GetPage", mock.Anything, URI)
 			assert.NoError(t, err)
-			assert.ElementsMatch(t, uris, links)
+			assert.ElementsMatch(t, uris, crawl.Links)
+			assert.True(t, crawl.FromStorage)
 		},
 		"should return link when have only one attribute": func(t *testing.T, pagerMock *mocks.PagerUsecaseMock, databaseMock *mocks.CrawlerDatabaseMock) {
 			depth := uint(1)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			node := &html.Node{
 				Type: html.ElementNode,
 				Data: linkTag,
 				Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}},
 			}
-			pagerMock.On("GetNode", URI).Return(node, nil)
-			pagerMock.On("GetNode", internalURI).Return(&html.Node{}, nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: &html.Node{}}, nil)
 			uris := []string{internalURI}
-			databaseMock.On("Insert", ctx, URI, depth, uris).Return(nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.NoError(t, err)
-			assert.ElementsMatch(t, uris, links)
+			assert.ElementsMatch(t, uris, crawl.Links)
 		},
 		"should return only one link when have two attribute but the last item has invalid key property": func(
 			t *testing.T,
@@ -118,22 +142,24 @@ func TestCrawlerService_Craw(t *testing.T) {
 			databaseMock *mocks.CrawlerDatabaseMock,
 		) {
 			depth := uint(1)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			node := &html.Node{
 				Type: html.ElementNode,
 				Data: linkTag,
 				Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}, {Key: "class", Val: "name"}},
 			}
-			pagerMock.On("GetNode", URI).Return(node, nil)
-			pagerMock.On("GetNode", internalURI).Return(&html.Node{}, nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: &html.Node{}}, nil)
 			uris := []string{internalURI}
-			databaseMock.On("Insert", ctx, URI, depth, uris).Return(nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.NoError(t, err)
-			assert.ElementsMatch(t, uris, links)
+			assert.ElementsMatch(t, uris, crawl.Links)
 		},
 		"should return only one link when have two attribute but the last item has invalid val link property": func(
 			t *testing.T,
@@ -141,26 +167,30 @@ func TestCrawlerService_Craw(t *testing.T) {
 			databaseMock *mocks.CrawlerDatabaseMock,
 		) {
 			depth := uint(1)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			node := &html.Node{
 				Type: html.ElementNode,
 				Data: linkTag,
 				Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}, {Key: hrefProp, Val: "index.html"}},
 			}
-			pagerMock.On("GetNode", URI).Return(node, nil)
-			pagerMock.On("GetNode", internalURI).Return(&html.Node{}, nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: &html.Node{}}, nil)
 			uris := []string{internalURI}
-			databaseMock.On("Insert", ctx, URI, depth, uris).Return(nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.NoError(t, err)
-			assert.ElementsMatch(t, uris, links)
+			assert.ElementsMatch(t, uris, crawl.Links)
 		},
 		"should return links when have two valid attributes": func(t *testing.T, pagerMock *mocks.PagerUsecaseMock, databaseMock *mocks.CrawlerDatabaseMock) {
 			depth := uint(1)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			node := &html.Node{
 				Type: html.ElementNode,
 				Data: linkTag,
@@ -169,17 +199,17 @@ func TestCrawlerService_Craw(t *testing.T) {
 					{Key: hrefProp, Val: randomInternalURI},
 				},
 			}
-			pagerMock.On("GetNode", URI).Return(node, nil)
-			pagerMock.On("GetNode", internalURI).Return(&html.Node{}, nil)
-			pagerMock.On("GetNode", randomInternalURI).Return(&html.Node{}, nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: &html.Node{}}, nil)
+			pagerMock.On("GetPage", mock.Anything, randomInternalURI).Return(pager.Page{URI: randomInternalURI, Node: &html.Node{}}, nil)
 			uris := []string{internalURI, randomInternalURI}
-			databaseMock.On("Insert", ctx, URI, depth, uris).Return(nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.NoError(t, err)
-			assert.ElementsMatch(t, uris, links)
+			assert.ElementsMatch(t, uris, crawl.Links)
 		},
 		"should return links from parent and child node when first child also have next sibling": func(
 			t *testing.T,
@@ -187,7 +217,9 @@ func TestCrawlerService_Craw(t *testing.T) {
 			databaseMock *mocks.CrawlerDatabaseMock,
 		) {
 			depth := uint(1)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			node := &html.Node{
 				Type: html.ElementNode,
 				Data: linkTag,
@@ -203,18 +235,18 @@ func TestCrawlerService_Craw(t *testing.T) {
 					},
 				},
 			}
-			pagerMock.On("GetNode", URI).Return(node, nil)
-			pagerMock.On("GetNode", internalURI).Return(&html.Node{}, nil)
-			pagerMock.On("GetNode", randomInternalURI).Return(&html.Node{}, nil)
-			pagerMock.On("GetNode", lastInternalURI).Return(&html.Node{}, nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: &html.Node{}}, nil)
+			pagerMock.On("GetPage", mock.Anything, randomInternalURI).Return(pager.Page{URI: randomInternalURI, Node: &html.Node{}}, nil)
+			pagerMock.On("GetPage", mock.Anything, lastInternalURI).Return(pager.Page{URI: lastInternalURI, Node: &html.Node{}}, nil)
 			uris := []string{internalURI, randomInternalURI, lastInternalURI}
-			databaseMock.On("Insert", ctx, URI, depth, uris).Return(nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.NoError(t, err)
-			assert.ElementsMatch(t, uris, links)
+			assert.ElementsMatch(t, uris, crawl.Links)
 		},
 		"should return links from parent and child node but will break when empty URIs": func(
 			t *testing.T,
@@ -222,7 +254,9 @@ func TestCrawlerService_Craw(t *testing.T) {
 			databaseMock *mocks.CrawlerDatabaseMock,
 		) {
 			depth := uint(2)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			node := &html.Node{
 				Type: html.ElementNode,
 				Data: linkTag,
@@ -233,17 +267,17 @@ func TestCrawlerService_Craw(t *testing.T) {
 					Attr: []html.Attribute{{Key: hrefProp, Val: randomInternalURI}},
 				},
 			}
-			pagerMock.On("GetNode", URI).Return(node, nil)
-			pagerMock.On("GetNode", internalURI).Return(&html.Node{}, nil)
-			pagerMock.On("GetNode", randomInternalURI).Return(&html.Node{}, nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: &html.Node{}}, nil)
+			pagerMock.On("GetPage", mock.Anything, randomInternalURI).Return(pager.Page{URI: randomInternalURI, Node: &html.Node{}}, nil)
 			uris := []string{internalURI, randomInternalURI}
-			databaseMock.On("Insert", ctx, URI, depth, uris).Return(nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.NoError(t, err)
-			assert.ElementsMatch(t, uris, links)
+			assert.ElementsMatch(t, uris, crawl.Links)
 		},
 		"should return links from first and second node and need to ignore the third node to respect depth": func(
 			t *testing.T,
@@ -251,7 +285,9 @@ func TestCrawlerService_Craw(t *testing.T) {
 			databaseMock *mocks.CrawlerDatabaseMock,
 		) {
 			depth := uint(2)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			firstNode := &html.Node{
 				Type: html.ElementNode,
 				Data: linkTag,
@@ -267,17 +303,191 @@ func TestCrawlerService_Craw(t *testing.T) {
 				Data: linkTag,
 				Attr: []html.Attribute{{Key: hrefProp, Val: lastInternalURI}},
 			}
-			pagerMock.On("GetNode", URI).Return(firstNode, nil)
-			pagerMock.On("GetNode", internalURI).Return(secondNode, nil)
-			pagerMock.On("GetNode", randomInternalURI).Return(thirdNode, nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: firstNode}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: secondNode}, nil)
+			pagerMock.On("GetPage", mock.Anything, randomInternalURI).Return(pager.Page{URI: randomInternalURI, Node: thirdNode}, nil)
 			uris := []string{internalURI, randomInternalURI}
-			databaseMock.On("Insert", ctx, URI, depth, uris).Return(nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, uris, crawl.Links)
+		},
+		"should keep crawling and record redirect chain when page fails by redirect policy": func(
+			t *testing.T,
+			pagerMock *mocks.PagerUsecaseMock,
+			databaseMock *mocks.CrawlerDatabaseMock,
+		) {
+			depth := uint(2)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
+			node := &html.Node{
+				Type: html.ElementNode,
+				Data: linkTag,
+				Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}},
+			}
+			redirects := []pager.Redirect{
+				{URI: internalURI, StatusCode: http.StatusMovedPermanently, Location: randomInternalURI},
+				{URI: randomInternalURI, StatusCode: http.StatusMovedPermanently, Location: internalURI},
+			}
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Redirects: redirects}, pager.ErrRedirectLoop)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.NoError(t, err)
-			assert.ElementsMatch(t, uris, links)
+			assert.ElementsMatch(t, []string{internalURI}, crawl.Links)
+			assert.Len(t, crawl.Pages, 2)
+			assert.Equal(t, []core.Page{{
+				URI:          internalURI,
+				Depth:        1,
+				Redirects:    redirects,
+				RedirectLoop: true,
+				Error:        pager.ErrRedirectLoop.Error(),
+				Links:        []string{},
+			}}, crawl.RedirectedPages())
+		},
+		"should return error when fail to authenticate the crawl": func(t *testing.T, pagerMock *mocks.PagerUsecaseMock, databaseMock *mocks.CrawlerDatabaseMock) {
+			depth := uint(1)
+			opts := core.Options{AuthProfile: "staging"}
+			databaseMock.On("Find", ctx, URI, depth, opts).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, opts).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, opts).Return(core.Page{}, unexpectedErr)
+			pagerMock.On("Authenticate", mock.Anything, opts.AuthProfile).Return(nil, pager.ErrUnknownAuthProfile)
+
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, opts)
+
+			assert.ErrorIs(t, err, pager.ErrUnknownAuthProfile)
+			assert.Empty(t, crawl.Links)
+			pagerMock.AssertNotCalled(t, "GetPage", mock.Anything, URI)
+		},
+		"should fetch pages with the authenticated pager when profile is given": func(
+			t *testing.T,
+			pagerMock *mocks.PagerUsecaseMock,
+			databaseMock *mocks.CrawlerDatabaseMock,
+		) {
+			depth := uint(1)
+			opts := core.Options{AuthProfile: "staging"}
+			databaseMock.On("Find", ctx, URI, depth, opts).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, opts).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, opts).Return(core.Page{}, unexpectedErr)
+			node := &html.Node{
+				Type: html.ElementNode,
+				Data: linkTag,
+				Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}},
+			}
+			authenticatedMock := new(mocks.PagerUsecaseMock)
+			pagerMock.On("Authenticate", mock.Anything, opts.AuthProfile).Return(authenticatedMock, nil)
+			authenticatedMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+			authenticatedMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: &html.Node{}}, nil)
+			databaseMock.On("Insert", mock.Anything, mock.MatchedBy(func(crawl core.Crawl) bool { return crawl.Options == opts })).Return(nil)
+
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, opts)
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []string{internalURI}, crawl.Links)
+			pagerMock.AssertNotCalled(t, "GetPage", mock.Anything, URI)
+		},
+		"should record the certificate of each host once": func(t *testing.T, pagerMock *mocks.PagerUsecaseMock, databaseMock *mocks.CrawlerDatabaseMock) {
+			depth := uint(2)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
+			node := &html.Node{
+				Type: html.ElementNode,
+				Data: linkTag,
+				Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}},
+			}
+			childNode := &html.Node{
+				Type: html.ElementNode,
+				Data: linkTag,
+				Attr: []html.Attribute{{Key: hrefProp, Val: randomInternalURI}},
+			}
+			expiring := &pager.Certificate{Host: "anyurl.com", NotAfter: time.Now().Add(time.Hour)}
+			valid := &pager.Certificate{Host: "internal-anyurl.com", NotAfter: time.Now().Add(365 * 24 * time.Hour)}
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node, Certificate: expiring}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: childNode, Certificate: valid}, nil)
+			pagerMock.On("GetPage", mock.Anything, randomInternalURI).Return(pager.Page{URI: randomInternalURI, Node: &html.Node{}, Certificate: valid}, nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, []pager.Certificate{*expiring, *valid}, crawl.Certificates)
+			assert.Equal(t, []pager.Certificate{*expiring}, crawl.ExpiringCertificates(time.Now(), 24*time.Hour))
+		},
+		"should reuse links of not modified page stored by previous crawl": func(
+			t *testing.T,
+			pagerMock *mocks.PagerUsecaseMock,
+			databaseMock *mocks.CrawlerDatabaseMock,
+		) {
+			depth := uint(1)
+			previous := core.Page{URI: URI, ETag: `"v1"`, ContentHash: "hash", BodyKey: "hash", Links: []string{internalURI, randomInternalURI}}
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, URI, core.Options{}).Return(previous, nil)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
+			validators := pager.Validators{ETag: `"v1"`}
+			notModified := pager.Page{URI: URI, StatusCode: http.StatusNotModified, NotModified: true}
+			pagerMock.On("GetPageIfModified", mock.Anything, URI, validators).Return(notModified, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: &html.Node{}}, nil)
+			pagerMock.On("GetPage", mock.Anything, randomInternalURI).Return(pager.Page{URI: randomInternalURI, Node: &html.Node{}}, nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []string{internalURI, randomInternalURI}, crawl.Links)
+			assert.True(t, crawl.Pages[0].NotModified)
+			assert.Equal(t, "hash", crawl.Pages[0].ContentHash)
+			assert.Equal(t, "hash", crawl.Pages[0].BodyKey)
+			assert.Equal(t, `"v1"`, crawl.Pages[0].ETag)
+			pagerMock.AssertNotCalled(t, "GetPage", mock.Anything, URI)
+		},
+		"should extend stored shallower crawl by fetching only its frontier": func(
+			t *testing.T,
+			pagerMock *mocks.PagerUsecaseMock,
+			databaseMock *mocks.CrawlerDatabaseMock,
+		) {
+			depth := uint(2)
+			shallower := core.Crawl{
+				URI:   URI,
+				Depth: 1,
+				Links: []string{internalURI},
+				Hops:  map[string]uint{internalURI: 1},
+				Pages: []core.Page{{URI: URI, Links: []string{internalURI}}},
+			}
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(shallower, nil)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
+			node := &html.Node{
+				Type: html.ElementNode,
+				Data: linkTag,
+				Attr: []html.Attribute{{Key: hrefProp, Val: URI}, {Key: hrefProp, Val: randomInternalURI}},
+			}
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: node}, nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, []string{internalURI, randomInternalURI}, crawl.Links)
+			assert.Equal(t, map[string]uint{internalURI: 1, randomInternalURI: 2}, crawl.Hops)
+			assert.Len(t, crawl.Pages, 2)
+			assert.Equal(t, uint(1), crawl.Pages[1].Depth)
+			assert.Equal(t, depth, crawl.Depth)
+			pagerMock.AssertNotCalled(t, "GetPage", mock.Anything, URI)
+			pagerMock.AssertNotCalled(t, "GetPage", mock.Anything, randomInternalURI)
 		},
 		"should return links from first and second node considering when node has more than one attributes and need to respect depth": func(
 			t *testing.T,
@@ -285,7 +495,9 @@ func TestCrawlerService_Craw(t *testing.T) {
 			databaseMock *mocks.CrawlerDatabaseMock,
 		) {
 			depth := uint(2)
-			databaseMock.On("Find", ctx, URI, depth).Return([]string{}, unexpectedErr)
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindShallower", ctx, URI, depth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
 			firstNode := &html.Node{
 				Type: html.ElementNode,
 				Data: linkTag,
@@ -301,18 +513,18 @@ func TestCrawlerService_Craw(t *testing.T) {
 				Data: linkTag,
 				Attr: []html.Attribute{{Key: hrefProp, Val: lastInternalURI}},
 			}
-			pagerMock.On("GetNode", URI).Return(firstNode, nil)
-			pagerMock.On("GetNode", internalURI).Return(secondNode, nil)
-			pagerMock.On("GetNode", randomInternalURI).Return(thirdNode, nil)
-			pagerMock.On("GetNode", subInternalURI).Return(&html.Node{}, nil)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: firstNode}, nil)
+			pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI, Node: secondNode}, nil)
+			pagerMock.On("GetPage", mock.Anything, randomInternalURI).Return(pager.Page{URI: randomInternalURI, Node: thirdNode}, nil)
+			pagerMock.On("GetPage", mock.Anything, subInternalURI).Return(pager.Page{URI: subInternalURI, Node: &html.Node{}}, nil)
 			uris := []string{internalURI, randomInternalURI, subInternalURI}
-			databaseMock.On("Insert", ctx, URI, depth, uris).Return(nil)
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
 
-			crawler := NewCrawlerService(pagerMock, databaseMock)
-			links, err := crawler.Craw(ctx, URI, depth)
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
 
 			assert.NoError(t, err)
-			assert.ElementsMatch(t, uris, links)
+			assert.ElementsMatch(t, uris, crawl.Links)
 		},
 	}
 
@@ -325,3 +537,653 @@ func TestCrawlerService_Craw(t *testing.T) {
 		})
 	}
 }
+
+func TestCrawlerService_Craw_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	URI := "https://anyurl.com"
+	internalURI := "https://internal-anyurl.com"
+	depth := uint(1)
+	notFound := errors.New("not found")
+	pagerMock := new(mocks.PagerUsecaseMock)
+	databaseMock := new(mocks.CrawlerDatabaseMock)
+
+	joined := make(chan struct{})
+	release := make(chan struct{})
+	lookups := atomic.Int32{}
+	databaseMock.On("Find", mock.Anything, URI, depth, core.Options{}).Return(core.Crawl{}, notFound)
+	databaseMock.On("FindShallower", mock.Anything, URI, depth, core.Options{}).Return(core.Crawl{}, notFound).Run(func(mock.Arguments) {
+		if lookups.Add(1) == 2 {
+			close(joined)
+		}
+	})
+	databaseMock.On("FindPage", mock.Anything, URI, core.Options{}).Return(core.Page{}, notFound)
+	node := &html.Node{Type: html.ElementNode, Data: linkTag, Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}}}
+	pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil).Once().Run(func(mock.Arguments) {
+		<-release
+	})
+	databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil).Once()
+
+	crawler := core.NewCrawlerService(pagerMock, databaseMock)
+	results := make(chan core.Crawl, 2)
+	for range 2 {
+		go func() {
+			crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
+			assert.NoError(t, err)
+			results <- crawl
+		}()
+	}
+
+	<-joined
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	first, second := <-results, <-results
+
+	assert.Equal(t, []string{internalURI}, first.Links)
+	assert.Equal(t, first, second)
+	pagerMock.AssertNumberOfCalls(t, "GetPage", 1)
+	databaseMock.AssertNumberOfCalls(t, "Insert", 1)
+}
+
+func TestCrawlerService_Craw_ConcurrentCancel(t *testing.T) {
+	URI := "https://anyurl.com"
+	internalURI := "https://internal-anyurl.com"
+	depth := uint(1)
+	notFound := errors.New("not found")
+	pagerMock := new(mocks.PagerUsecaseMock)
+	databaseMock := new(mocks.CrawlerDatabaseMock)
+
+	joined := make(chan struct{})
+	release := make(chan struct{})
+	lookups := atomic.Int32{}
+	databaseMock.On("Find", mock.Anything, URI, depth, core.Options{}).Return(core.Crawl{}, notFound)
+	databaseMock.On("FindShallower", mock.Anything, URI, depth, core.Options{}).Return(core.Crawl{}, notFound).Run(func(mock.Arguments) {
+		if lookups.Add(1) == 2 {
+			close(joined)
+		}
+	})
+	databaseMock.On("FindPage", mock.Anything, URI, core.Options{}).Return(core.Page{}, notFound)
+	node := &html.Node{Type: html.ElementNode, Data: linkTag, Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}}}
+	pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil).Once().Run(func(mock.Arguments) {
+		<-release
+	})
+	databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil).Once()
+
+	crawler := core.NewCrawlerService(pagerMock, databaseMock)
+	cancelled, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := crawler.Craw(cancelled, URI, depth, core.Options{})
+		errs <- err
+	}()
+	results := make(chan core.Crawl, 1)
+	go func() {
+		crawl, err := crawler.Craw(context.Background(), URI, depth, core.Options{})
+		assert.NoError(t, err)
+		results <- crawl
+	}()
+
+	<-joined
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	close(release)
+
+	assert.Equal(t, []string{internalURI}, (<-results).Links)
+	pagerMock.AssertNumberOfCalls(t, "GetPage", 1)
+}
+
+func TestCrawlerService_Refresh_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	URI := "https://anyurl.com"
+	internalURI := "https://internal-anyurl.com"
+	depth := uint(2)
+	notFound := errors.New("not found")
+	shallower := core.Crawl{
+		URI:   URI,
+		Depth: 1,
+		Links: []string{internalURI},
+		Hops:  map[string]uint{internalURI: 1},
+		Pages: []core.Page{{URI: URI, Links: []string{internalURI}}},
+	}
+	pagerMock := new(mocks.PagerUsecaseMock)
+	databaseMock := new(mocks.CrawlerDatabaseMock)
+
+	extending := make(chan struct{})
+	refreshing := make(chan struct{})
+	release := make(chan struct{})
+	fetches := atomic.Int32{}
+	databaseMock.On("Find", mock.Anything, URI, depth, core.Options{}).Return(core.Crawl{}, notFound)
+	databaseMock.On("FindShallower", mock.Anything, URI, depth, core.Options{}).Return(shallower, nil)
+	databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, notFound)
+	node := &html.Node{Type: html.ElementNode, Data: linkTag, Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}}}
+	pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil).Run(func(mock.Arguments) {
+		close(refreshing)
+	})
+	pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI}, nil).Run(func(mock.Arguments) {
+		if fetches.Add(1) == 1 {
+			close(extending)
+		}
+		<-release
+	})
+	databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+	crawler := core.NewCrawlerService(pagerMock, databaseMock)
+	extended := make(chan core.Crawl, 1)
+	go func() {
+		crawl, err := crawler.Craw(ctx, URI, depth, core.Options{})
+		assert.NoError(t, err)
+		extended <- crawl
+	}()
+	<-extending
+
+	refreshed := make(chan core.Crawl, 1)
+	go func() {
+		crawl, err := crawler.Refresh(ctx, URI, depth, core.Options{})
+		assert.NoError(t, err)
+		refreshed <- crawl
+	}()
+	select {
+	case <-refreshing:
+	case <-time.After(time.Second):
+		t.Error("refresh joined the crawl extending the stored one")
+	}
+	close(release)
+
+	assert.Equal(t, uint(1), (<-extended).Pages[1].Depth)
+	refresh := <-refreshed
+	assert.Equal(t, URI, refresh.Pages[0].URI)
+	assert.Equal(t, uint(0), refresh.Pages[0].Depth)
+	pagerMock.AssertNumberOfCalls(t, "GetPage", 3)
+	databaseMock.AssertNumberOfCalls(t, "Insert", 2)
+}
+
+func TestCrawlerService_Craw_ConcurrentRecorded(t *testing.T) {
+	URI := "https://anyurl.com"
+	depth := uint(1)
+	notFound := errors.New("not found")
+	writer, err := warc.NewWriter(t.TempDir(), "test", 0)
+	assert.NoError(t, err)
+	ctx := warc.NewContext(context.Background(), writer)
+	pagerMock := new(mocks.PagerUsecaseMock)
+	databaseMock := new(mocks.CrawlerDatabaseMock)
+
+	release := make(chan struct{})
+	fetches := atomic.Int32{}
+	databaseMock.On("Find", mock.Anything, URI, depth, core.Options{}).Return(core.Crawl{}, notFound)
+	databaseMock.On("FindShallower", mock.Anything, URI, depth, core.Options{}).Return(core.Crawl{}, notFound)
+	databaseMock.On("FindPage", mock.Anything, URI, core.Options{}).Return(core.Page{}, notFound)
+	pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI}, nil).Run(func(mock.Arguments) {
+		if fetches.Add(1) == 2 {
+			close(release)
+		}
+		select {
+		case <-release:
+		case <-time.After(time.Second):
+		}
+	})
+	databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+	crawler := core.NewCrawlerService(pagerMock, databaseMock)
+	done := make(chan struct{}, 2)
+	for range 2 {
+		go func() {
+			_, err := crawler.Craw(ctx, URI, depth, core.Options{})
+			assert.NoError(t, err)
+			done <- struct{}{}
+		}()
+	}
+	<-done
+	<-done
+
+	pagerMock.AssertNumberOfCalls(t, "GetPage", 2)
+}
+
+func TestCrawlerService_Craw_Recorded(t *testing.T) {
+	URI := "https://anyurl.com"
+	notFound := errors.New("not found")
+	writer, err := warc.NewWriter(t.TempDir(), "test", 0)
+	assert.NoError(t, err)
+	ctx := warc.NewContext(context.Background(), writer)
+	pagerMock := new(mocks.PagerUsecaseMock)
+	databaseMock := new(mocks.CrawlerDatabaseMock)
+	databaseMock.On("Find", mock.Anything, URI, uint(1), core.Options{}).Return(core.Crawl{URI: URI, Links: []string{URI}}, nil)
+	databaseMock.On("FindPage", mock.Anything, URI, core.Options{}).Return(core.Page{}, notFound)
+	pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI}, nil)
+	databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+	crawl, err := core.NewCrawlerService(pagerMock, databaseMock).Craw(ctx, URI, 1, core.Options{})
+
+	assert.NoError(t, err)
+	assert.False(t, crawl.FromStorage)
+	pagerMock.AssertNumberOfCalls(t, "GetPage", 1)
+	databaseMock.AssertNotCalled(t, "Find", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCrawlerService_Craw_Levels(t *testing.T) {
+	ctx := context.Background()
+	URI := "https://anyurl.com"
+	linksTo := func(uris ...string) *html.Node {
+		node := &html.Node{Type: html.ElementNode, Data: linkTag}
+		for _, uri := range uris {
+			node.Attr = append(node.Attr, html.Attribute{Key: hrefProp, Val: uri})
+		}
+
+		return node
+	}
+	graph := map[string][]string{
+		URI:                    {"https://a.anyurl.com", "https://b.anyurl.com"},
+		"https://a.anyurl.com": {"https://c.anyurl.com", "https://b.anyurl.com"},
+		"https://b.anyurl.com": {"https://c.anyurl.com", "https://d.anyurl.com", URI},
+		"https://c.anyurl.com": {"https://d.anyurl.com", "https://a.anyurl.com"},
+		"https://d.anyurl.com": {"https://e.anyurl.com", "https://c.anyurl.com"},
+	}
+	notFound := errors.New("not found")
+
+	t.Run("should fetch level by level keeping the shortest hop distance of each link", func(t *testing.T) {
+		pagerMock := new(mocks.PagerUsecaseMock)
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("Find", ctx, URI, uint(3), core.Options{}).Return(core.Crawl{}, notFound)
+		databaseMock.On("FindShallower", ctx, URI, uint(3), core.Options{}).Return(core.Crawl{}, notFound)
+		databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, notFound)
+		for uri, links := range graph {
+			pagerMock.On("GetPage", mock.Anything, uri).Return(pager.Page{URI: uri, Node: linksTo(links...)}, nil)
+		}
+		databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+		crawl, err := core.NewCrawlerService(pagerMock, databaseMock, core.WithConcurrency(1)).Craw(ctx, URI, 3, core.Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"https://a.anyurl.com", "https://b.anyurl.com", "https://c.anyurl.com", "https://d.anyurl.com", "https://e.anyurl.com",
+		}, crawl.Links)
+		assert.Equal(t, map[string]uint{
+			"https://a.anyurl.com": 1,
+			"https://b.anyurl.com": 1,
+			"https://c.anyurl.com": 2,
+			"https://d.anyurl.com": 2,
+			"https://e.anyurl.com": 3,
+		}, crawl.Hops)
+		depths := make(map[string]uint, len(crawl.Pages))
+		order := make([]string, 0, len(crawl.Pages))
+		for _, page := range crawl.Pages {
+			depths[page.URI] = page.Depth
+			order = append(order, page.URI)
+		}
+		assert.Equal(t, []string{
+			URI, "https://a.anyurl.com", "https://b.anyurl.com", "https://c.anyurl.com", "https://d.anyurl.com",
+		}, order)
+		assert.Equal(t, map[string]uint{
+			URI:                    0,
+			"https://a.anyurl.com": 1,
+			"https://b.anyurl.com": 1,
+			"https://c.anyurl.com": 2,
+			"https://d.anyurl.com": 2,
+		}, depths)
+		pagerMock.AssertNumberOfCalls(t, "GetPage", len(graph))
+		pagerMock.AssertNotCalled(t, "GetPage", mock.Anything, "https://e.anyurl.com")
+	})
+	t.Run("should not fetch more pages of a level at a time than the concurrency", func(t *testing.T) {
+		wide := make([]string, 0, 20)
+		for i := range 20 {
+			wide = append(wide, fmt.Sprintf("https://anyurl.com/%d", i))
+		}
+		fetching, highest := atomic.Int32{}, atomic.Int32{}
+		pagerMock := new(mocks.PagerUsecaseMock)
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, notFound)
+		pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: linksTo(wide...)}, nil)
+		for _, uri := range wide {
+			pagerMock.On("GetPage", mock.Anything, uri).Return(pager.Page{URI: uri}, nil).Run(func(mock.Arguments) {
+				current := fetching.Add(1)
+				for {
+					seen := highest.Load()
+					if current <= seen || highest.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				fetching.Add(-1)
+			})
+		}
+		databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+		crawl, err := core.NewCrawlerService(pagerMock, databaseMock, core.WithConcurrency(3)).Refresh(ctx, URI, 2, core.Options{})
+
+		assert.NoError(t, err)
+		assert.Len(t, crawl.Pages, 21)
+		assert.LessOrEqual(t, highest.Load(), int32(3))
+	})
+}
+
+func TestCrawlerService_Refresh(t *testing.T) {
+	ctx := context.Background()
+	URI := "https://anyurl.com"
+	internalURI := "https://internal-anyurl.com"
+	depth := uint(1)
+	pagerMock := new(mocks.PagerUsecaseMock)
+	databaseMock := new(mocks.CrawlerDatabaseMock)
+	node := &html.Node{Type: html.ElementNode, Data: linkTag, Attr: []html.Attribute{{Key: hrefProp, Val: internalURI}}}
+	databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, errors.New("not found"))
+	pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+	pagerMock.On("GetPage", mock.Anything, internalURI).Return(pager.Page{URI: internalURI}, nil).Maybe()
+	databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+	crawler := core.NewCrawlerService(pagerMock, databaseMock)
+	before := time.Now().UTC()
+	crawl, err := crawler.Refresh(ctx, URI, depth, core.Options{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{internalURI}, crawl.Links)
+	assert.False(t, crawl.FromStorage)
+	assert.False(t, crawl.CrawledAt.Before(before))
+	databaseMock.AssertNotCalled(t, "Find", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	databaseMock.AssertCalled(t, "Insert", mock.Anything, crawl)
+}
+
+func TestCrawlerService_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	id := "65a0f0f0f0f0f0f0f0f0f0f1"
+
+	t.Run("should return stored snapshot flagged as served from storage", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindSnapshot", ctx, id).Return(core.Crawl{URI: "https://anyurl.com", Depth: 1}, nil)
+
+		crawler := core.NewCrawlerService(new(mocks.PagerUsecaseMock), databaseMock)
+		crawl, err := crawler.Snapshot(ctx, id)
+
+		assert.NoError(t, err)
+		assert.True(t, crawl.FromStorage)
+		assert.Equal(t, "https://anyurl.com", crawl.URI)
+	})
+	t.Run("should return error when snapshot does not exist", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindSnapshot", ctx, id).Return(core.Crawl{}, core.ErrSnapshotNotFound)
+
+		crawler := core.NewCrawlerService(new(mocks.PagerUsecaseMock), databaseMock)
+		_, err := crawler.Snapshot(ctx, id)
+
+		assert.ErrorIs(t, err, core.ErrSnapshotNotFound)
+	})
+}
+
+func TestCrawl_Stale(t *testing.T) {
+	now := time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		crawl    core.Crawl
+		after    time.Duration
+		expected bool
+	}{
+		"should not be stale when younger than the duration": {
+			crawl:    core.Crawl{CrawledAt: now.Add(-time.Hour)},
+			after:    24 * time.Hour,
+			expected: false,
+		},
+		"should be stale when older than the duration": {
+			crawl:    core.Crawl{CrawledAt: now.Add(-48 * time.Hour)},
+			after:    24 * time.Hour,
+			expected: true,
+		},
+		"should be stale when crawl time is unknown": {
+			crawl:    core.Crawl{},
+			after:    24 * time.Hour,
+			expected: true,
+		},
+		"should never be stale when duration is zero": {
+			crawl:    core.Crawl{},
+			after:    0,
+			expected: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.crawl.Stale(now, tc.after))
+		})
+	}
+}
+
+func TestCrawl_Truncate(t *testing.T) {
+	URI := "https://anyurl.com"
+	internalURI := "https://internal-anyurl.com"
+	deepURI := "https://deep-anyurl.com"
+	deeperURI := "https://deeper-anyurl.com"
+	crawl := core.Crawl{
+		URI:   URI,
+		Depth: 3,
+		Links: []string{internalURI, deepURI, deeperURI},
+		Hops:  map[string]uint{internalURI: 1, deepURI: 2, deeperURI: 3},
+		Pages: []core.Page{
+			{URI: URI, Depth: 0},
+			{URI: internalURI, Depth: 1},
+			{URI: deepURI, Depth: 2},
+		},
+		Certificates: []pager.Certificate{{Host: "anyurl.com"}, {Host: "internal-anyurl.com"}, {Host: "deep-anyurl.com"}},
+	}
+
+	t.Run("should keep only links and pages within the smaller depth", func(t *testing.T) {
+		truncated := crawl.Truncate(2)
+
+		assert.Equal(t, uint(2), truncated.Depth)
+		assert.Equal(t, []string{internalURI, deepURI}, truncated.Links)
+		assert.Equal(t, map[string]uint{internalURI: 1, deepURI: 2}, truncated.Hops)
+		assert.Equal(t, []core.Page{{URI: URI, Depth: 0}, {URI: internalURI, Depth: 1}}, truncated.Pages)
+		assert.Equal(t, []pager.Certificate{{Host: "anyurl.com"}, {Host: "internal-anyurl.com"}}, truncated.Certificates)
+		assert.Len(t, crawl.Links, 3)
+	})
+	t.Run("should return crawl unchanged when depth is not smaller", func(t *testing.T) {
+		assert.Equal(t, crawl, crawl.Truncate(3))
+	})
+	t.Run("should return crawl unchanged when it has no hop distances", func(t *testing.T) {
+		legacy := core.Crawl{URI: URI, Depth: 3, Links: []string{internalURI}}
+
+		assert.Equal(t, legacy, legacy.Truncate(1))
+	})
+}
+
+func TestCrawlerService_Recraw(t *testing.T) {
+	ctx := context.Background()
+	URI := "https://anyurl.com"
+	keptURI := "https://kept-anyurl.com"
+	addedURI := "https://added-anyurl.com"
+	removedURI := "https://removed-anyurl.com"
+	depth := uint(2)
+	unexpectedErr := errors.New("unexpected error")
+	node := &html.Node{
+		Type: html.ElementNode,
+		Data: "div",
+		FirstChild: &html.Node{
+			Type: html.ElementNode,
+			Data: linkTag,
+			Attr: []html.Attribute{{Key: hrefProp, Val: keptURI}},
+			NextSibling: &html.Node{
+				Type: html.ElementNode,
+				Data: linkTag,
+				Attr: []html.Attribute{{Key: hrefProp, Val: addedURI}},
+			},
+		},
+	}
+
+	testCases := map[string]func(*testing.T, *mocks.PagerUsecaseMock, *mocks.CrawlerDatabaseMock){
+		"should crawl again and report changes since the stored crawl": func(
+			t *testing.T,
+			pagerMock *mocks.PagerUsecaseMock,
+			databaseMock *mocks.CrawlerDatabaseMock,
+		) {
+			previous := core.Crawl{
+				URI:   URI,
+				Depth: depth,
+				Links: []string{keptURI, removedURI},
+				Pages: []core.Page{{URI: URI, ContentHash: "old"}, {URI: removedURI, ContentHash: "removed"}},
+			}
+			databaseMock.On("Find", ctx, URI, depth, core.Options{}).Return(previous, nil)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, ContentHash: "new", Node: node}, nil)
+			pagerMock.On("GetPage", mock.Anything, keptURI).Return(pager.Page{URI: keptURI, ContentHash: "kept"}, nil)
+			pagerMock.On("GetPage", mock.Anything, addedURI).Return(pager.Page{URI: addedURI, ContentHash: "added"}, nil).Maybe()
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+			databaseMock.On("InsertDiff", ctx, mock.AnythingOfType("crawler.CrawlDiff")).Return(nil)
+
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			crawl, diff, err := crawler.Recraw(ctx, URI, depth, core.Options{})
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []string{keptURI, addedURI}, crawl.Links)
+			assert.False(t, diff.FirstCrawl)
+			assert.True(t, diff.HasChanges())
+			assert.Equal(t, []string{URI}, diff.ChangedPages)
+			assert.Equal(t, []string{removedURI}, diff.RemovedPages)
+			assert.Equal(t, []string{addedURI}, diff.AddedLinks)
+			assert.Equal(t, []string{removedURI}, diff.RemovedLinks)
+			databaseMock.AssertCalled(t, "InsertDiff", ctx, diff)
+		},
+		"should report everything as added when URI was never crawled": func(
+			t *testing.T,
+			pagerMock *mocks.PagerUsecaseMock,
+			databaseMock *mocks.CrawlerDatabaseMock,
+		) {
+			databaseMock.On("Find", ctx, URI, uint(1), core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, ContentHash: "new", Node: node}, nil)
+			pagerMock.On("GetPage", mock.Anything, mock.Anything).Return(pager.Page{}, nil).Maybe()
+			databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+			databaseMock.On("InsertDiff", ctx, mock.AnythingOfType("crawler.CrawlDiff")).Return(unexpectedErr)
+
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			_, diff, err := crawler.Recraw(ctx, URI, uint(1), core.Options{})
+
+			assert.NoError(t, err)
+			assert.True(t, diff.FirstCrawl)
+			assert.Equal(t, []string{URI}, diff.AddedPages)
+			assert.Equal(t, []string{addedURI, keptURI}, diff.AddedLinks)
+			assert.Empty(t, diff.RemovedLinks)
+		},
+		"should return error without diff when crawl fails": func(
+			t *testing.T,
+			pagerMock *mocks.PagerUsecaseMock,
+			databaseMock *mocks.CrawlerDatabaseMock,
+		) {
+			databaseMock.On("Find", ctx, URI, uint(1), core.Options{}).Return(core.Crawl{}, unexpectedErr)
+			databaseMock.On("FindPage", mock.Anything, mock.Anything, core.Options{}).Return(core.Page{}, unexpectedErr)
+			pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{}, unexpectedErr)
+
+			crawler := core.NewCrawlerService(pagerMock, databaseMock)
+			_, diff, err := crawler.Recraw(ctx, URI, uint(1), core.Options{})
+
+			assert.EqualError(t, err, unexpectedErr.Error())
+			assert.Empty(t, diff)
+			databaseMock.AssertNotCalled(t, "InsertDiff", mock.Anything, mock.Anything)
+		},
+	}
+
+	for name, run := range testCases {
+		t.Run(name, func(t *testing.T) {
+			pagerMock := new(mocks.PagerUsecaseMock)
+			databaseMock := new(mocks.CrawlerDatabaseMock)
+
+			run(t, pagerMock, databaseMock)
+		})
+	}
+}
+
+func TestCrawlerService_Reprocess(t *testing.T) {
+	ctx := context.Background()
+	URI := "https://anyurl.com"
+	internalURI := "https://internal-anyurl.com"
+	newURI := "https://new-anyurl.com"
+	deepURI := "https://deep-anyurl.com"
+	unexpectedErr := errors.New("unexpected error")
+	snapshot := core.Crawl{
+		URI:       URI,
+		Depth:     2,
+		CrawledAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Links:     []string{internalURI, deepURI},
+		Hops:      map[string]uint{internalURI: 1, deepURI: 2},
+		Pages: []core.Page{
+			{URI: URI, Depth: 0, ContentHash: "root", BodyKey: "root", Links: []string{internalURI}},
+			{URI: internalURI, Depth: 1, ContentHash: "internal", Links: []string{deepURI}},
+		},
+	}
+
+	t.Run("should extract again from the archived bodies and store a new snapshot", func(t *testing.T) {
+		pagerMock := new(mocks.PagerUsecaseMock)
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		archiveMock := new(mocks.BodyArchiveMock)
+		databaseMock.On("FindSnapshot", ctx, "1").Return(snapshot, nil)
+		body := `<a href="` + internalURI + `">internal</a><a href="` + newURI + `">new</a>`
+		archiveMock.On("Load", ctx, "root").Return([]byte(body), nil)
+		databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+		crawler := core.NewCrawlerService(pagerMock, databaseMock, core.WithBodyArchive(archiveMock))
+		crawl, err := crawler.Reprocess(ctx, "1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{internalURI, newURI}, crawl.Pages[0].Links)
+		assert.Equal(t, []string{deepURI}, crawl.Pages[1].Links)
+		assert.Equal(t, []string{internalURI, newURI, deepURI}, crawl.Links)
+		assert.Equal(t, map[string]uint{internalURI: 1, newURI: 1, deepURI: 2}, crawl.Hops)
+		assert.True(t, crawl.CrawledAt.After(snapshot.CrawledAt))
+		databaseMock.AssertCalled(t, "Insert", mock.Anything, crawl)
+		pagerMock.AssertNotCalled(t, "GetPage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should decode the archived body with the charset it was fetched with", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		archiveMock := new(mocks.BodyArchiveMock)
+		latin1 := core.Crawl{URI: URI, Depth: 1, Pages: []core.Page{{URI: URI, BodyKey: "latin1", Charset: "windows-1252"}}}
+		databaseMock.On("FindSnapshot", ctx, "3").Return(latin1, nil)
+		archiveMock.On("Load", ctx, "latin1").Return([]byte("<title>P\xe1gina</title>"), nil)
+		databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+		crawler := core.NewCrawlerService(nil, databaseMock, core.WithBodyArchive(archiveMock))
+		crawl, err := crawler.Reprocess(ctx, "3")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Página", crawl.Pages[0].Metadata.Title)
+		assert.Equal(t, "windows-1252", crawl.Pages[0].Charset)
+	})
+
+	t.Run("should keep the page as stored when its body is missing from the archive", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		archiveMock := new(mocks.BodyArchiveMock)
+		databaseMock.On("FindSnapshot", ctx, "1").Return(snapshot, nil)
+		archiveMock.On("Load", ctx, "root").Return(nil, pager.ErrBodyNotArchived)
+		databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+		crawler := core.NewCrawlerService(nil, databaseMock, core.WithBodyArchive(archiveMock))
+		crawl, err := crawler.Reprocess(ctx, "1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, snapshot.Links, crawl.Links)
+		assert.Equal(t, snapshot.Pages, crawl.Pages)
+	})
+
+	t.Run("should return error when the archive fails", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		archiveMock := new(mocks.BodyArchiveMock)
+		databaseMock.On("FindSnapshot", ctx, "1").Return(snapshot, nil)
+		archiveMock.On("Load", ctx, "root").Return(nil, unexpectedErr)
+
+		crawler := core.NewCrawlerService(nil, databaseMock, core.WithBodyArchive(archiveMock))
+		_, err := crawler.Reprocess(ctx, "1")
+
+		assert.ErrorIs(t, err, unexpectedErr)
+		databaseMock.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return error when the snapshot does not exist", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindSnapshot", ctx, "2").Return(core.Crawl{}, core.ErrSnapshotNotFound)
+
+		crawler := core.NewCrawlerService(nil, databaseMock, core.WithBodyArchive(new(mocks.BodyArchiveMock)))
+		_, err := crawler.Reprocess(ctx, "2")
+
+		assert.ErrorIs(t, err, core.ErrSnapshotNotFound)
+	})
+
+	t.Run("should return error when there is no body archive", func(t *testing.T) {
+		crawler := core.NewCrawlerService(nil, new(mocks.CrawlerDatabaseMock))
+		_, err := crawler.Reprocess(ctx, "1")
+
+		assert.ErrorIs(t, err, core.ErrNoBodyArchive)
+	})
+}
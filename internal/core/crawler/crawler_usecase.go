@@ -3,5 +3,11 @@ package crawler
 import "context"
 
 type CrawlerUsecase interface {
-	Craw(ctx context.Context, uri string, depth uint) ([]string, error)
+	Craw(ctx context.Context, uri string, depth uint, opts Options) (Crawl, error)
+	Refresh(ctx context.Context, uri string, depth uint, opts Options) (Crawl, error)
+	Recraw(ctx context.Context, uri string, depth uint, opts Options) (Crawl, CrawlDiff, error)
+	History(ctx context.Context, uri string, opts Options) ([]Snapshot, error)
+	Snapshot(ctx context.Context, id string) (Crawl, error)
+	Reprocess(ctx context.Context, id string) (Crawl, error)
+	Search(ctx context.Context, id, query string) ([]SearchResult, error)
 }
@@ -0,0 +1,210 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidExtractionRule is returned when an extraction rule cannot be used to extract fields.
+var ErrInvalidExtractionRule = errors.New("invalid extraction rule")
+
+// ExtractionRule extracts named fields from the pages whose URI matches the pattern, a regular expression
+// where empty matches every page.
+type ExtractionRule struct {
+	Name       string      `yaml:"name"`
+	URLPattern string      `yaml:"url_pattern"`
+	Fields     []FieldRule `yaml:"fields"`
+
+	pattern *regexp.Regexp
+}
+
+// FieldRule finds the value of a field by a CSS selector or an XPath expression, being the value the text
+// of the element found or, when the attribute is filled, its attribute. Only the first element found is
+// taken unless all of them are asked for.
+type FieldRule struct {
+	Name      string `yaml:"name"`
+	CSS       string `yaml:"css"`
+	XPath     string `yaml:"xpath"`
+	Attribute string `yaml:"attribute"`
+	All       bool   `yaml:"all"`
+
+	selector   cascadia.Sel
+	expression *xpath.Expr
+}
+
+type extractionRulesFile struct {
+	Rules []ExtractionRule `yaml:"rules"`
+}
+
+type extractionRulesKey struct{}
+
+// LoadExtractionRules reads the extraction rules from a YAML or JSON file.
+func LoadExtractionRules(file string) ([]ExtractionRule, error) {
+	content, err := os.ReadFile(file) //nolint:gosec // the file is given by configuration
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseExtractionRules(content)
+}
+
+// ParseExtractionRules parses the extraction rules given as YAML or JSON, compiling their patterns,
+// selectors and expressions.
+func ParseExtractionRules(content []byte) ([]ExtractionRule, error) {
+	var rulesFile extractionRulesFile
+	if err := yaml.Unmarshal(content, &rulesFile); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidExtractionRule, err)
+	}
+
+	rules := make([]ExtractionRule, 0, len(rulesFile.Rules))
+	for _, rule := range rulesFile.Rules {
+		compiled, err := rule.compile()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, compiled)
+	}
+
+	return rules, nil
+}
+
+func (r ExtractionRule) compile() (ExtractionRule, error) {
+	pattern, err := regexp.Compile(r.URLPattern)
+	if err != nil {
+		return ExtractionRule{}, fmt.Errorf("%w %q: %w", ErrInvalidExtractionRule, r.Name, err)
+	}
+	r.pattern = pattern
+
+	fields := make([]FieldRule, 0, len(r.Fields))
+	for _, field := range r.Fields {
+		compiled, err := field.compile()
+		if err != nil {
+			return ExtractionRule{}, fmt.Errorf("%w %q, field %q: %w", ErrInvalidExtractionRule, r.Name, field.Name, err)
+		}
+		fields = append(fields, compiled)
+	}
+	r.Fields = fields
+
+	return r, nil
+}
+
+func (f FieldRule) compile() (FieldRule, error) {
+	var err error
+	switch {
+	case f.Name == "":
+		return FieldRule{}, errors.New("the name is required")
+	case (f.CSS == "") == (f.XPath == ""):
+		return FieldRule{}, errors.New("either a CSS selector or an XPath expression is required")
+	case f.CSS != "":
+		f.selector, err = cascadia.Parse(f.CSS)
+	default:
+		f.expression, err = xpath.Compile(f.XPath)
+	}
+
+	return f, err
+}
+
+// NewExtractionContext returns a context carrying the extraction rules of a single crawl, applied along
+// with the ones the service is configured with.
+func NewExtractionContext(ctx context.Context, rules []ExtractionRule) context.Context {
+	return context.WithValue(ctx, extractionRulesKey{}, rules)
+}
+
+// ExtractionRulesFromContext returns the extraction rules carried by the context, if any.
+func ExtractionRulesFromContext(ctx context.Context) []ExtractionRule {
+	rules, _ := ctx.Value(extractionRulesKey{}).([]ExtractionRule)
+
+	return rules
+}
+
+// extractFields applies the rules matching the URI to the page, the field found by a later rule
+// replacing the one of the same name found by an earlier rule.
+func extractFields(uri string, node *html.Node, rules []ExtractionRule) map[string][]string {
+	if node == nil {
+		return nil
+	}
+
+	var fields map[string][]string
+	for _, rule := range rules {
+		if rule.pattern == nil || !rule.pattern.MatchString(uri) {
+			continue
+		}
+		for _, field := range rule.Fields {
+			values := field.values(node)
+			if len(values) == 0 {
+				continue
+			}
+			if fields == nil {
+				fields = make(map[string][]string)
+			}
+			fields[field.Name] = values
+		}
+	}
+
+	return fields
+}
+
+func (f FieldRule) values(node *html.Node) []string {
+	var values []string
+	add := func(value string) bool {
+		if value != "" {
+			values = append(values, value)
+		}
+
+		return f.All || len(values) == 0
+	}
+
+	if f.selector != nil {
+		for _, found := range cascadia.QueryAll(node, f.selector) {
+			if !add(f.value(found)) {
+				break
+			}
+		}
+
+		return values
+	}
+
+	switch result := f.expression.Evaluate(htmlquery.CreateXPathNavigator(node)).(type) {
+	case *xpath.NodeIterator:
+		for result.MoveNext() {
+			navigator, ok := result.Current().(*htmlquery.NodeNavigator)
+			if !ok {
+				continue
+			}
+			value := strings.TrimSpace(navigator.Value())
+			if navigator.NodeType() != xpath.AttributeNode {
+				value = f.value(navigator.Current())
+			}
+			if !add(value) {
+				break
+			}
+		}
+	case string:
+		add(strings.TrimSpace(result))
+	case float64:
+		add(strconv.FormatFloat(result, 'f', -1, 64))
+	case bool:
+		add(strconv.FormatBool(result))
+	}
+
+	return values
+}
+
+func (f FieldRule) value(node *html.Node) string {
+	if f.Attribute != "" {
+		return strings.TrimSpace(attribute(node, f.Attribute))
+	}
+
+	return textContent(node)
+}
@@ -0,0 +1,159 @@
+package crawler_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	core "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/hiago-balbino/web-crawler/v2/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/html"
+)
+
+const productPage = `<html><body>
+	<h1 class="title">Crawler</h1>
+	<span class="price" data-currency="USD"> 9.90 </span>
+	<a rel="author" href="/authors/ada">Ada</a>
+	<ul><li class="tag">go</li><li class="tag">crawler</li></ul>
+	<time datetime="2024-01-02">January 2</time>
+</body></html>`
+
+func TestParseExtractionRules(t *testing.T) {
+	t.Run("should parse the rules given as YAML", func(t *testing.T) {
+		rules, err := core.ParseExtractionRules([]byte(`
+rules:
+  - name: products
+    url_pattern: /products/
+    fields:
+      - name: price
+        css: .price
+      - name: date
+        xpath: //time/@datetime
+`))
+
+		assert.NoError(t, err)
+		assert.Len(t, rules, 1)
+		assert.Equal(t, "products", rules[0].Name)
+		assert.Len(t, rules[0].Fields, 2)
+	})
+	t.Run("should parse the rules given as JSON", func(t *testing.T) {
+		rules, err := core.ParseExtractionRules([]byte(`{"rules": [{"name": "all", "fields": [{"name": "title", "css": "h1"}]}]}`))
+
+		assert.NoError(t, err)
+		assert.Len(t, rules, 1)
+		assert.Equal(t, "h1", rules[0].Fields[0].CSS)
+	})
+
+	for name, content := range map[string]string{
+		"the content is malformed":      `rules: [`,
+		"the URL pattern is invalid":    `{"rules": [{"name": "r", "url_pattern": "(", "fields": [{"name": "f", "css": "h1"}]}]}`,
+		"the field has no name":         `{"rules": [{"name": "r", "fields": [{"css": "h1"}]}]}`,
+		"the field has no selector":     `{"rules": [{"name": "r", "fields": [{"name": "f"}]}]}`,
+		"the field has both selectors":  `{"rules": [{"name": "r", "fields": [{"name": "f", "css": "h1", "xpath": "//h1"}]}]}`,
+		"the CSS selector is invalid":   `{"rules": [{"name": "r", "fields": [{"name": "f", "css": "h1["}]}]}`,
+		"the XPath expression is wrong": `{"rules": [{"name": "r", "fields": [{"name": "f", "xpath": "//h1["}]}]}`,
+	} {
+		t.Run("should return error when "+name, func(t *testing.T) {
+			_, err := core.ParseExtractionRules([]byte(content))
+
+			assert.ErrorIs(t, err, core.ErrInvalidExtractionRule)
+		})
+	}
+}
+
+func TestCrawlerService_ExtractionRules(t *testing.T) {
+	URI := "https://anyurl.com/products/1"
+
+	t.Run("should extract the fields of the rules matching the page", func(t *testing.T) {
+		rules, err := core.ParseExtractionRules([]byte(`
+rules:
+  - name: products
+    url_pattern: ^https://anyurl\.com/products/
+    fields:
+      - {name: title, css: h1.title}
+      - {name: price, css: .price}
+      - {name: currency, css: .price, attribute: data-currency}
+      - {name: author, xpath: "//a[@rel='author']"}
+      - {name: date, xpath: //time/@datetime}
+      - {name: tags, css: li.tag, all: true}
+      - {name: first_tag, xpath: //li}
+      - {name: tag_count, xpath: count(//li)}
+      - {name: missing, css: .missing}
+  - name: articles
+    url_pattern: /articles/
+    fields:
+      - {name: headline, css: h1}
+`))
+		assert.NoError(t, err)
+
+		page := crawlPage(t, URI, productPage, core.WithExtractionRules(rules))
+
+		assert.Equal(t, map[string][]string{
+			"title":     {"Crawler"},
+			"price":     {"9.90"},
+			"currency":  {"USD"},
+			"author":    {"Ada"},
+			"date":      {"2024-01-02"},
+			"tags":      {"go", "crawler"},
+			"first_tag": {"go"},
+			"tag_count": {"2"},
+		}, page.Fields)
+	})
+	t.Run("should not extract fields when no rule matches the page", func(t *testing.T) {
+		rules, err := core.ParseExtractionRules([]byte(`{"rules": [{"name": "r", "url_pattern": "/articles/", "fields": [{"name": "title", "css": "h1"}]}]}`))
+		assert.NoError(t, err)
+
+		page := crawlPage(t, URI, productPage, core.WithExtractionRules(rules))
+
+		assert.Empty(t, page.Fields)
+	})
+	t.Run("should crawl again with the rules of the crawl taking precedence", func(t *testing.T) {
+		serviceRules, err := core.ParseExtractionRules([]byte(`{"rules": [{"name": "r", "fields": [
+			{"name": "title", "css": "h1"}, {"name": "price", "css": ".price"}
+		]}]}`))
+		assert.NoError(t, err)
+		crawlRules, err := core.ParseExtractionRules([]byte(`{"rules": [{"name": "r", "fields": [{"name": "title", "xpath": "//li"}]}]}`))
+		assert.NoError(t, err)
+		ctx := core.NewExtractionContext(context.Background(), crawlRules)
+		node, err := html.Parse(strings.NewReader(productPage))
+		assert.NoError(t, err)
+
+		pagerMock := new(mocks.PagerUsecaseMock)
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindPage", mock.Anything, URI, core.Options{}).Return(core.Page{ETag: `"v1"`}, nil)
+		pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+
+		crawl, err := core.NewCrawlerService(pagerMock, databaseMock, core.WithExtractionRules(serviceRules)).
+			Craw(ctx, URI, 1, core.Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]string{"title": {"go"}, "price": {"9.90"}}, crawl.Pages[0].Fields)
+		databaseMock.AssertNotCalled(t, "Find", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		databaseMock.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+		pagerMock.AssertNotCalled(t, "GetPageIfModified", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("should not store the crawl again nor its changes when it has rules of its own", func(t *testing.T) {
+		crawlRules, err := core.ParseExtractionRules([]byte(`{"rules": [{"name": "r", "fields": [{"name": "title", "css": "h1"}]}]}`))
+		assert.NoError(t, err)
+		ctx := core.NewExtractionContext(context.Background(), crawlRules)
+		node, err := html.Parse(strings.NewReader(productPage))
+		assert.NoError(t, err)
+
+		pagerMock := new(mocks.PagerUsecaseMock)
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("Find", mock.Anything, URI, uint(1), core.Options{}).Return(core.Crawl{URI: URI, Depth: 1}, nil)
+		databaseMock.On("FindPage", mock.Anything, URI, core.Options{}).Return(core.Page{}, errors.New("not found"))
+		pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Node: node}, nil)
+
+		crawl, _, err := core.NewCrawlerService(pagerMock, databaseMock).Recraw(ctx, URI, 1, core.Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]string{"title": {"Crawler"}}, crawl.Pages[0].Fields)
+		databaseMock.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+		databaseMock.AssertNotCalled(t, "InsertDiff", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,100 @@
+package crawler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Formats the fields extracted from the pages can be exported to.
+const (
+	CSVExport   = "csv"
+	JSONLExport = "jsonl"
+)
+
+// csvValuesSeparator joins the values of a field when all the elements found are taken.
+const csvValuesSeparator = " | "
+
+// csvFormulaPrefixes are the first characters that make a spreadsheet evaluate a cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// ErrUnknownExportFormat is returned when the fields are asked to be exported to an unknown format.
+var ErrUnknownExportFormat = errors.New("unknown export format")
+
+type fieldsRecord struct {
+	URI    string              `json:"uri"`
+	Fields map[string][]string `json:"fields"`
+}
+
+// ExportFields writes the fields extracted from the pages in the format, CSV with a column per field
+// or JSON Lines with a record per page. The pages without fields are left out. The CSV cells that would
+// be evaluated as formulas by spreadsheets are prefixed with a quote.
+func ExportFields(w io.Writer, format string, pages []Page) error {
+	switch format {
+	case CSVExport:
+		return exportFieldsCSV(w, pages)
+	case JSONLExport:
+		return exportFieldsJSONL(w, pages)
+	default:
+		return ErrUnknownExportFormat
+	}
+}
+
+func exportFieldsCSV(w io.Writer, pages []Page) error {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, page := range pages {
+		for name := range page.Fields {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append([]string{"uri"}, names...)); err != nil {
+		return err
+	}
+	for _, page := range pages {
+		if len(page.Fields) == 0 {
+			continue
+		}
+		record := []string{csvCell(page.URI)}
+		for _, name := range names {
+			record = append(record, csvCell(strings.Join(page.Fields[name], csvValuesSeparator)))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+func exportFieldsJSONL(w io.Writer, pages []Page) error {
+	encoder := json.NewEncoder(w)
+	for _, page := range pages {
+		if len(page.Fields) == 0 {
+			continue
+		}
+		if err := encoder.Encode(fieldsRecord{URI: page.URI, Fields: page.Fields}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package crawler_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	core "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportFields(t *testing.T) {
+	pages := []core.Page{
+		{URI: "https://anyurl.com/products/1", Fields: map[string][]string{"price": {"9.90"}, "tags": {"go", "crawler"}}},
+		{URI: "https://anyurl.com"},
+		{URI: "https://anyurl.com/products/2", Fields: map[string][]string{"price": {"1,50"}, "title": {"Other"}}},
+	}
+
+	t.Run("should export the fields as CSV", func(t *testing.T) {
+		output := strings.Builder{}
+
+		err := core.ExportFields(&output, core.CSVExport, pages)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "uri,price,tags,title\n"+
+			"https://anyurl.com/products/1,9.90,go | crawler,\n"+
+			"https://anyurl.com/products/2,\"1,50\",,Other\n", output.String())
+	})
+	t.Run("should keep the CSV values from being evaluated as formulas", func(t *testing.T) {
+		output := strings.Builder{}
+		formulas := []core.Page{{URI: "https://anyurl.com", Fields: map[string][]string{
+			"a": {"=HYPERLINK(\"http://evil.com\")"}, "b": {"+1"}, "c": {"-1", "2"}, "d": {"@SUM(A1)"}, "e": {"9.90"},
+		}}}
+
+		err := core.ExportFields(&output, core.CSVExport, formulas)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "uri,a,b,c,d,e\n"+
+			"https://anyurl.com,\"'=HYPERLINK(\"\"http://evil.com\"\")\",'+1,'-1 | 2,'@SUM(A1),9.90\n", output.String())
+	})
+	t.Run("should export the fields as JSON Lines", func(t *testing.T) {
+		output := strings.Builder{}
+
+		err := core.ExportFields(&output, core.JSONLExport, pages)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `{"uri":"https://anyurl.com/products/1","fields":{"price":["9.90"],"tags":["go","crawler"]}}`+"\n"+
+			`{"uri":"https://anyurl.com/products/2","fields":{"price":["1,50"],"title":["Other"]}}`+"\n", output.String())
+	})
+	t.Run("should return error when the format is unknown", func(t *testing.T) {
+		err := core.ExportFields(&strings.Builder{}, "xml", pages)
+
+		assert.True(t, errors.Is(err, core.ErrUnknownExportFormat))
+	})
+}
@@ -1,7 +1,11 @@
 package crawler
 
+import "github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+
 type linkAddress struct {
-	uri  string
-	uris []string
-	err  error
+	uri         string
+	uris        []string
+	page        Page
+	certificate *pager.Certificate
+	err         error
 }
@@ -0,0 +1,215 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Metadata is what a page tells about itself in its head and headings.
+type Metadata struct {
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	H1          []string          `json:"h1,omitempty"`
+	H2          []string          `json:"h2,omitempty"`
+	H3          []string          `json:"h3,omitempty"`
+	Canonical   string            `json:"canonical,omitempty"`
+	Icon        string            `json:"icon,omitempty"`
+	Lang        string            `json:"lang,omitempty"`
+	Alternates  []Alternate       `json:"alternates,omitempty"`
+	OpenGraph   map[string]string `json:"openGraph,omitempty"`
+	Twitter     map[string]string `json:"twitter,omitempty"`
+}
+
+// Alternate is a version of the page in another language, given by a hreflang link.
+type Alternate struct {
+	Lang string `json:"lang"`
+	URI  string `json:"uri"`
+}
+
+// IsZero reports whether nothing was found on the page.
+func (m Metadata) IsZero() bool {
+	return m.Title == "" && m.Description == "" && len(m.H1) == 0 && len(m.H2) == 0 && len(m.H3) == 0 &&
+		m.Canonical == "" && m.Icon == "" && m.Lang == "" && len(m.Alternates) == 0 && len(m.OpenGraph) == 0 && len(m.Twitter) == 0
+}
+
+// urlProperties are the OpenGraph and Twitter card properties whose content is a URI.
+var urlProperties = map[string]bool{
+	"og:url": true, "og:image": true, "og:image:url": true, "og:image:secure_url": true, "og:audio": true,
+	"og:video": true, "twitter:image": true, "twitter:image:src": true,
+}
+
+// extractMetadata walks the page collecting its metadata, keeping the first value found of each single
+// valued field. The URIs found are resolved against the URI the page was served from, or against its
+// <base href> when it has one.
+func extractMetadata(uri string, node *html.Node) Metadata {
+	metadata := Metadata{}
+	base, _ := url.Parse(uri)
+	walkMetadata(&metadata, documentBase(base, node), node)
+
+	return metadata
+}
+
+// documentBase returns the URI given by the first <base href> of the page, resolved against the URI the
+// page was served from, which is returned when there is none.
+func documentBase(base *url.URL, node *html.Node) *url.URL {
+	if node == nil {
+		return base
+	}
+
+	if node.Type == html.ElementNode && node.Data == "base" {
+		if href := strings.TrimSpace(attribute(node, "href")); href != "" {
+			if reference, err := url.Parse(href); err == nil && base != nil {
+				return base.ResolveReference(reference)
+			}
+		}
+	}
+
+	for next := node.FirstChild; next != nil; next = next.NextSibling {
+		if found := documentBase(base, next); found != base {
+			return found
+		}
+	}
+
+	return base
+}
+
+func walkMetadata(metadata *Metadata, base *url.URL, node *html.Node) {
+	if node == nil {
+		return
+	}
+
+	if node.Type == html.ElementNode {
+		switch node.Data {
+		case "html":
+			setFirst(&metadata.Lang, attribute(node, "lang"))
+		case "title":
+			setFirst(&metadata.Title, textContent(node))
+		case "meta":
+			addMeta(metadata, base, node)
+		case "link":
+			addLink(metadata, base, node)
+		case "h1":
+			metadata.H1 = appendText(metadata.H1, node)
+		case "h2":
+			metadata.H2 = appendText(metadata.H2, node)
+		case "h3":
+			metadata.H3 = appendText(metadata.H3, node)
+		}
+	}
+
+	for next := node.FirstChild; next != nil; next = next.NextSibling {
+		walkMetadata(metadata, base, next)
+	}
+}
+
+func addMeta(metadata *Metadata, base *url.URL, node *html.Node) {
+	content := strings.TrimSpace(attribute(node, "content"))
+	if content == "" {
+		return
+	}
+
+	for _, key := range []string{attribute(node, "property"), attribute(node, "name")} {
+		key = strings.ToLower(strings.TrimSpace(key))
+		value := content
+		if urlProperties[key] {
+			value = resolve(base, content)
+		}
+		switch {
+		case key == "description":
+			setFirst(&metadata.Description, value)
+		case strings.HasPrefix(key, "og:"):
+			metadata.OpenGraph = setFirstKey(metadata.OpenGraph, key, value)
+		case strings.HasPrefix(key, "twitter:"):
+			metadata.Twitter = setFirstKey(metadata.Twitter, key, value)
+		}
+	}
+}
+
+func addLink(metadata *Metadata, base *url.URL, node *html.Node) {
+	href := resolve(base, attribute(node, "href"))
+	if href == "" {
+		return
+	}
+
+	for _, rel := range strings.Fields(strings.ToLower(attribute(node, "rel"))) {
+		switch rel {
+		case "canonical":
+			setFirst(&metadata.Canonical, href)
+		case "icon":
+			setFirst(&metadata.Icon, href)
+		case "alternate":
+			if lang := strings.TrimSpace(attribute(node, "hreflang")); lang != "" {
+				metadata.Alternates = append(metadata.Alternates, Alternate{Lang: lang, URI: href})
+			}
+		}
+	}
+}
+
+func appendText(texts []string, node *html.Node) []string {
+	if text := textContent(node); text != "" {
+		return append(texts, text)
+	}
+
+	return texts
+}
+
+func setFirst(field *string, value string) {
+	if *field == "" {
+		*field = strings.TrimSpace(value)
+	}
+}
+
+func setFirstKey(values map[string]string, key, value string) map[string]string {
+	if values == nil {
+		values = make(map[string]string)
+	}
+	if _, found := values[key]; !found {
+		values[key] = value
+	}
+
+	return values
+}
+
+func attribute(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+
+	return ""
+}
+
+// textContent joins the text found under the node, collapsing the white space.
+func textContent(node *html.Node) string {
+	builder := strings.Builder{}
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			builder.WriteString(node.Data)
+			builder.WriteString(" ")
+		}
+		for next := node.FirstChild; next != nil; next = next.NextSibling {
+			collect(next)
+		}
+	}
+	collect(node)
+
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+func resolve(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || base == nil {
+		return href
+	}
+
+	reference, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+
+	return base.ResolveReference(reference).String()
+}
@@ -0,0 +1,121 @@
+package crawler_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	core "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/hiago-balbino/web-crawler/v2/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/html"
+)
+
+// crawlPage crawls the URI with depth one, being its page the body given.
+func crawlPage(t *testing.T, uri, body string, opts ...core.ServiceOption) core.Page {
+	t.Helper()
+	ctx := context.Background()
+	unexpectedErr := errors.New("unexpected error")
+
+	node, err := html.Parse(strings.NewReader(body))
+	assert.NoError(t, err)
+
+	pagerMock := new(mocks.PagerUsecaseMock)
+	databaseMock := new(mocks.CrawlerDatabaseMock)
+	databaseMock.On("Find", ctx, uri, uint(1), core.Options{}).Return(core.Crawl{}, unexpectedErr)
+	databaseMock.On("FindShallower", ctx, uri, uint(1), core.Options{}).Return(core.Crawl{}, unexpectedErr)
+	databaseMock.On("FindPage", mock.Anything, uri, core.Options{}).Return(core.Page{}, unexpectedErr)
+	pagerMock.On("GetPage", mock.Anything, uri).Return(pager.Page{URI: uri, Node: node}, nil)
+	databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+	crawl, err := core.NewCrawlerService(pagerMock, databaseMock, opts...).Craw(ctx, uri, 1, core.Options{})
+	assert.NoError(t, err)
+
+	return crawl.Pages[0]
+}
+
+func TestCrawlerService_Metadata(t *testing.T) {
+	URI := "https://anyurl.com/blog/post"
+
+	t.Run("should extract the metadata of the page", func(t *testing.T) {
+		page := crawlPage(t, URI, `<!DOCTYPE html>
+			<html lang="en-US">
+			<head>
+				<title>  Deprecated   API </title>
+				<meta name="description" content="What changed in the API">
+				<meta name="description" content="ignored">
+				<link rel="canonical" href="/blog/post">
+				<link rel="alternate" hreflang="pt-BR" href="https://anyurl.com/pt/blog/post">
+				<link rel="alternate" type="application/rss+xml" href="/feed">
+				<meta property="og:title" content="Deprecated API">
+				<meta property="og:type" content="article">
+				<meta name="twitter:card" content="summary">
+			</head>
+			<body>
+				<h1>Deprecated <em>API</em></h1>
+				<h2>Why</h2><h2>How</h2>
+				<h3>Migrating</h3>
+				<svg><title>icon</title></svg>
+			</body>
+			</html>`)
+
+		assert.Equal(t, core.Metadata{
+			Title:       "Deprecated API",
+			Description: "What changed in the API",
+			H1:          []string{"Deprecated API"},
+			H2:          []string{"Why", "How"},
+			H3:          []string{"Migrating"},
+			Canonical:   "https://anyurl.com/blog/post",
+			Lang:        "en-US",
+			Alternates:  []core.Alternate{{Lang: "pt-BR", URI: "https://anyurl.com/pt/blog/post"}},
+			OpenGraph:   map[string]string{"og:title": "Deprecated API", "og:type": "article"},
+			Twitter:     map[string]string{"twitter:card": "summary"},
+		}, page.Metadata)
+	})
+
+	t.Run("should return empty metadata when the page has none", func(t *testing.T) {
+		page := crawlPage(t, URI, `<p>no metadata</p>`)
+
+		assert.True(t, page.Metadata.IsZero())
+	})
+
+	t.Run("should resolve the URIs against the base of the page", func(t *testing.T) {
+		page := crawlPage(t, URI, `<head>
+			<base href="https://cdn.anyurl.com/static/">
+			<link rel="canonical" href="post">
+			<link rel="shortcut icon" href="favicon.ico">
+			<meta property="og:url" content="/blog/post">
+			<meta name="twitter:image" content="cover.png">
+			</head>`)
+
+		assert.Equal(t, "https://cdn.anyurl.com/static/post", page.Metadata.Canonical)
+		assert.Equal(t, "https://cdn.anyurl.com/static/favicon.ico", page.Metadata.Icon)
+		assert.Equal(t, "https://cdn.anyurl.com/blog/post", page.Metadata.OpenGraph["og:url"])
+		assert.Equal(t, "https://cdn.anyurl.com/static/cover.png", page.Metadata.Twitter["twitter:image"])
+	})
+
+	t.Run("should resolve the URIs against where the page was redirected to", func(t *testing.T) {
+		node, err := html.Parse(strings.NewReader(`<link rel="canonical" href="post"><link rel="icon" href="/favicon.ico">
+			<meta property="og:url" content="post">`))
+		assert.NoError(t, err)
+		redirects := []pager.Redirect{
+			{URI: URI, StatusCode: 301, Location: "https://www.anyurl.com/2024/post"},
+			{URI: "https://www.anyurl.com/2024/post", StatusCode: 302, Location: "/archive/2024/post"},
+		}
+		pagerMock := new(mocks.PagerUsecaseMock)
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindPage", mock.Anything, URI, core.Options{}).Return(core.Page{}, errors.New("not found"))
+		pagerMock.On("GetPage", mock.Anything, URI).Return(pager.Page{URI: URI, Redirects: redirects, Node: node}, nil)
+		databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+
+		crawl, err := core.NewCrawlerService(pagerMock, databaseMock).Refresh(context.Background(), URI, 1, core.Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://www.anyurl.com/archive/2024/post", crawl.Pages[0].Metadata.Canonical)
+		assert.Equal(t, "https://www.anyurl.com/favicon.ico", crawl.Pages[0].Metadata.Icon)
+		assert.Equal(t, "https://www.anyurl.com/archive/2024/post", crawl.Pages[0].Metadata.OpenGraph["og:url"])
+	})
+}
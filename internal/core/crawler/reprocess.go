@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+)
+
+// ErrNoBodyArchive is returned when a crawl is asked to be reprocessed without a body archive to read from.
+var ErrNoBodyArchive = errors.New("no body archive configured to reprocess from")
+
+// Reprocess extracts again from the archived bodies of a snapshot, with the rules in use now, storing
+// the result as the current crawl. Pages without an archived body keep what they had.
+func (p CrawlerService) Reprocess(ctx context.Context, id string) (Crawl, error) {
+	if p.bodyArchive == nil {
+		return Crawl{}, ErrNoBodyArchive
+	}
+
+	crawl, err := p.database.FindSnapshot(ctx, id)
+	if err != nil {
+		return Crawl{}, err
+	}
+
+	pages := make([]Page, 0, len(crawl.Pages))
+	for _, page := range crawl.Pages {
+		reprocessed, err := p.reprocessPage(ctx, page)
+		if err != nil {
+			log.Error("error reprocessing page", zap.String("uri", page.URI), logger.FieldError(err))
+
+			return Crawl{}, err
+		}
+		pages = append(pages, reprocessed)
+	}
+
+	crawl.Pages = pages
+	crawl.Links, crawl.Hops = linkGraph(crawl.URI, pages)
+	crawl.CrawledAt = time.Now().UTC()
+	if err := p.database.Insert(ctx, crawl); err != nil {
+		log.Error("error inserting data into database", logger.FieldError(err))
+
+		return Crawl{}, err
+	}
+	p.pruneSearchIndexes(ctx, crawl.URI, crawl.Options)
+
+	return crawl, nil
+}
+
+func (p CrawlerService) reprocessPage(ctx context.Context, page Page) (Page, error) {
+	if page.BodyKey == "" {
+		return page, nil
+	}
+
+	body, err := p.bodyArchive.Load(ctx, page.BodyKey)
+	if errors.Is(err, pager.ErrBodyNotArchived) {
+		log.Warn("page body missing from archive", zap.String("uri", page.URI), zap.String("key", page.BodyKey))
+
+		return page, nil
+	}
+	if err != nil {
+		return Page{}, err
+	}
+
+	decoded, err := pager.DecodeBody(body, page.Charset)
+	if err != nil {
+		return Page{}, err
+	}
+
+	node, err := html.Parse(decoded)
+	if err != nil {
+		return Page{}, err
+	}
+
+	return page.extract(node, p.extractionRules(ctx)), nil
+}
+
+// linkGraph collects the links found on the pages in the order they were fetched, each one being a hop
+// further than the page it was first found on.
+func linkGraph(uri string, pages []Page) ([]string, map[string]uint) {
+	links := make([]string, 0)
+	hops := make(map[string]uint)
+	seen := map[string]bool{uri: true}
+	for _, page := range pages {
+		for _, link := range page.Links {
+			if !seen[link] {
+				seen[link] = true
+				links = append(links, link)
+				hops[link] = page.Depth + 1
+			}
+		}
+	}
+
+	return links, hops
+}
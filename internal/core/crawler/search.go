@@ -0,0 +1,149 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/search"
+	"go.uber.org/zap"
+)
+
+// ErrNoSearchIndex is returned when a crawl is searched without a directory to keep the search indexes in.
+var ErrNoSearchIndex = errors.New("no search index directory configured")
+
+// SearchResult is a page of a crawl matching a search, best first.
+type SearchResult struct {
+	URI     string  `json:"uri"`
+	Title   string  `json:"title,omitempty"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+// WithSearchIndex sets the directory the search indexes of the snapshots are kept in, and the language
+// the pages are indexed in when the crawled page does not tell its own.
+func WithSearchIndex(dir, language string) ServiceOption {
+	return func(p *CrawlerService) {
+		p.searchDir = dir
+		p.searchLanguage = language
+	}
+}
+
+// Search looks for the query in the title and content of the pages of a snapshot. The index of the snapshot
+// is built on its first search and kept on disk, keyed by the id and the time of the snapshot, so an index
+// is never taken for another snapshot given the same id.
+func (p CrawlerService) Search(ctx context.Context, id, query string) ([]SearchResult, error) {
+	if p.searchDir == "" {
+		return nil, ErrNoSearchIndex
+	}
+
+	index, err := p.searchIndex(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hits, err := index.Search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, SearchResult{URI: hit.URI, Title: hit.Title, Score: hit.Score, Snippet: hit.Snippet})
+	}
+
+	return results, nil
+}
+
+// searchIndex loads the index of the snapshot, building it from the stored crawl when not built yet.
+func (p CrawlerService) searchIndex(ctx context.Context, id string) (*search.Index, error) {
+	crawl, err := p.database.FindSnapshot(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	file := filepath.Join(p.searchIndexDir(crawl.URI, crawl.Options), searchIndexFile(id, crawl.CrawledAt))
+	index, err := search.Load(file)
+	if err == nil {
+		return index, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		log.Warn("error loading search index, building it again", zap.String("file", file), logger.FieldError(err))
+	}
+
+	index = search.NewIndex(p.crawlLanguage(crawl))
+	for _, page := range crawl.Pages {
+		if page.Content.IsZero() && page.Metadata.Title == "" {
+			continue
+		}
+		index.Add(search.Document{URI: page.URI, Title: page.Metadata.Title, Text: page.Content.Text})
+	}
+	if err := index.Save(file); err != nil {
+		log.Error("error saving search index", zap.String("file", file), logger.FieldError(err))
+	}
+
+	return index, nil
+}
+
+// pruneSearchIndexes removes the indexes of the snapshots of the URI and options no longer stored, once
+// a new snapshot was stored and the oldest ones may have been pruned.
+func (p CrawlerService) pruneSearchIndexes(ctx context.Context, uri string, opts Options) {
+	if p.searchDir == "" {
+		return
+	}
+
+	dir := p.searchIndexDir(uri, opts)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	snapshots, err := p.database.History(ctx, uri, opts)
+	if err != nil {
+		log.Error("error listing snapshots to prune search indexes", logger.FieldError(err))
+
+		return
+	}
+	stored := make(map[string]bool, len(snapshots))
+	for _, snapshot := range snapshots {
+		stored[searchIndexFile(snapshot.ID, snapshot.CrawledAt)] = true
+	}
+
+	for _, entry := range entries {
+		if stored[entry.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			log.Error("error removing search index", zap.String("file", entry.Name()), logger.FieldError(err))
+		}
+	}
+}
+
+// searchIndexDir is the directory the indexes of the snapshots of the URI and options are kept in.
+func (p CrawlerService) searchIndexDir(uri string, opts Options) string {
+	digest := sha256.Sum256([]byte(uri + "\x00" + opts.AuthProfile))
+
+	return filepath.Join(p.searchDir, hex.EncodeToString(digest[:]))
+}
+
+func searchIndexFile(id string, crawledAt time.Time) string {
+	return fmt.Sprintf("%s-%d.idx", url.PathEscape(id), crawledAt.UnixNano())
+}
+
+// crawlLanguage is the language of the page crawled first telling its own, or the one configured.
+func (p CrawlerService) crawlLanguage(crawl Crawl) string {
+	for _, page := range crawl.Pages {
+		if page.Metadata.Lang != "" {
+			return search.Language(page.Metadata.Lang)
+		}
+	}
+
+	return search.Language(p.searchLanguage)
+}
@@ -0,0 +1,122 @@
+package crawler_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	core "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/search"
+	"github.com/hiago-balbino/web-crawler/v2/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCrawlerService_Search(t *testing.T) {
+	ctx := context.Background()
+	id := "65a0f0f0f0f0f0f0f0f0f0f1"
+	crawledAt := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := core.Crawl{URI: "https://anyurl.com", Depth: 1, CrawledAt: crawledAt, Pages: []core.Page{
+		{URI: "https://anyurl.com", Metadata: core.Metadata{Title: "Home", Lang: "pt-BR"}, Content: core.Content{Text: "Bem-vindo"}},
+		{URI: "https://anyurl.com/paginas", Metadata: core.Metadata{Title: "Páginas"}, Content: core.Content{Text: "Todas as páginas rastreadas"}},
+		{URI: "https://anyurl.com/empty"},
+	}}
+
+	t.Run("should build the index of the snapshot on the first search and load it afterwards", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindSnapshot", ctx, id).Return(snapshot, nil).Once()
+		databaseMock.On("FindSnapshot", ctx, id).Return(core.Crawl{URI: snapshot.URI, CrawledAt: crawledAt}, nil)
+		crawler := core.NewCrawlerService(new(mocks.PagerUsecaseMock), databaseMock, core.WithSearchIndex(t.TempDir(), search.English))
+
+		results, err := crawler.Search(ctx, id, "página")
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "https://anyurl.com/paginas", results[0].URI)
+		assert.Equal(t, "Páginas", results[0].Title)
+		assert.Equal(t, "Todas as páginas rastreadas", results[0].Snippet)
+		assert.Positive(t, results[0].Score)
+
+		again, err := crawler.Search(ctx, id, "paginas")
+		assert.NoError(t, err)
+		assert.Equal(t, results, again)
+	})
+	t.Run("should build the index again when another snapshot is given the same id", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindSnapshot", ctx, id).Return(snapshot, nil).Once()
+		databaseMock.On("FindSnapshot", ctx, id).Return(core.Crawl{URI: snapshot.URI, CrawledAt: crawledAt.Add(time.Hour)}, nil)
+		crawler := core.NewCrawlerService(new(mocks.PagerUsecaseMock), databaseMock, core.WithSearchIndex(t.TempDir(), search.English))
+
+		results, err := crawler.Search(ctx, id, "página")
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+
+		again, err := crawler.Search(ctx, id, "página")
+		assert.NoError(t, err)
+		assert.Empty(t, again)
+	})
+	t.Run("should remove the indexes of the snapshots pruned once a crawl is stored", func(t *testing.T) {
+		dir := t.TempDir()
+		pruned := "65a0f0f0f0f0f0f0f0f0f0f0"
+		pagerMock := new(mocks.PagerUsecaseMock)
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindSnapshot", ctx, pruned).Return(snapshot, nil)
+		databaseMock.On("FindSnapshot", ctx, id).Return(snapshot, nil)
+		databaseMock.On("FindPage", mock.Anything, snapshot.URI, core.Options{}).Return(core.Page{}, core.ErrSnapshotNotFound)
+		pagerMock.On("GetPage", mock.Anything, snapshot.URI).Return(pager.Page{URI: snapshot.URI}, nil)
+		databaseMock.On("Insert", mock.Anything, mock.AnythingOfType("crawler.Crawl")).Return(nil)
+		databaseMock.On("History", mock.Anything, snapshot.URI, core.Options{}).
+			Return([]core.Snapshot{{ID: id, URI: snapshot.URI, CrawledAt: crawledAt}}, nil)
+		crawler := core.NewCrawlerService(pagerMock, databaseMock, core.WithSearchIndex(dir, search.English))
+
+		for _, snapshotID := range []string{pruned, id} {
+			_, err := crawler.Search(ctx, snapshotID, "página")
+			assert.NoError(t, err)
+		}
+		_, err := crawler.Refresh(ctx, snapshot.URI, 1, core.Options{})
+		assert.NoError(t, err)
+
+		files, err := filepath.Glob(filepath.Join(dir, "*", "*.idx"))
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+		assert.Contains(t, files[0], id)
+	})
+	t.Run("should return no results when no page matches", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindSnapshot", ctx, id).Return(snapshot, nil)
+		crawler := core.NewCrawlerService(new(mocks.PagerUsecaseMock), databaseMock, core.WithSearchIndex(t.TempDir(), search.English))
+
+		results, err := crawler.Search(ctx, id, "crawler")
+
+		assert.NoError(t, err)
+		assert.Empty(t, results)
+	})
+	t.Run("should return error when the query has no words", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindSnapshot", ctx, id).Return(snapshot, nil)
+		crawler := core.NewCrawlerService(new(mocks.PagerUsecaseMock), databaseMock, core.WithSearchIndex(t.TempDir(), search.English))
+
+		_, err := crawler.Search(ctx, id, "?")
+
+		assert.ErrorIs(t, err, search.ErrEmptyQuery)
+	})
+	t.Run("should return error when snapshot does not exist", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		databaseMock.On("FindSnapshot", ctx, id).Return(core.Crawl{}, core.ErrSnapshotNotFound)
+		crawler := core.NewCrawlerService(new(mocks.PagerUsecaseMock), databaseMock, core.WithSearchIndex(t.TempDir(), search.English))
+
+		_, err := crawler.Search(ctx, id, "página")
+
+		assert.ErrorIs(t, err, core.ErrSnapshotNotFound)
+	})
+	t.Run("should return error when there is no directory for the indexes", func(t *testing.T) {
+		databaseMock := new(mocks.CrawlerDatabaseMock)
+		crawler := core.NewCrawlerService(new(mocks.PagerUsecaseMock), databaseMock)
+
+		_, err := crawler.Search(ctx, id, "página")
+
+		assert.ErrorIs(t, err, core.ErrNoSearchIndex)
+		databaseMock.AssertNotCalled(t, "FindSnapshot", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,19 @@
+package crawler
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrSnapshotNotFound is returned when the snapshot asked does not exist or was already pruned.
+var ErrSnapshotNotFound = errors.New("crawl snapshot not found")
+
+// Snapshot describes one of the stored versions of the crawls of a URI.
+type Snapshot struct {
+	ID        string
+	URI       string
+	Depth     uint
+	Options   Options
+	CrawledAt time.Time
+	Links     int
+}
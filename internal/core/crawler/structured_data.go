@@ -0,0 +1,275 @@
+package crawler
+
+import (
+	"encoding/json"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Formats of the structured data found on the pages.
+const (
+	JSONLDFormat    = "json-ld"
+	MicrodataFormat = "microdata"
+	RDFaFormat      = "rdfa"
+)
+
+// StructuredData is an item of schema.org like markup found on a page, normalised to JSON. A JSON-LD
+// block that does not parse keeps the error and has no data.
+type StructuredData struct {
+	Format string          `json:"format"`
+	Types  []string        `json:"types,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// HasType reports whether the item is of the type, given with or without the schema.org prefix.
+func (s StructuredData) HasType(itemType string) bool {
+	itemType = normalizeType(itemType)
+	for _, found := range s.Types {
+		if strings.EqualFold(found, itemType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PagesWithType returns the pages carrying structured data of the type.
+func (c Crawl) PagesWithType(itemType string) []Page {
+	pages := make([]Page, 0)
+	for _, page := range c.Pages {
+		for _, data := range page.StructuredData {
+			if data.HasType(itemType) {
+				pages = append(pages, page)
+
+				break
+			}
+		}
+	}
+
+	return pages
+}
+
+// extractStructuredData collects the JSON-LD blocks, the Microdata items and the RDFa resources of the
+// page, in the order they appear. Only the outermost items are returned, the nested ones being part of them.
+func extractStructuredData(node *html.Node) []StructuredData {
+	var items []StructuredData
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			switch {
+			case node.Data == "script" && strings.EqualFold(strings.TrimSpace(attribute(node, "type")), "application/ld+json"):
+				items = append(items, parseJSONLD(textContent(node)))
+
+				return
+			case hasAttribute(node, "itemscope") && !hasAttribute(node, "itemprop"):
+				items = append(items, newStructuredData(MicrodataFormat, microdataItem(node)))
+
+				return
+			case hasAttribute(node, "typeof") && !hasAttribute(node, "property"):
+				items = append(items, newStructuredData(RDFaFormat, rdfaItem(node)))
+
+				return
+			}
+		}
+
+		for next := node.FirstChild; next != nil; next = next.NextSibling {
+			walk(next)
+		}
+	}
+	if node != nil {
+		walk(node)
+	}
+
+	return items
+}
+
+func parseJSONLD(text string) StructuredData {
+	var data any
+	if err := json.Unmarshal([]byte(text), &data); err != nil {
+		return StructuredData{Format: JSONLDFormat, Error: err.Error()}
+	}
+
+	return newStructuredData(JSONLDFormat, data)
+}
+
+func newStructuredData(format string, data any) StructuredData {
+	normalized, err := json.Marshal(data)
+	if err != nil {
+		return StructuredData{Format: format, Error: err.Error()}
+	}
+
+	return StructuredData{Format: format, Types: collectTypes(nil, data), Data: normalized}
+}
+
+// collectTypes gathers the @type of the item, of the items in a list and of the ones in its @graph.
+func collectTypes(types []string, data any) []string {
+	switch value := data.(type) {
+	case []any:
+		for _, item := range value {
+			types = collectTypes(types, item)
+		}
+	case map[string]any:
+		switch itemType := value["@type"].(type) {
+		case string:
+			types = appendType(types, itemType)
+		case []any:
+			for _, each := range itemType {
+				if text, ok := each.(string); ok {
+					types = appendType(types, text)
+				}
+			}
+		}
+		types = collectTypes(types, value["@graph"])
+	}
+
+	return types
+}
+
+func appendType(types []string, itemType string) []string {
+	for _, each := range strings.Fields(itemType) {
+		types = append(types, normalizeType(each))
+	}
+
+	return types
+}
+
+// normalizeType removes the schema.org vocabulary from the type, so it can be asked by its name alone.
+func normalizeType(itemType string) string {
+	for _, prefix := range []string{"https://schema.org/", "http://schema.org/", "schema:"} {
+		itemType = strings.TrimPrefix(itemType, prefix)
+	}
+
+	return itemType
+}
+
+// microdataItem reads the properties of the item down to the nested items, which are read as values.
+func microdataItem(node *html.Node) map[string]any {
+	item := map[string]any{}
+	if itemType := strings.Fields(attribute(node, "itemtype")); len(itemType) > 0 {
+		item["@type"] = typeValue(itemType)
+	}
+	if id := attribute(node, "itemid"); id != "" {
+		item["@id"] = id
+	}
+
+	properties(item, node, "itemprop", "itemscope", func(node *html.Node) any {
+		if hasAttribute(node, "itemscope") {
+			return microdataItem(node)
+		}
+
+		return microdataValue(node)
+	})
+
+	return item
+}
+
+func microdataValue(node *html.Node) string {
+	switch node.Data {
+	case "meta":
+		return attribute(node, "content")
+	case "a", "area", "link":
+		return attribute(node, "href")
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return attribute(node, "src")
+	case "object":
+		return attribute(node, "data")
+	case "data", "meter":
+		return attribute(node, "value")
+	case "time":
+		if datetime := attribute(node, "datetime"); datetime != "" {
+			return datetime
+		}
+	}
+
+	return textContent(node)
+}
+
+// rdfaItem reads the properties of the resource down to the nested resources, which are read as values.
+func rdfaItem(node *html.Node) map[string]any {
+	item := map[string]any{}
+	if itemType := strings.Fields(attribute(node, "typeof")); len(itemType) > 0 {
+		item["@type"] = typeValue(itemType)
+	}
+	if vocab := attribute(node, "vocab"); vocab != "" {
+		item["@context"] = vocab
+	}
+	if resource := attribute(node, "resource"); resource != "" {
+		item["@id"] = resource
+	}
+
+	properties(item, node, "property", "typeof", func(node *html.Node) any {
+		if hasAttribute(node, "typeof") {
+			return rdfaItem(node)
+		}
+
+		return rdfaValue(node)
+	})
+
+	return item
+}
+
+func rdfaValue(node *html.Node) string {
+	for _, key := range []string{"content", "href", "src", "resource"} {
+		if value := attribute(node, key); value != "" {
+			return value
+		}
+	}
+
+	return textContent(node)
+}
+
+// properties adds to the item the values of the descendants carrying the property attribute, without
+// going into the nested items, a property found more than once being kept as a list.
+func properties(item map[string]any, node *html.Node, propertyKey, scopeKey string, value func(*html.Node) any) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+
+		if names := strings.Fields(attribute(child, propertyKey)); len(names) > 0 {
+			propertyValue := value(child)
+			for _, name := range names {
+				addProperty(item, name, propertyValue)
+			}
+		}
+		if !hasAttribute(child, scopeKey) {
+			properties(item, child, propertyKey, scopeKey, value)
+		}
+	}
+}
+
+func addProperty(item map[string]any, name string, value any) {
+	switch existing := item[name].(type) {
+	case nil:
+		item[name] = value
+	case []any:
+		item[name] = append(existing, value)
+	default:
+		item[name] = []any{existing, value}
+	}
+}
+
+func typeValue(types []string) any {
+	if len(types) == 1 {
+		return types[0]
+	}
+
+	values := make([]any, 0, len(types))
+	for _, each := range types {
+		values = append(values, each)
+	}
+
+	return values
+}
+
+func hasAttribute(node *html.Node, key string) bool {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
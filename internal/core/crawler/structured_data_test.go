@@ -0,0 +1,90 @@
+package crawler_test
+
+import (
+	"testing"
+
+	core "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrawlerService_StructuredData(t *testing.T) {
+	URI := "https://anyurl.com/products/1"
+
+	t.Run("should extract JSON-LD blocks", func(t *testing.T) {
+		page := crawlPage(t, URI, `<html><head>
+			<script type="application/ld+json">
+				{"@context": "https://schema.org", "@type": "Product", "name": "Crawler"}
+			</script>
+			<script type="application/ld+json">
+				{"@context": "https://schema.org", "@graph": [{"@type": "BreadcrumbList"}, {"@type": ["Organization", "Brand"]}]}
+			</script>
+			<script type="application/ld+json">{"@type": "Product",}</script>
+		</head></html>`)
+
+		assert.Len(t, page.StructuredData, 3)
+		assert.Equal(t, core.JSONLDFormat, page.StructuredData[0].Format)
+		assert.Equal(t, []string{"Product"}, page.StructuredData[0].Types)
+		assert.JSONEq(t, `{"@context": "https://schema.org", "@type": "Product", "name": "Crawler"}`, string(page.StructuredData[0].Data))
+		assert.Equal(t, []string{"BreadcrumbList", "Organization", "Brand"}, page.StructuredData[1].Types)
+		assert.NotEmpty(t, page.StructuredData[2].Error)
+		assert.Empty(t, page.StructuredData[2].Data)
+	})
+
+	t.Run("should extract Microdata items with their nested items", func(t *testing.T) {
+		page := crawlPage(t, URI, `<div itemscope itemtype="https://schema.org/Product">
+			<h1 itemprop="name">Crawler</h1>
+			<img itemprop="image" src="https://anyurl.com/crawler.png">
+			<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+				<meta itemprop="price" content="9.90">
+				<span itemprop="priceCurrency">USD</span>
+			</div>
+			<span itemprop="color">blue</span><span itemprop="color">red</span>
+		</div>`)
+
+		assert.Len(t, page.StructuredData, 1)
+		assert.Equal(t, core.MicrodataFormat, page.StructuredData[0].Format)
+		assert.Equal(t, []string{"Product"}, page.StructuredData[0].Types)
+		assert.JSONEq(t, `{
+			"@type": "https://schema.org/Product",
+			"name": "Crawler",
+			"image": "https://anyurl.com/crawler.png",
+			"offers": {"@type": "https://schema.org/Offer", "price": "9.90", "priceCurrency": "USD"},
+			"color": ["blue", "red"]
+		}`, string(page.StructuredData[0].Data))
+	})
+
+	t.Run("should extract RDFa resources", func(t *testing.T) {
+		page := crawlPage(t, URI, `<head><meta property="og:title" content="ignored"></head>
+			<div vocab="https://schema.org/" typeof="Person">
+				<span property="name">Ada</span>
+				<a property="url" href="https://ada.example.com">site</a>
+				<div property="address" typeof="PostalAddress"><span property="addressLocality">London</span></div>
+			</div>`)
+
+		assert.Len(t, page.StructuredData, 1)
+		assert.Equal(t, core.RDFaFormat, page.StructuredData[0].Format)
+		assert.Equal(t, []string{"Person"}, page.StructuredData[0].Types)
+		assert.JSONEq(t, `{
+			"@context": "https://schema.org/",
+			"@type": "Person",
+			"name": "Ada",
+			"url": "https://ada.example.com",
+			"address": {"@type": "PostalAddress", "addressLocality": "London"}
+		}`, string(page.StructuredData[0].Data))
+	})
+}
+
+func TestCrawl_PagesWithType(t *testing.T) {
+	product := core.Page{URI: "https://anyurl.com/product", StructuredData: []core.StructuredData{
+		{Format: core.JSONLDFormat, Types: []string{"Product"}},
+	}}
+	article := core.Page{URI: "https://anyurl.com/article", StructuredData: []core.StructuredData{
+		{Format: core.MicrodataFormat, Types: []string{"Article"}},
+		{Format: core.RDFaFormat, Types: []string{"Person"}},
+	}}
+	crawl := core.Crawl{Pages: []core.Page{product, article, {URI: "https://anyurl.com"}}}
+
+	assert.Equal(t, []core.Page{product}, crawl.PagesWithType("Product"))
+	assert.Equal(t, []core.Page{article}, crawl.PagesWithType("https://schema.org/Person"))
+	assert.Empty(t, crawl.PagesWithType("Event"))
+}
@@ -0,0 +1,65 @@
+package pager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnknownAuthProfile = errors.New("unknown authentication profile")
+
+// AuthProfile describes how the requests of a crawl are authenticated.
+type AuthProfile struct {
+	Name        string           `yaml:"name"`
+	Credentials []HostCredential `yaml:"credentials"`
+	CookiesFile string           `yaml:"cookies_file"`
+	FormLogin   *FormLogin       `yaml:"form_login"`
+}
+
+// HostCredential is sent in the Authorization header of the requests whose hostname matches the
+// pattern, as a bearer token when the token is filled or as basic credentials otherwise.
+type HostCredential struct {
+	Host     string `yaml:"host"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Token    string `yaml:"token"`
+}
+
+// FormLogin posts the fields to the URI before the crawl starts, so the session cookies are carried
+// into the crawl. When the page URI is filled, the hidden inputs of its form(like CSRF tokens) are
+// posted as well.
+type FormLogin struct {
+	PageURI string            `yaml:"page_uri"`
+	URI     string            `yaml:"uri"`
+	Fields  map[string]string `yaml:"fields"`
+}
+
+type authProfilesFile struct {
+	Profiles []AuthProfile `yaml:"profiles"`
+}
+
+// LoadAuthProfiles reads the profiles from a YAML file, expanding environment variables so secrets
+// do not need to be written in the file.
+func LoadAuthProfiles(file string) (map[string]AuthProfile, error) {
+	content, err := os.ReadFile(file) //nolint:gosec // the file is given by configuration
+	if err != nil {
+		return nil, err
+	}
+
+	var profilesFile authProfilesFile
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(content))), &profilesFile); err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]AuthProfile, len(profilesFile.Profiles))
+	for _, profile := range profilesFile.Profiles {
+		if profile.Name == "" {
+			return nil, fmt.Errorf("authentication profile without name in %s", file)
+		}
+		profiles[profile.Name] = profile
+	}
+
+	return profiles, nil
+}
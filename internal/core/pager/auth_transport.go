@@ -0,0 +1,34 @@
+package pager
+
+import (
+	"net/http"
+)
+
+// authTransport adds the credential of the first host pattern matching each request.
+type authTransport struct {
+	base        http.RoundTripper
+	credentials []HostCredential
+}
+
+func (a authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" {
+		return a.base.RoundTrip(req)
+	}
+
+	for _, credential := range a.credentials {
+		if !matchHost(credential.Host, req.URL.Hostname()) {
+			continue
+		}
+
+		req = req.Clone(req.Context())
+		if credential.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+credential.Token)
+		} else {
+			req.SetBasicAuth(credential.Username, credential.Password)
+		}
+
+		break
+	}
+
+	return a.base.RoundTrip(req)
+}
@@ -0,0 +1,145 @@
+package pager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
+	"golang.org/x/net/html"
+	"golang.org/x/net/publicsuffix"
+)
+
+var ErrLoginFailed = errors.New("form login failed")
+
+// Authenticate returns a pager bound to a fresh cookie jar and to the credentials of the profile,
+// performing the form login of the profile when there is one.
+//
+//nolint:ireturn // the interface is returned so the authenticated pager is used in place of the default one
+func (c PagerService) Authenticate(ctx context.Context, profileName string) (PagerUsecase, error) {
+	profile, found := c.authProfiles[profileName]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAuthProfile, profileName)
+	}
+
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	if err := loadCookiesFile(jar, profile.CookiesFile); err != nil {
+		log.Error("error loading cookies file", logger.FieldError(err))
+
+		return nil, err
+	}
+
+	httpClient := *c.httpClient
+	httpClient.Jar = jar
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	httpClient.Transport = authTransport{base: transport, credentials: profile.Credentials}
+
+	authenticated := c
+	authenticated.httpClient = &httpClient
+	if profile.FormLogin != nil {
+		if err := authenticated.login(ctx, *profile.FormLogin); err != nil {
+			log.Error("error performing form login", logger.FieldError(err))
+
+			return nil, err
+		}
+	}
+
+	return authenticated, nil
+}
+
+func loadCookiesFile(jar http.CookieJar, file string) error {
+	if file == "" {
+		return nil
+	}
+
+	reader, err := os.Open(file) //nolint:gosec // the file is given by configuration
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	cookies, err := ParseCookiesFile(reader)
+	if err != nil {
+		return err
+	}
+
+	for uri, hostCookies := range cookies {
+		jar.SetCookies(uri, hostCookies)
+	}
+
+	return nil
+}
+
+func (c PagerService) login(ctx context.Context, formLogin FormLogin) error {
+	fields := url.Values{}
+	if formLogin.PageURI != "" {
+		page, err := c.GetPage(ctx, formLogin.PageURI)
+		if err != nil {
+			return err
+		}
+
+		for name, value := range hiddenInputs(page.Node) {
+			fields.Set(name, value)
+		}
+	}
+
+	for name, value := range formLogin.Fields {
+		fields.Set(name, value)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, formLogin.URI, strings.NewReader(fields.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%w: status %d", ErrLoginFailed, response.StatusCode)
+	}
+
+	return nil
+}
+
+func hiddenInputs(node *html.Node) map[string]string {
+	inputs := make(map[string]string)
+	if node == nil {
+		return inputs
+	}
+
+	if node.Type == html.ElementNode && node.Data == "input" {
+		attrs := make(map[string]string, len(node.Attr))
+		for _, attr := range node.Attr {
+			attrs[attr.Key] = attr.Val
+		}
+		if strings.EqualFold(attrs["type"], "hidden") && attrs["name"] != "" {
+			inputs[attrs["name"]] = attrs["value"]
+		}
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		for name, value := range hiddenInputs(child) {
+			inputs[name] = value
+		}
+	}
+
+	return inputs
+}
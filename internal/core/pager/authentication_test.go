@@ -0,0 +1,177 @@
+package pager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCookiesFile(t *testing.T) {
+	content := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"",
+		".example.com\tTRUE\t/\tTRUE\t0\tsession\tabc",
+		"#HttpOnly_intranet.com\tFALSE\t/app\tFALSE\t2147483647\ttoken\txyz",
+	}, "\n")
+
+	cookies, err := ParseCookiesFile(strings.NewReader(content))
+
+	assert.NoError(t, err)
+	assert.Len(t, cookies, 2)
+	for uri, hostCookies := range cookies {
+		assert.Len(t, hostCookies, 1)
+		switch uri.String() {
+		case "https://example.com/":
+			assert.Equal(t, "session", hostCookies[0].Name)
+			assert.Equal(t, "example.com", hostCookies[0].Domain)
+			assert.True(t, hostCookies[0].Secure)
+		case "http://intranet.com/":
+			assert.Equal(t, "token", hostCookies[0].Name)
+			assert.Empty(t, hostCookies[0].Domain)
+			assert.True(t, hostCookies[0].HttpOnly)
+			assert.Equal(t, int64(2147483647), hostCookies[0].Expires.Unix())
+		default:
+			assert.Fail(t, "unexpected URL", uri.String())
+		}
+	}
+
+	t.Run("should return error when line is malformed", func(t *testing.T) {
+		_, err := ParseCookiesFile(strings.NewReader("example.com\tTRUE\t/"))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestPagerService_Authenticate(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<form><input type="hidden" name="csrf" value="token-123"></form>`))
+	})
+	mux.HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("csrf") != "token-123" || r.PostFormValue("password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "logged-in", Path: "/"})
+		http.Redirect(w, r, "/private", http.StatusFound)
+	})
+	mux.HandleFunc("/private", func(w http.ResponseWriter, r *http.Request) {
+		session, err := r.Cookie("session")
+		if err != nil || session.Value != "logged-in" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+		_, _ = w.Write([]byte(`<a href="http://google.com">link</a>`))
+	})
+	mux.HandleFunc("/authorization", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	hostname := strings.Split(host, ":")[0]
+
+	cookiesFile := filepath.Join(t.TempDir(), "cookies.txt")
+	err := os.WriteFile(cookiesFile, []byte(hostname+"\tFALSE\t/\tFALSE\t0\tsession\tlogged-in\n"), 0o600)
+	assert.NoError(t, err)
+
+	profiles := map[string]AuthProfile{
+		"form": {
+			Name: "form",
+			FormLogin: &FormLogin{
+				PageURI: server.URL + "/login",
+				URI:     server.URL + "/session",
+				Fields:  map[string]string{"username": "crawler", "password": "secret"},
+			},
+		},
+		"wrong-form": {
+			Name:      "wrong-form",
+			FormLogin: &FormLogin{URI: server.URL + "/session", Fields: map[string]string{"password": "wrong"}},
+		},
+		"cookies": {Name: "cookies", CookiesFile: cookiesFile},
+		"basic": {
+			Name:        "basic",
+			Credentials: []HostCredential{{Host: hostname, Username: "crawler", Password: "secret"}},
+		},
+		"bearer": {
+			Name:        "bearer",
+			Credentials: []HostCredential{{Host: "*.other.com", Username: "other"}, {Host: hostname, Token: "abc"}},
+		},
+	}
+	pagerService := NewPagerService(&http.Client{}, WithAuthProfiles(profiles))
+	ctx := context.Background()
+
+	t.Run("should return error when profile is unknown", func(t *testing.T) {
+		_, err := pagerService.Authenticate(ctx, "unknown")
+
+		assert.ErrorIs(t, err, ErrUnknownAuthProfile)
+	})
+	t.Run("should carry the session from form login into the crawl", func(t *testing.T) {
+		authenticated, err := pagerService.Authenticate(ctx, "form")
+		assert.NoError(t, err)
+
+		page, err := authenticated.GetPage(ctx, server.URL+"/private")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, page.StatusCode)
+	})
+	t.Run("should return error when form login fails", func(t *testing.T) {
+		_, err := pagerService.Authenticate(ctx, "wrong-form")
+
+		assert.ErrorIs(t, err, ErrLoginFailed)
+	})
+	t.Run("should send cookies imported from cookies file", func(t *testing.T) {
+		authenticated, err := pagerService.Authenticate(ctx, "cookies")
+		assert.NoError(t, err)
+
+		page, err := authenticated.GetPage(ctx, server.URL+"/private")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, page.StatusCode)
+	})
+	t.Run("should not share the session with requests without profile", func(t *testing.T) {
+		page, err := pagerService.GetPage(ctx, server.URL+"/private")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, page.StatusCode)
+	})
+	t.Run("should send credentials scoped by host", func(t *testing.T) {
+		for profile, expected := range map[string]string{"basic": "Basic Y3Jhd2xlcjpzZWNyZXQ=", "bearer": "Bearer abc"} {
+			authenticated, err := pagerService.Authenticate(ctx, profile)
+			assert.NoError(t, err)
+
+			page, err := authenticated.GetPage(ctx, server.URL+"/authorization")
+
+			assert.NoError(t, err)
+			assert.Contains(t, textContent(page.Node), expected)
+		}
+	})
+	t.Run("should not send credentials to other hosts", func(t *testing.T) {
+		transport := authTransport{
+			base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				assert.Empty(t, req.Header.Get("Authorization"))
+
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}),
+			credentials: profiles["basic"].Credentials,
+		}
+		_, err := transport.RoundTrip(&http.Request{URL: &url.URL{Scheme: "http", Host: "google.com"}, Header: http.Header{}})
+
+		assert.NoError(t, err)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
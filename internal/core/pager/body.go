@@ -0,0 +1,143 @@
+package pager
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html/charset"
+)
+
+const acceptEncoding = "gzip, deflate, br"
+
+var (
+	ErrBodyTooLarge               = errors.New("response body exceeds the maximum size allowed")
+	ErrUnsupportedContentEncoding = errors.New("unsupported content encoding")
+)
+
+// readBody returns the body with the content codings removed, as it is archived and hashed, being
+// the charset conversion left to the parsing.
+func (c PagerService) readBody(response *http.Response) (io.Reader, error) {
+	if c.maxBodySize > 0 && response.ContentLength > c.maxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+
+	var body io.Reader = response.Body
+	if c.decompression {
+		decompressed, err := decompress(body, response.Header.Get("Content-Encoding"))
+		if err != nil {
+			return nil, err
+		}
+		body = decompressed
+	}
+
+	if c.maxBodySize > 0 {
+		body = &limitedReader{reader: body, remaining: c.maxBodySize}
+	}
+
+	return body, nil
+}
+
+// detectCharset returns the name of the charset of the body, found from its byte order mark, the
+// Content-Type or its meta tags, being empty when the detection is disabled.
+func (c PagerService) detectCharset(body []byte, contentType string) string {
+	if !c.charsetDetection {
+		return ""
+	}
+	_, name, _ := charset.DetermineEncoding(body, contentType)
+
+	return name
+}
+
+// DecodeBody converts the body from the charset given to UTF-8, an empty charset meaning the body is
+// read as it is. It is used to parse again the bodies archived along with their charset.
+func DecodeBody(body []byte, charsetName string) (io.Reader, error) {
+	if charsetName == "" {
+		return bytes.NewReader(body), nil
+	}
+
+	return charset.NewReaderLabel(charsetName, bytes.NewReader(body))
+}
+
+// decompress decodes the body following the content codings in the reverse order they were applied.
+func decompress(body io.Reader, contentEncoding string) (io.Reader, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+
+		var err error
+		switch encoding {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			body, err = gzip.NewReader(body)
+		case "deflate":
+			body, err = newDeflateReader(body)
+		case "br":
+			body = brotli.NewReader(body)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentEncoding, encoding)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+// newDeflateReader handles the zlib wrapped format defined by the RFC and the raw deflate
+// format that some servers send instead.
+func newDeflateReader(body io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(body)
+	header, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if isZlibHeader(header[0], header[1]) {
+		return zlib.NewReader(buffered)
+	}
+
+	return flate.NewReader(buffered), nil
+}
+
+func isZlibHeader(cmf, flg byte) bool {
+	const (
+		deflateMethod = 8
+		checkDivisor  = 31
+	)
+
+	return cmf&0x0f == deflateMethod && (uint16(cmf)<<8|uint16(flg))%checkDivisor == 0
+}
+
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrBodyTooLarge
+	}
+
+	return n, err
+}
@@ -0,0 +1,16 @@
+package pager
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBodyNotArchived is returned when there is no body archived with the digest asked.
+var ErrBodyNotArchived = errors.New("body not archived")
+
+// BodyArchive keeps the bodies of the pages fetched keyed by their SHA-256 digest, so identical
+// bodies are kept once.
+type BodyArchive interface {
+	Store(ctx context.Context, digest string, body []byte) error
+	Load(ctx context.Context, digest string) ([]byte, error)
+}
@@ -0,0 +1,169 @@
+package pager
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestDecompress(t *testing.T) {
+	content := `<a href="http://google.com">link</a>`
+
+	compress := func(newWriter func(io.Writer) io.WriteCloser) io.Reader {
+		buffer := &bytes.Buffer{}
+		writer := newWriter(buffer)
+		_, _ = writer.Write([]byte(content))
+		_ = writer.Close()
+
+		return buffer
+	}
+
+	testCases := map[string]struct {
+		body            io.Reader
+		contentEncoding string
+	}{
+		"should return the same body when there is no encoding": {
+			body:            strings.NewReader(content),
+			contentEncoding: "",
+		},
+		"should decode gzip body": {
+			body:            compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }),
+			contentEncoding: "gzip",
+		},
+		"should decode zlib wrapped deflate body": {
+			body:            compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }),
+			contentEncoding: "deflate",
+		},
+		"should decode raw deflate body": {
+			body: compress(func(w io.Writer) io.WriteCloser {
+				writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+
+				return writer
+			}),
+			contentEncoding: "deflate",
+		},
+		"should decode brotli body": {
+			body:            compress(func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }),
+			contentEncoding: "br",
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			body, err := decompress(test.body, test.contentEncoding)
+			assert.NoError(t, err)
+
+			decoded, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			assert.Equal(t, content, string(decoded))
+		})
+	}
+
+	t.Run("should return error when encoding is not supported", func(t *testing.T) {
+		_, err := decompress(strings.NewReader(content), "compress")
+
+		assert.ErrorIs(t, err, ErrUnsupportedContentEncoding)
+	})
+}
+
+func TestPagerService_GetPage_Body(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/large", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("a"), 2048))
+	})
+	mux.HandleFunc("/gzip", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, acceptEncoding, r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		_, _ = writer.Write([]byte(`<a href="http://google.com">link</a>`))
+		_ = writer.Close()
+	})
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=ISO-8859-1")
+		_, _ = w.Write([]byte("<a href=\"http://google.com\">caf\xe9</a>"))
+	})
+	mux.HandleFunc("/meta-charset", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><meta charset=\"shift_jis\"></head><body><a href=\"http://google.com\">\x83e\x83X\x83g</a></body></html>"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	pagerService := NewPagerService(
+		&http.Client{},
+		WithMaxBodySize(1024),
+		WithDecompression(true),
+		WithCharsetDetection(true),
+	)
+
+	t.Run("should return error when body exceeds the maximum size", func(t *testing.T) {
+		page, err := pagerService.GetPage(context.Background(), server.URL+"/large")
+
+		assert.ErrorIs(t, err, ErrBodyTooLarge)
+		assert.Nil(t, page.Node)
+	})
+	t.Run("should decode compressed body", func(t *testing.T) {
+		page, err := pagerService.GetPage(context.Background(), server.URL+"/gzip")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "link", anchorText(page.Node))
+	})
+	t.Run("should decode charset from content type header", func(t *testing.T) {
+		page, err := pagerService.GetPage(context.Background(), server.URL+"/latin1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "café", anchorText(page.Node))
+	})
+	t.Run("should decode charset from meta tag", func(t *testing.T) {
+		page, err := pagerService.GetPage(context.Background(), server.URL+"/meta-charset")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "テスト", anchorText(page.Node))
+	})
+}
+
+func anchorText(node *html.Node) string {
+	if node == nil {
+		return ""
+	}
+
+	if node.Type == html.ElementNode && node.Data == "a" && node.FirstChild != nil {
+		return node.FirstChild.Data
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if text := anchorText(child); text != "" {
+			return text
+		}
+	}
+
+	return ""
+}
+
+func textContent(node *html.Node) string {
+	if node == nil {
+		return ""
+	}
+
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+
+	text := ""
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text += textContent(child)
+	}
+
+	return text
+}
@@ -0,0 +1,43 @@
+package pager
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// Certificate holds the details of the certificate presented by a host.
+type Certificate struct {
+	Host      string
+	Subject   string
+	Issuer    string
+	NotBefore time.Time
+	NotAfter  time.Time
+	SANs      []string
+}
+
+// ExpiresWithin reports whether the certificate is expired or expires before the duration elapses.
+func (c Certificate) ExpiresWithin(now time.Time, within time.Duration) bool {
+	return !c.NotAfter.After(now.Add(within))
+}
+
+func newCertificate(host string, state *tls.ConnectionState) *Certificate {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	leaf := state.PeerCertificates[0]
+	sans := make([]string, 0, len(leaf.DNSNames)+len(leaf.IPAddresses))
+	sans = append(sans, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return &Certificate{
+		Host:      host,
+		Subject:   leaf.Subject.String(),
+		Issuer:    leaf.Issuer.String(),
+		NotBefore: leaf.NotBefore.UTC(),
+		NotAfter:  leaf.NotAfter.UTC(),
+		SANs:      sans,
+	}
+}
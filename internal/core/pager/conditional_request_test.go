@@ -0,0 +1,65 @@
+package pager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagerService_GetPageIfModified(t *testing.T) {
+	const (
+		etag         = `"v1"`
+		lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+		body         = `<a href="http://google.com">link</a>`
+		bodyHash     = "4ada44d8464f4129118ed258d8ed667e5832320e2f0b81ce4d07c056c679dccc"
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-None-Match") == etag || r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	pagerService := NewPagerService(&http.Client{})
+	ctx := context.Background()
+
+	t.Run("should return validators and content hash when page is fetched in full", func(t *testing.T) {
+		page, err := pagerService.GetPage(ctx, server.URL)
+
+		assert.NoError(t, err)
+		assert.False(t, page.NotModified)
+		assert.NotNil(t, page.Node)
+		assert.Equal(t, etag, page.ETag)
+		assert.Equal(t, lastModified, page.LastModified)
+		assert.Equal(t, bodyHash, page.ContentHash)
+	})
+	t.Run("should return not modified page when ETag matches", func(t *testing.T) {
+		page, err := pagerService.GetPageIfModified(ctx, server.URL, Validators{ETag: etag})
+
+		assert.NoError(t, err)
+		assert.True(t, page.NotModified)
+		assert.Equal(t, http.StatusNotModified, page.StatusCode)
+		assert.Nil(t, page.Node)
+	})
+	t.Run("should return not modified page when Last-Modified matches", func(t *testing.T) {
+		page, err := pagerService.GetPageIfModified(ctx, server.URL, Validators{LastModified: lastModified})
+
+		assert.NoError(t, err)
+		assert.True(t, page.NotModified)
+	})
+	t.Run("should return page in full when validators do not match", func(t *testing.T) {
+		page, err := pagerService.GetPageIfModified(ctx, server.URL, Validators{ETag: `"v0"`})
+
+		assert.NoError(t, err)
+		assert.False(t, page.NotModified)
+		assert.NotNil(t, page.Node)
+	})
+}
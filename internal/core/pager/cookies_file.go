@@ -0,0 +1,80 @@
+package pager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	httpOnlyPrefix    = "#HttpOnly_"
+	cookiesFileFields = 7
+)
+
+// ParseCookiesFile reads cookies in the Netscape cookies.txt format, grouped by the URL they
+// must be set for in a cookie jar.
+func ParseCookiesFile(reader io.Reader) (map[*url.URL][]*http.Cookie, error) {
+	cookies := make(map[string][]*http.Cookie)
+	urls := make(map[string]*url.URL)
+
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(text, httpOnlyPrefix)
+		text = strings.TrimPrefix(text, httpOnlyPrefix)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != cookiesFileFields {
+			return nil, fmt.Errorf("invalid cookies file at line %d", line)
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie expiration at line %d: %w", line, err)
+		}
+
+		host := strings.TrimPrefix(fields[0], ".")
+		secure := strings.EqualFold(fields[3], "TRUE")
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		key := scheme + "://" + host
+		if _, found := urls[key]; !found {
+			urls[key] = &url.URL{Scheme: scheme, Host: host, Path: "/"}
+		}
+		cookies[key] = append(cookies[key], cookie)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[*url.URL][]*http.Cookie, len(cookies))
+	for key, hostCookies := range cookies {
+		grouped[urls[key]] = hostCookies
+	}
+
+	return grouped, nil
+}
@@ -0,0 +1,132 @@
+package pager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+var ErrForbiddenDestination = errors.New("destination address is not allowed")
+
+// reservedPrefixes are ranges not covered by the netip.Addr helpers that must not be reached either.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// DestinationGuard refuses connections to private, loopback, link-local and multicast addresses,
+// except for the trusted ranges in the allowlist.
+type DestinationGuard struct {
+	allowlist []netip.Prefix
+}
+
+func NewDestinationGuard(allowlist []netip.Prefix) DestinationGuard {
+	return DestinationGuard{allowlist: allowlist}
+}
+
+// ParseAllowlist parses a comma separated list of CIDRs or single IP addresses.
+func ParseAllowlist(value string) ([]netip.Prefix, error) {
+	allowlist := make([]netip.Prefix, 0)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, err
+			}
+			allowlist = append(allowlist, netip.PrefixFrom(addr, addr.BitLen()))
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, err
+		}
+		allowlist = append(allowlist, prefix.Masked())
+	}
+
+	return allowlist, nil
+}
+
+// Control is meant to be used as the Control function of a net.Dialer. It runs with the address
+// resolved for the connection being made, so every connection is checked against the IP actually
+// dialed, including the ones made after redirects or when DNS answers change between lookups.
+func (g DestinationGuard) Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !g.Allowed(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, addr)
+	}
+
+	return nil
+}
+
+// CheckHost resolves the hostname and checks every address found. It is meant for connections made
+// through a proxy, where the address actually dialed to the destination is not known by the dialer.
+func (g DestinationGuard) CheckHost(ctx context.Context, hostname string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", hostname)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		if !g.Allowed(addr) {
+			return fmt.Errorf("%w: %s", ErrForbiddenDestination, addr)
+		}
+	}
+
+	return nil
+}
+
+// Allowed reports whether a connection to the address can be made.
+func (g DestinationGuard) Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	for _, prefix := range g.allowlist {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return !isInternalAddr(addr)
+}
+
+func isInternalAddr(addr netip.Addr) bool {
+	if addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() {
+		return true
+	}
+
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,92 @@
+package pager
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDestinationGuard_Allowed(t *testing.T) {
+	testCases := map[string]struct {
+		address  string
+		expected bool
+	}{
+		"should allow public IPv4 address":           {address: "93.184.216.34", expected: true},
+		"should allow public IPv6 address":           {address: "2606:2800:220:1:248:1893:25c8:1946", expected: true},
+		"should refuse loopback address":             {address: "127.0.0.1", expected: false},
+		"should refuse IPv6 loopback address":        {address: "::1", expected: false},
+		"should refuse private address":              {address: "10.1.2.3", expected: false},
+		"should refuse cloud metadata address":       {address: "169.254.169.254", expected: false},
+		"should refuse multicast address":            {address: "224.0.0.1", expected: false},
+		"should refuse unspecified address":          {address: "0.0.0.0", expected: false},
+		"should refuse carrier grade NAT address":    {address: "100.64.0.1", expected: false},
+		"should refuse IPv4 mapped loopback address": {address: "::ffff:127.0.0.1", expected: false},
+		"should refuse IPv6 unique local address":    {address: "fd00::1", expected: false},
+	}
+
+	guard := NewDestinationGuard(nil)
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, guard.Allowed(netip.MustParseAddr(test.address)))
+		})
+	}
+
+	t.Run("should allow internal address in the allowlist", func(t *testing.T) {
+		allowlist, err := ParseAllowlist("10.0.0.0/8, 192.168.1.10")
+		assert.NoError(t, err)
+
+		guard := NewDestinationGuard(allowlist)
+
+		assert.True(t, guard.Allowed(netip.MustParseAddr("10.20.30.40")))
+		assert.True(t, guard.Allowed(netip.MustParseAddr("192.168.1.10")))
+		assert.False(t, guard.Allowed(netip.MustParseAddr("192.168.1.11")))
+	})
+	t.Run("should return error when allowlist is invalid", func(t *testing.T) {
+		_, err := ParseAllowlist("10.0.0.0/33")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestDestinationGuard_Control(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<a href="http://google.com">link</a>`))
+	}))
+	defer server.Close()
+
+	newPagerService := func(guard DestinationGuard) PagerService {
+		dialer := &net.Dialer{Control: guard.Control}
+		transport := &http.Transport{DialContext: dialer.DialContext}
+
+		return NewPagerService(&http.Client{Transport: transport})
+	}
+
+	t.Run("should refuse to connect to loopback address", func(t *testing.T) {
+		page, err := newPagerService(NewDestinationGuard(nil)).GetPage(context.Background(), server.URL)
+
+		assert.ErrorIs(t, err, ErrForbiddenDestination)
+		assert.Nil(t, page.Node)
+	})
+	t.Run("should refuse to connect to hostname resolved to loopback address", func(t *testing.T) {
+		uri := "http://localhost:" + strconv.Itoa(server.Listener.Addr().(*net.TCPAddr).Port)
+
+		_, err := newPagerService(NewDestinationGuard(nil)).GetPage(context.Background(), uri)
+
+		assert.ErrorIs(t, err, ErrForbiddenDestination)
+	})
+	t.Run("should connect to loopback address when it is in the allowlist", func(t *testing.T) {
+		allowlist, err := ParseAllowlist("127.0.0.0/8")
+		assert.NoError(t, err)
+
+		page, err := newPagerService(NewDestinationGuard(allowlist)).GetPage(context.Background(), server.URL)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, page.Node)
+	})
+}
@@ -0,0 +1,16 @@
+package pager
+
+import (
+	"path"
+	"strings"
+)
+
+// matchHost matches the hostname against a glob pattern, where *.example.com also matches example.com.
+func matchHost(pattern, hostname string) bool {
+	pattern, hostname = strings.ToLower(pattern), strings.ToLower(hostname)
+	if matched, _ := path.Match(pattern, hostname); matched {
+		return true
+	}
+
+	return strings.HasPrefix(pattern, "*.") && hostname == pattern[2:]
+}
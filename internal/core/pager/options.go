@@ -0,0 +1,40 @@
+package pager
+
+// Option configures how the PagerService fetches and reads pages.
+type Option func(*PagerService)
+
+// WithMaxBodySize limits the size in bytes of the decoded response body of each page, zero means unlimited.
+func WithMaxBodySize(size int64) Option {
+	return func(p *PagerService) {
+		p.maxBodySize = size
+	}
+}
+
+// WithDecompression makes the pager ask for and decode gzip, deflate and brotli compressed responses.
+func WithDecompression(enabled bool) Option {
+	return func(p *PagerService) {
+		p.decompression = enabled
+	}
+}
+
+// WithCharsetDetection makes the pager convert non UTF-8 pages using the charset found
+// in the Content-Type header, the BOM or the <meta charset> tag.
+func WithCharsetDetection(enabled bool) Option {
+	return func(p *PagerService) {
+		p.charsetDetection = enabled
+	}
+}
+
+// WithAuthProfiles sets the profiles that can be used to authenticate a crawl.
+func WithAuthProfiles(profiles map[string]AuthProfile) Option {
+	return func(p *PagerService) {
+		p.authProfiles = profiles
+	}
+}
+
+// WithBodyArchive makes the pager keep the body of every page parsed in the archive, nil means not archiving.
+func WithBodyArchive(archive BodyArchive) Option {
+	return func(p *PagerService) {
+		p.bodyArchive = archive
+	}
+}
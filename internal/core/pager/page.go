@@ -0,0 +1,27 @@
+package pager
+
+import "golang.org/x/net/html"
+
+// Page is the result of fetching a URI. When the page did not change since the validators given
+// to the request, it is flagged as not modified and has no node. The body is archived with the
+// ContentHash as its key, being BodyKey empty when it was not archived. Both are taken from the body
+// as fetched, only with its content codings removed, whose Charset is needed to parse it again.
+type Page struct {
+	URI          string
+	StatusCode   int
+	Redirects    []Redirect
+	Certificate  *Certificate
+	ETag         string
+	LastModified string
+	ContentHash  string
+	BodyKey      string
+	Charset      string
+	NotModified  bool
+	Node         *html.Node
+}
+
+// Validators are the values of a previous response used to make a conditional request.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
@@ -1,6 +1,10 @@
 package pager
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"net/http"
 
 	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
@@ -10,32 +14,119 @@ import (
 var log = logger.GetLogger()
 
 type PagerService struct {
-	httpClient *http.Client
+	httpClient       *http.Client
+	maxBodySize      int64
+	decompression    bool
+	charsetDetection bool
+	authProfiles     map[string]AuthProfile
+	bodyArchive      BodyArchive
 }
 
-func NewPagerService(httpClient *http.Client) PagerService {
-	return PagerService{httpClient: httpClient}
+func NewPagerService(httpClient *http.Client, opts ...Option) PagerService {
+	pagerService := PagerService{httpClient: httpClient}
+	for _, opt := range opts {
+		opt(&pagerService)
+	}
+
+	return pagerService
 }
 
-func (c PagerService) GetNode(uri string) (*html.Node, error) {
-	response, err := c.httpClient.Get(uri)
+func (c PagerService) GetPage(ctx context.Context, uri string) (Page, error) {
+	return c.GetPageIfModified(ctx, uri, Validators{})
+}
+
+// GetPageIfModified makes a conditional request when validators are given, so a page that did not
+// change is answered with 304 and returned as not modified.
+func (c PagerService) GetPageIfModified(ctx context.Context, uri string, validators Validators) (Page, error) {
+	page := Page{URI: uri}
+	ctx, chain := withRedirectChain(ctx)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		log.Error("error to create request to provider", logger.FieldError(err))
+
+		return page, err
+	}
+	if c.decompression {
+		request.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	if validators.ETag != "" {
+		request.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		request.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	response, err := c.httpClient.Do(request)
+	page.Redirects = chain.hops
+	if err != nil {
+		log.Error("error to perform get request in provider", logger.FieldError(err))
+
+		return page, err
+	}
 	defer func() {
-		if err == nil {
-			_ = response.Body.Close()
-		}
+		_ = response.Body.Close()
 	}()
+	page.StatusCode = response.StatusCode
+	if response.TLS != nil {
+		page.Certificate = newCertificate(response.Request.URL.Hostname(), response.TLS)
+	}
+	page.ETag = response.Header.Get("ETag")
+	page.LastModified = response.Header.Get("Last-Modified")
+
+	if response.StatusCode == http.StatusNotModified {
+		page.NotModified = true
+
+		return page, nil
+	}
+
+	body, err := c.readBody(response)
 	if err != nil {
-		log.Error("error to perform get request in provider", logger.FieldError(err))
+		log.Error("error to read response body", logger.FieldError(err))
+
+		return page, err
+	}
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		log.Error("error to read response body", logger.FieldError(err))
+
+		return page, err
+	}
+	page.Charset = c.detectCharset(raw, response.Header.Get("Content-Type"))
+	decoded, err := DecodeBody(raw, page.Charset)
+	if err != nil {
+		log.Error("error to decode response body", logger.FieldError(err))
 
-		return nil, err
+		return page, err
 	}
 
-	node, err := html.Parse(response.Body)
+	node, err := html.Parse(decoded)
 	if err != nil {
 		log.Error("error to parse response body to html", logger.FieldError(err))
 
-		return nil, err
+		return page, err
+	}
+	digest := sha256.Sum256(raw)
+	page.Node = node
+	page.ContentHash = hex.EncodeToString(digest[:])
+	page.BodyKey = c.archiveBody(ctx, page.ContentHash, raw)
+
+	return page, nil
+}
+
+// archiveBody keeps the body read in the archive, returning the key it was kept with. A failure to
+// archive does not fail the page, being only logged.
+func (c PagerService) archiveBody(ctx context.Context, digest string, body []byte) string {
+	if c.bodyArchive == nil {
+		return ""
+	}
+
+	if err := c.bodyArchive.Store(ctx, digest, body); err != nil {
+		log.Error("error to archive response body", logger.FieldError(err))
+
+		return ""
 	}
 
-	return node, nil
+	return digest
 }
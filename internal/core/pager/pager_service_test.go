@@ -1,7 +1,13 @@
 package pager
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"io"
 	"net/http"
 	"testing"
 
@@ -9,7 +15,7 @@ import (
 	"gopkg.in/h2non/gock.v1"
 )
 
-func TestPagerService_GetNode(t *testing.T) {
+func TestPagerService_GetPage(t *testing.T) {
 	testCases := []struct {
 		name          string
 		uri           string
@@ -49,14 +55,15 @@ func TestPagerService_GetNode(t *testing.T) {
 			defer gock.Off()
 			httpClient := httpClientMock(test)
 
-			node, err := NewPagerService(httpClient).GetNode(test.uri)
+			page, err := NewPagerService(httpClient).GetPage(context.Background(), test.uri)
 
 			if test.isExpectedErr {
 				assert.ErrorIs(t, err, test.expectedErr)
-				assert.Nil(t, node)
+				assert.Nil(t, page.Node)
 			} else {
 				assert.NoError(t, err)
-				assert.NotNil(t, node)
+				assert.NotNil(t, page.Node)
+				assert.Equal(t, test.statusCode, page.StatusCode)
 			}
 		})
 	}
@@ -85,3 +92,100 @@ func httpClientMock(
 
 	return httpClient
 }
+
+func TestPagerService_BodyArchive(t *testing.T) {
+	uri := "http://archived-page.com"
+	body := `<a href="http://archived-page.com/about">about</a>`
+
+	t.Run("should archive the body keyed by its content hash", func(t *testing.T) {
+		defer gock.Off()
+		gock.New(uri).Reply(http.StatusOK).BodyString(body)
+		httpClient := &http.Client{Transport: &http.Transport{}}
+		gock.InterceptClient(httpClient)
+		archive := bodyArchiveFake{bodies: map[string][]byte{}}
+
+		page, err := NewPagerService(httpClient, WithBodyArchive(archive)).GetPage(context.Background(), uri)
+
+		assert.NoError(t, err)
+		assert.Equal(t, page.ContentHash, page.BodyKey)
+		assert.Equal(t, []byte(body), archive.bodies[page.BodyKey])
+	})
+
+	t.Run("should archive and hash the body as fetched, keeping its charset", func(t *testing.T) {
+		defer gock.Off()
+		latin1 := []byte("<title>P\xe1gina</title>")
+		compressed := &bytes.Buffer{}
+		writer := gzip.NewWriter(compressed)
+		_, _ = writer.Write(latin1)
+		_ = writer.Close()
+		gock.New(uri).Reply(http.StatusOK).
+			SetHeader("Content-Type", "text/html; charset=ISO-8859-1").
+			SetHeader("Content-Encoding", "gzip").
+			Body(compressed)
+		httpClient := &http.Client{Transport: &http.Transport{}}
+		gock.InterceptClient(httpClient)
+		archive := bodyArchiveFake{bodies: map[string][]byte{}}
+
+		page, err := NewPagerService(httpClient, WithBodyArchive(archive), WithDecompression(true), WithCharsetDetection(true)).
+			GetPage(context.Background(), uri)
+
+		assert.NoError(t, err)
+		digest := sha256.Sum256(latin1)
+		assert.Equal(t, hex.EncodeToString(digest[:]), page.ContentHash)
+		assert.Equal(t, latin1, archive.bodies[page.BodyKey])
+		assert.Equal(t, "windows-1252", page.Charset)
+		decoded, err := DecodeBody(archive.bodies[page.BodyKey], page.Charset)
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(decoded)
+		assert.Equal(t, "<title>Página</title>", string(content))
+	})
+
+	t.Run("should return the page without body key when archiving fails", func(t *testing.T) {
+		defer gock.Off()
+		gock.New(uri).Reply(http.StatusOK).BodyString(body)
+		httpClient := &http.Client{Transport: &http.Transport{}}
+		gock.InterceptClient(httpClient)
+		archive := bodyArchiveFake{err: errors.New("archive unavailable")}
+
+		page, err := NewPagerService(httpClient, WithBodyArchive(archive)).GetPage(context.Background(), uri)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, page.Node)
+		assert.Empty(t, page.BodyKey)
+	})
+
+	t.Run("should not archive when no archive is given", func(t *testing.T) {
+		defer gock.Off()
+		gock.New(uri).Reply(http.StatusOK).BodyString(body)
+		httpClient := &http.Client{Transport: &http.Transport{}}
+		gock.InterceptClient(httpClient)
+
+		page, err := NewPagerService(httpClient).GetPage(context.Background(), uri)
+
+		assert.NoError(t, err)
+		assert.Empty(t, page.BodyKey)
+	})
+}
+
+type bodyArchiveFake struct {
+	bodies map[string][]byte
+	err    error
+}
+
+func (b bodyArchiveFake) Store(_ context.Context, digest string, body []byte) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.bodies[digest] = body
+
+	return nil
+}
+
+func (b bodyArchiveFake) Load(_ context.Context, digest string) ([]byte, error) {
+	body, found := b.bodies[digest]
+	if !found {
+		return nil, ErrBodyNotArchived
+	}
+
+	return body, nil
+}
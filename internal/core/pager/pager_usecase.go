@@ -1,9 +1,9 @@
 package pager
 
-import (
-	"golang.org/x/net/html"
-)
+import "context"
 
 type PagerUsecase interface {
-	GetNode(uri string) (*html.Node, error)
+	GetPage(ctx context.Context, uri string) (Page, error)
+	GetPageIfModified(ctx context.Context, uri string, validators Validators) (Page, error)
+	Authenticate(ctx context.Context, profileName string) (PagerUsecase, error)
 }
@@ -0,0 +1,164 @@
+package pager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+const directRoute = "direct"
+
+var ErrInvalidProxy = errors.New("invalid proxy configuration")
+
+var supportedProxySchemes = map[string]bool{"http": true, "https": true, "socks5": true, "socks5h": true}
+
+// ProxyRule routes the requests whose hostname matches the pattern through the pool of proxies,
+// or straight to the host when the pool is empty.
+type ProxyRule struct {
+	Pattern string
+	Proxies []*url.URL
+}
+
+// ProxySelector picks the proxy of each request using the first rule matching its hostname or
+// the default pool otherwise, rotating over the proxies of the pool on every request.
+type ProxySelector struct {
+	rules       []ProxyRule
+	defaultPool []*url.URL
+	next        *atomic.Uint64
+}
+
+func NewProxySelector(rules []ProxyRule, defaultPool []*url.URL) ProxySelector {
+	return ProxySelector{rules: rules, defaultPool: defaultPool, next: &atomic.Uint64{}}
+}
+
+// Proxy is meant to be used as the Proxy function of an http.Transport.
+func (p ProxySelector) Proxy(req *http.Request) (*url.URL, error) {
+	pool := p.defaultPool
+	for _, rule := range p.rules {
+		if matchHost(rule.Pattern, req.URL.Hostname()) {
+			pool = rule.Proxies
+
+			break
+		}
+	}
+
+	if len(pool) == 0 {
+		return nil, nil //nolint:nilnil // a nil URL means no proxy for http.Transport
+	}
+
+	index := (p.next.Add(1) - 1) % uint64(len(pool))
+
+	return pool[index], nil
+}
+
+// HasProxies reports whether any request can be routed through a proxy.
+func (p ProxySelector) HasProxies() bool {
+	if len(p.defaultPool) > 0 {
+		return true
+	}
+
+	for _, rule := range p.rules {
+		if len(rule.Proxies) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Hosts returns the host and port of every configured proxy.
+func (p ProxySelector) Hosts() []string {
+	hosts := make([]string, 0)
+	for _, proxy := range p.defaultPool {
+		hosts = append(hosts, proxyHost(proxy))
+	}
+
+	for _, rule := range p.rules {
+		for _, proxy := range rule.Proxies {
+			hosts = append(hosts, proxyHost(proxy))
+		}
+	}
+
+	return hosts
+}
+
+// ParseProxyURLs parses a list of proxies separated by comma or pipe, applying the credentials
+// to the proxies that do not have their own.
+func ParseProxyURLs(value, username, password string) ([]*url.URL, error) {
+	proxies := make([]*url.URL, 0)
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '|' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		proxy, err := url.Parse(entry)
+		if err != nil {
+			return nil, err
+		}
+		if !supportedProxySchemes[proxy.Scheme] || proxy.Host == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidProxy, entry)
+		}
+		if proxy.User == nil && username != "" {
+			proxy.User = url.UserPassword(username, password)
+		}
+
+		proxies = append(proxies, proxy)
+	}
+
+	return proxies, nil
+}
+
+// ParseProxyRules parses rules separated by semicolon in the format pattern=proxy1|proxy2,
+// where the proxies can be replaced by the word direct to bypass any proxy.
+func ParseProxyRules(value, username, password string) ([]ProxyRule, error) {
+	rules := make([]ProxyRule, 0)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pattern, route, found := strings.Cut(entry, "=")
+		pattern, route = strings.TrimSpace(pattern), strings.TrimSpace(route)
+		if !found || pattern == "" || route == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidProxy, entry)
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidProxy, entry)
+		}
+
+		rule := ProxyRule{Pattern: pattern}
+		if !strings.EqualFold(route, directRoute) {
+			proxies, err := ParseProxyURLs(route, username, password)
+			if err != nil {
+				return nil, err
+			}
+			rule.Proxies = proxies
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func proxyHost(proxy *url.URL) string {
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+
+	switch proxy.Scheme {
+	case "https":
+		return proxy.Host + ":443"
+	case "socks5", "socks5h":
+		return proxy.Host + ":1080"
+	default:
+		return proxy.Host + ":80"
+	}
+}
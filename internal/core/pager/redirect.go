@@ -0,0 +1,82 @@
+package pager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrTooManyRedirects  = errors.New("stopped after too many redirects")
+	ErrRedirectLoop      = errors.New("redirect loop detected")
+	ErrCrossHostRedirect = errors.New("redirect to another host is not allowed")
+)
+
+// Redirect is a single hop of a redirect chain.
+type Redirect struct {
+	URI        string
+	StatusCode int
+	Location   string
+}
+
+// RedirectPolicy decides which redirects the HTTP client follows and records every hop
+// into the chain attached to the request context.
+type RedirectPolicy struct {
+	maxRedirects   int
+	allowCrossHost bool
+}
+
+func NewRedirectPolicy(maxRedirects int, allowCrossHost bool) RedirectPolicy {
+	return RedirectPolicy{maxRedirects: maxRedirects, allowCrossHost: allowCrossHost}
+}
+
+// CheckRedirect is meant to be used as the CheckRedirect function of an http.Client.
+func (r RedirectPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	previous := via[len(via)-1]
+	if chain, ok := req.Context().Value(redirectChainKey{}).(*redirectChain); ok && req.Response != nil {
+		chain.add(Redirect{
+			URI:        previous.URL.String(),
+			StatusCode: req.Response.StatusCode,
+			Location:   req.Response.Header.Get("Location"),
+		})
+	}
+
+	for _, visited := range via {
+		if visited.URL.String() == req.URL.String() {
+			return ErrRedirectLoop
+		}
+	}
+
+	if len(via) > r.maxRedirects {
+		return ErrTooManyRedirects
+	}
+
+	if !r.allowCrossHost && req.URL.Hostname() != via[0].URL.Hostname() {
+		return ErrCrossHostRedirect
+	}
+
+	return nil
+}
+
+// IsRedirectError reports whether the error was caused by the redirect policy.
+func IsRedirectError(err error) bool {
+	return errors.Is(err, ErrTooManyRedirects) ||
+		errors.Is(err, ErrRedirectLoop) ||
+		errors.Is(err, ErrCrossHostRedirect)
+}
+
+type redirectChainKey struct{}
+
+type redirectChain struct {
+	hops []Redirect
+}
+
+func withRedirectChain(ctx context.Context) (context.Context, *redirectChain) {
+	chain := &redirectChain{}
+
+	return context.WithValue(ctx, redirectChainKey{}, chain), chain
+}
+
+func (r *redirectChain) add(hop Redirect) {
+	r.hops = append(r.hops, hop)
+}
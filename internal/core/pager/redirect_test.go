@@ -0,0 +1,74 @@
+package pager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectPolicy_CheckRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/first", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/second", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/second", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/last", http.StatusFound)
+	})
+	mux.HandleFunc("/last", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<a href="http://google.com">link</a>`))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop-back", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop-back", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/cross-host", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://other-host.com/", http.StatusMovedPermanently)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	getPage := func(policy RedirectPolicy, path string) (Page, error) {
+		httpClient := &http.Client{CheckRedirect: policy.CheckRedirect}
+
+		return NewPagerService(httpClient).GetPage(context.Background(), server.URL+path)
+	}
+
+	t.Run("should record every hop of the redirect chain", func(t *testing.T) {
+		page, err := getPage(NewRedirectPolicy(10, true), "/first")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, page.Node)
+		assert.Equal(t, http.StatusOK, page.StatusCode)
+		assert.Equal(t, []Redirect{
+			{URI: server.URL + "/first", StatusCode: http.StatusMovedPermanently, Location: "/second"},
+			{URI: server.URL + "/second", StatusCode: http.StatusFound, Location: "/last"},
+		}, page.Redirects)
+	})
+	t.Run("should return error when exceed the maximum of redirects", func(t *testing.T) {
+		page, err := getPage(NewRedirectPolicy(1, true), "/first")
+
+		assert.ErrorIs(t, err, ErrTooManyRedirects)
+		assert.True(t, IsRedirectError(err))
+		assert.Nil(t, page.Node)
+		assert.Len(t, page.Redirects, 2)
+	})
+	t.Run("should return error when redirect loops", func(t *testing.T) {
+		page, err := getPage(NewRedirectPolicy(10, true), "/loop")
+
+		assert.ErrorIs(t, err, ErrRedirectLoop)
+		assert.Nil(t, page.Node)
+		assert.Len(t, page.Redirects, 2)
+		assert.Equal(t, "/loop", page.Redirects[1].Location)
+	})
+	t.Run("should return error when redirect to another host is not allowed", func(t *testing.T) {
+		page, err := getPage(NewRedirectPolicy(10, false), "/cross-host")
+
+		assert.ErrorIs(t, err, ErrCrossHostRedirect)
+		assert.Len(t, page.Redirects, 1)
+	})
+}
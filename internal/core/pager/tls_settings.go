@@ -0,0 +1,144 @@
+package pager
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidTLSSettings = errors.New("invalid TLS settings")
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSSettings are applied to every connection, and the settings of the first host pattern
+// matching the hostname are applied on top of them.
+type TLSSettings struct {
+	CAFile     string
+	MinVersion uint16
+	Hosts      []HostTLS
+}
+
+// HostTLS holds the client certificate presented to the hosts matching the pattern and whether
+// their certificate is verified.
+type HostTLS struct {
+	Host     string `yaml:"host"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+type tlsHostsFile struct {
+	Hosts []HostTLS `yaml:"hosts"`
+}
+
+// ParseTLSVersion parses versions in the 1.2 format, returning zero for an empty value.
+func ParseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+
+	parsed, found := tlsVersions[version]
+	if !found {
+		return 0, fmt.Errorf("%w: unknown TLS version %s", ErrInvalidTLSSettings, version)
+	}
+
+	return parsed, nil
+}
+
+// LoadTLSHosts reads the settings per host from a YAML file.
+func LoadTLSHosts(file string) ([]HostTLS, error) {
+	content, err := os.ReadFile(file) //nolint:gosec // the file is given by configuration
+	if err != nil {
+		return nil, err
+	}
+
+	var hostsFile tlsHostsFile
+	if err := yaml.Unmarshal(content, &hostsFile); err != nil {
+		return nil, err
+	}
+
+	return hostsFile.Hosts, nil
+}
+
+// NewTLSTransport applies the settings to the base transport, returning a round tripper that
+// dispatches each request to the transport of the first host pattern matching it.
+func NewTLSTransport(base *http.Transport, settings TLSSettings) (http.RoundTripper, error) {
+	config := &tls.Config{MinVersion: settings.MinVersion} //nolint:gosec // the minimum version is configurable
+	if settings.CAFile != "" {
+		pool, err := loadCertPool(settings.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	base.TLSClientConfig = config
+
+	transport := hostTLSTransport{base: base}
+	for _, host := range settings.Hosts {
+		hostConfig := config.Clone()
+		hostConfig.InsecureSkipVerify = host.Insecure
+
+		if host.CertFile != "" || host.KeyFile != "" {
+			certificate, err := tls.LoadX509KeyPair(host.CertFile, host.KeyFile)
+			if err != nil {
+				return nil, err
+			}
+			hostConfig.Certificates = []tls.Certificate{certificate}
+		}
+
+		hostTransport := base.Clone()
+		hostTransport.TLSClientConfig = hostConfig
+		transport.hosts = append(transport.hosts, hostRoundTripper{pattern: host.Host, transport: hostTransport})
+	}
+
+	return transport, nil
+}
+
+// loadCertPool appends the certificates of the bundle to the ones trusted by the system.
+func loadCertPool(file string) (*x509.CertPool, error) {
+	bundle, err := os.ReadFile(file) //nolint:gosec // the file is given by configuration
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("%w: no certificate found in %s", ErrInvalidTLSSettings, file)
+	}
+
+	return pool, nil
+}
+
+type hostRoundTripper struct {
+	pattern   string
+	transport http.RoundTripper
+}
+
+type hostTLSTransport struct {
+	base  http.RoundTripper
+	hosts []hostRoundTripper
+}
+
+func (h hostTLSTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, host := range h.hosts {
+		if matchHost(host.pattern, req.URL.Hostname()) {
+			return host.transport.RoundTrip(req)
+		}
+	}
+
+	return h.base.RoundTrip(req)
+}
@@ -0,0 +1,128 @@
+package pager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTLSTransport(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<a href="http://google.com">link</a>`))
+	})
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	mutualServer := httptest.NewUnstartedServer(handler)
+	mutualServer.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	mutualServer.StartTLS()
+	defer mutualServer.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", server.Certificate().Raw)
+	certFile, keyFile := writeClientCertificate(t, dir)
+
+	getPage := func(settings TLSSettings, uri string) (Page, error) {
+		transport, err := NewTLSTransport(&http.Transport{}, settings)
+		assert.NoError(t, err)
+
+		return NewPagerService(&http.Client{Transport: transport}).GetPage(context.Background(), uri)
+	}
+
+	t.Run("should return error when certificate is signed by unknown authority", func(t *testing.T) {
+		_, err := getPage(TLSSettings{}, server.URL)
+
+		assert.Error(t, err)
+	})
+	t.Run("should trust the custom CA bundle and record the certificate", func(t *testing.T) {
+		page, err := getPage(TLSSettings{CAFile: caFile, MinVersion: tls.VersionTLS12}, server.URL)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, page.Certificate)
+		assert.Equal(t, "127.0.0.1", page.Certificate.Host)
+		assert.Equal(t, server.Certificate().NotAfter.UTC(), page.Certificate.NotAfter)
+		assert.Contains(t, page.Certificate.SANs, "example.com")
+		assert.Contains(t, page.Certificate.SANs, "127.0.0.1")
+	})
+	t.Run("should skip verification only for insecure host", func(t *testing.T) {
+		page, err := getPage(TLSSettings{Hosts: []HostTLS{{Host: "127.0.0.1", Insecure: true}}}, server.URL)
+		assert.NoError(t, err)
+		assert.NotNil(t, page.Node)
+
+		_, err = getPage(TLSSettings{Hosts: []HostTLS{{Host: "*.example.com", Insecure: true}}}, server.URL)
+		assert.Error(t, err)
+	})
+	t.Run("should present client certificate to the host", func(t *testing.T) {
+		settings := TLSSettings{CAFile: caFile}
+		_, err := getPage(settings, mutualServer.URL)
+		assert.Error(t, err)
+
+		settings.Hosts = []HostTLS{{Host: "127.0.0.1", CertFile: certFile, KeyFile: keyFile}}
+		page, err := getPage(settings, mutualServer.URL)
+		assert.NoError(t, err)
+		assert.NotNil(t, page.Node)
+	})
+	t.Run("should return error when CA bundle has no certificate", func(t *testing.T) {
+		invalidFile := filepath.Join(dir, "invalid.pem")
+		assert.NoError(t, os.WriteFile(invalidFile, []byte("invalid"), 0o600))
+
+		_, err := NewTLSTransport(&http.Transport{}, TLSSettings{CAFile: invalidFile})
+
+		assert.ErrorIs(t, err, ErrInvalidTLSSettings)
+	})
+}
+
+func TestCertificate_ExpiresWithin(t *testing.T) {
+	now := time.Now()
+	certificate := Certificate{NotAfter: now.Add(10 * 24 * time.Hour)}
+
+	assert.True(t, certificate.ExpiresWithin(now, 30*24*time.Hour))
+	assert.False(t, certificate.ExpiresWithin(now, 24*time.Hour))
+}
+
+func writeClientCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "crawler"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	privateKey, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", certificate)
+	writePEM(t, keyFile, "EC PRIVATE KEY", privateKey)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, file, blockType string, content []byte) {
+	t.Helper()
+
+	encoded := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: content})
+	assert.NoError(t, os.WriteFile(file, encoded, 0o600))
+}
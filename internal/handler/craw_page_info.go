@@ -1,8 +1,15 @@
 package handler
 
+import "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+
 type crawPageInfo struct {
-	URI   string `form:"uri"`
-	Depth uint   `form:"depth"`
+	URI         string `form:"uri"`
+	Depth       uint   `form:"depth"`
+	AuthProfile string `form:"profile"`
+	Incremental bool   `form:"incremental"`
+	Refresh     bool   `form:"refresh"`
+	WARC        bool   `form:"warc"`
+	Rules       string `form:"rules"`
 }
 
 func (cp crawPageInfo) validate() error {
@@ -15,3 +22,7 @@ func (cp crawPageInfo) validate() error {
 		return nil
 	}
 }
+
+func (cp crawPageInfo) options() crawler.Options {
+	return crawler.Options{AuthProfile: cp.AuthProfile}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+)
+
+// crawlResponse is the crawl as returned to the clients asking for JSON, with what the result page
+// shows along with it.
+type crawlResponse struct {
+	URI                  string                `json:"uri"`
+	Depth                uint                  `json:"depth"`
+	Profile              string                `json:"profile,omitempty"`
+	CrawledAt            time.Time             `json:"crawledAt"`
+	FromStorage          bool                  `json:"fromStorage"`
+	Stale                bool                  `json:"stale,omitempty"`
+	Links                []string              `json:"links"`
+	Pages                []pageResponse        `json:"pages"`
+	Diff                 *diffResponse         `json:"diff,omitempty"`
+	ExpiringCertificates []certificateResponse `json:"expiringCertificates,omitempty"`
+	WARCFiles            []string              `json:"warcFiles,omitempty"`
+}
+
+type pageResponse struct {
+	URI            string                   `json:"uri"`
+	Depth          uint                     `json:"depth"`
+	StatusCode     int                      `json:"statusCode"`
+	Redirects      []redirectResponse       `json:"redirects,omitempty"`
+	RedirectLoop   bool                     `json:"redirectLoop,omitempty"`
+	Error          string                   `json:"error,omitempty"`
+	Metadata       crawler.Metadata         `json:"metadata"`
+	StructuredData []crawler.StructuredData `json:"structuredData"`
+	Fields         map[string][]string      `json:"fields,omitempty"`
+	Content        crawler.Content          `json:"content"`
+}
+
+type redirectResponse struct {
+	URI        string `json:"uri"`
+	StatusCode int    `json:"statusCode"`
+	Location   string `json:"location"`
+}
+
+// diffResponse is what changed since the previous crawl, returned by the incremental crawls.
+type diffResponse struct {
+	FirstCrawl   bool     `json:"firstCrawl"`
+	AddedPages   []string `json:"addedPages,omitempty"`
+	RemovedPages []string `json:"removedPages,omitempty"`
+	ChangedPages []string `json:"changedPages,omitempty"`
+	AddedLinks   []string `json:"addedLinks,omitempty"`
+	RemovedLinks []string `json:"removedLinks,omitempty"`
+}
+
+type certificateResponse struct {
+	Host      string    `json:"host"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	SANs      []string  `json:"sans,omitempty"`
+}
+
+func newCrawlResponse(
+	crawl crawler.Crawl,
+	diff *crawler.CrawlDiff,
+	stale bool,
+	certificates []pager.Certificate,
+	warcFiles []string,
+) crawlResponse {
+	links := crawl.Links
+	if links == nil {
+		links = []string{}
+	}
+
+	response := crawlResponse{
+		URI:         crawl.URI,
+		Depth:       crawl.Depth,
+		Profile:     crawl.Options.AuthProfile,
+		CrawledAt:   crawl.CrawledAt,
+		FromStorage: crawl.FromStorage,
+		Stale:       stale,
+		Links:       links,
+		Pages:       newPageResponses(crawl.Pages),
+		WARCFiles:   warcFiles,
+	}
+	if diff != nil {
+		response.Diff = &diffResponse{
+			FirstCrawl:   diff.FirstCrawl,
+			AddedPages:   diff.AddedPages,
+			RemovedPages: diff.RemovedPages,
+			ChangedPages: diff.ChangedPages,
+			AddedLinks:   diff.AddedLinks,
+			RemovedLinks: diff.RemovedLinks,
+		}
+	}
+	for _, certificate := range certificates {
+		response.ExpiringCertificates = append(response.ExpiringCertificates, certificateResponse(certificate))
+	}
+
+	return response
+}
+
+func newPageResponses(crawlPages []crawler.Page) []pageResponse {
+	pages := make([]pageResponse, 0, len(crawlPages))
+	for _, page := range crawlPages {
+		structuredData := page.StructuredData
+		if structuredData == nil {
+			structuredData = []crawler.StructuredData{}
+		}
+		redirects := make([]redirectResponse, 0, len(page.Redirects))
+		for _, redirect := range page.Redirects {
+			redirects = append(redirects, redirectResponse(redirect))
+		}
+		pages = append(pages, pageResponse{
+			URI:            page.URI,
+			Depth:          page.Depth,
+			StatusCode:     page.StatusCode,
+			Redirects:      redirects,
+			RedirectLoop:   page.RedirectLoop,
+			Error:          page.Error,
+			Metadata:       page.Metadata,
+			StructuredData: structuredData,
+			Fields:         page.Fields,
+			Content:        page.Content,
+		})
+	}
+
+	return pages
+}
@@ -1,13 +1,26 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	core "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
 	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/search"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/warc"
+	"github.com/spf13/viper"
 )
 
+var exportContentTypes = map[string]string{
+	core.CSVExport:   "text/csv; charset=utf-8",
+	core.JSONLExport: "application/x-ndjson",
+}
+
 type Handler struct {
 	service core.CrawlerUsecase
 }
@@ -32,7 +45,36 @@ func (h Handler) getPageCrawled(c *gin.Context) {
 		return
 	}
 
-	links, err := h.service.Craw(c.Request.Context(), crawPageInfo.URI, crawPageInfo.Depth)
+	ctx := c.Request.Context()
+	if crawPageInfo.Rules != "" {
+		rules, err := core.ParseExtractionRules([]byte(crawPageInfo.Rules))
+		if err != nil {
+			log.Error("error parsing extraction rules", logger.FieldError(err))
+			c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": err.Error()})
+
+			return
+		}
+		ctx = core.NewExtractionContext(ctx, rules)
+	}
+	if crawPageInfo.WARC {
+		writer, err := NewWARCWriter()
+		if err != nil {
+			log.Error("error creating warc writer", logger.FieldError(err))
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+
+			return
+		}
+		ctx = warc.NewContext(ctx, writer)
+	}
+
+	crawl, diff, err := h.crawl(ctx, crawPageInfo)
+	warcFiles := closeWARCWriter(warc.FromContext(ctx))
+	if errors.Is(err, pager.ErrUnknownAuthProfile) {
+		log.Error("error authenticating crawl", logger.FieldError(err))
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
 	if err != nil {
 		log.Error("error crawling page", logger.FieldError(err))
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
@@ -40,13 +82,303 @@ func (h Handler) getPageCrawled(c *gin.Context) {
 		return
 	}
 
-	if len(links) == 0 {
+	renderCrawl(c, crawl, diff, warcFiles)
+}
+
+// closeWARCWriter closes the writer the crawl was recorded by, returning the files written.
+func closeWARCWriter(writer *warc.Writer) []string {
+	if writer == nil {
+		return nil
+	}
+	if err := writer.Close(); err != nil {
+		log.Error("error closing warc writer", logger.FieldError(err))
+	}
+
+	return writer.Files()
+}
+
+func (h Handler) getHistory(c *gin.Context) {
+	var historyInfo historyInfo
+	if err := c.BindQuery(&historyInfo); err != nil {
+		log.Error("error binding query params", logger.FieldError(err))
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if err := historyInfo.validate(); err != nil {
+		log.Error("error validating parameters", logger.FieldError(err))
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+
+	snapshots, err := h.service.History(c.Request.Context(), historyInfo.URI, historyInfo.options())
+	if err != nil {
+		log.Error("error listing crawl history", logger.FieldError(err))
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if len(snapshots) == 0 {
+		c.HTML(http.StatusOK, "empty_result.html", gin.H{"message": "The URI was never crawled"})
+
+		return
+	}
+
+	c.HTML(http.StatusOK, "history.html", gin.H{"uri": historyInfo.URI, "snapshots": snapshots})
+}
+
+func (h Handler) getSnapshot(c *gin.Context) {
+	crawl, err := h.service.Snapshot(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, core.ErrSnapshotNotFound) {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+	if err != nil {
+		log.Error("error fetching crawl snapshot", logger.FieldError(err))
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+
+	renderCrawl(c, crawl, nil, nil)
+}
+
+func (h Handler) reprocessSnapshot(c *gin.Context) {
+	crawl, err := h.service.Reprocess(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, core.ErrSnapshotNotFound) {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+	if errors.Is(err, core.ErrNoBodyArchive) {
+		c.HTML(http.StatusConflict, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+	if err != nil {
+		log.Error("error reprocessing crawl snapshot", logger.FieldError(err))
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+
+	renderCrawl(c, crawl, nil, nil)
+}
+
+// getStructuredData returns as JSON the pages of the snapshot having structured data of the @type asked.
+func (h Handler) getStructuredData(c *gin.Context) {
+	itemType := c.Query("type")
+	if itemType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the type of the structured data is required"})
+
+		return
+	}
+
+	crawl, err := h.service.Snapshot(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, core.ErrSnapshotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+
+		return
+	}
+	if err != nil {
+		log.Error("error fetching crawl snapshot", logger.FieldError(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"type": itemType, "pages": newPageResponses(crawl.PagesWithType(itemType))})
+}
+
+// getContent returns as JSON the pages of the snapshot whose readable text contains the text asked,
+// or every page when no text is asked, for content audits.
+func (h Handler) getContent(c *gin.Context) {
+	crawl, err := h.service.Snapshot(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, core.ErrSnapshotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+
+		return
+	}
+	if err != nil {
+		log.Error("error fetching crawl snapshot", logger.FieldError(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	contains := c.Query("contains")
+	pages := crawl.Pages
+	if contains != "" {
+		pages = crawl.PagesContaining(contains)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contains": contains, "pages": newPageResponses(pages)})
+}
+
+// searchCrawl searches the latest snapshot of the crawls of the URI.
+func (h Handler) searchCrawl(c *gin.Context) {
+	var searchInfo searchInfo
+	if err := c.BindQuery(&searchInfo); err != nil {
+		log.Error("error binding query params", logger.FieldError(err))
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if err := searchInfo.validate(); err != nil {
+		log.Error("error validating parameters", logger.FieldError(err))
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+
+	snapshots, err := h.service.History(c.Request.Context(), searchInfo.URI, searchInfo.options())
+	if err != nil {
+		log.Error("error listing crawl history", logger.FieldError(err))
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if len(snapshots) == 0 {
+		c.HTML(http.StatusOK, "empty_result.html", gin.H{"message": "The URI was never crawled"})
+
+		return
+	}
+
+	h.search(c, snapshots[0].ID, searchInfo.Query)
+}
+
+func (h Handler) searchSnapshot(c *gin.Context) {
+	h.search(c, c.Param("id"), c.Query("q"))
+}
+
+// search shows the pages of the snapshot matching the query, or returns them as JSON when asked by the
+// Accept header. Without a query only the search box is shown.
+func (h Handler) search(c *gin.Context, id, query string) {
+	results := []core.SearchResult{}
+	var err error
+	if query != "" {
+		results, err = h.service.Search(c.Request.Context(), id, query)
+	}
+	if errors.Is(err, search.ErrEmptyQuery) {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+	if errors.Is(err, core.ErrSnapshotNotFound) {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+	if errors.Is(err, core.ErrNoSearchIndex) {
+		c.HTML(http.StatusConflict, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+	if err != nil {
+		log.Error("error searching crawl snapshot", logger.FieldError(err))
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(http.StatusOK, gin.H{"snapshot": id, "query": query, "results": results})
+
+		return
+	}
+
+	c.HTML(http.StatusOK, "search.html", gin.H{"snapshot": id, "query": query, "results": results})
+}
+
+// exportFields downloads the fields extracted from the pages of the snapshot as CSV or JSON Lines.
+func (h Handler) exportFields(c *gin.Context) {
+	format := c.DefaultQuery("format", core.JSONLExport)
+	contentType, found := exportContentTypes[format]
+	if !found {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": core.ErrUnknownExportFormat.Error()})
+
+		return
+	}
+
+	crawl, err := h.service.Snapshot(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, core.ErrSnapshotNotFound) {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+	if err != nil {
+		log.Error("error fetching crawl snapshot", logger.FieldError(err))
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="fields-%s.%s"`, c.Param("id"), format))
+	if err := core.ExportFields(c.Writer, format, crawl.Pages); err != nil {
+		log.Error("error exporting fields", logger.FieldError(err))
+	}
+}
+
+// renderCrawl shows the crawl, or returns it as JSON when asked by the Accept header.
+func renderCrawl(c *gin.Context, crawl core.Crawl, diff *core.CrawlDiff, warcFiles []string) {
+	now := time.Now().UTC()
+	certificates := crawl.ExpiringCertificates(now, viper.GetDuration("TLS_EXPIRY_WARNING"))
+	stale := crawl.Stale(now, viper.GetDuration("CRAWL_STALE_AFTER"))
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(http.StatusOK, newCrawlResponse(crawl, diff, stale, certificates, warcFiles))
+
+		return
+	}
+
+	if len(crawl.Links) == 0 {
 		c.HTML(http.StatusOK, "empty_result.html", gin.H{"message": "The process did not return any valid results"})
 
 		return
 	}
 
-	c.HTML(http.StatusOK, "links.html", gin.H{"links": links})
+	c.HTML(http.StatusOK, "links.html", gin.H{
+		"uri":          crawl.URI,
+		"depth":        crawl.Depth,
+		"profile":      crawl.Options.AuthProfile,
+		"links":        crawl.Links,
+		"pages":        crawl.Pages,
+		"redirects":    crawl.RedirectedPages(),
+		"certificates": certificates,
+		"diff":         diff,
+		"fromStorage":  crawl.FromStorage,
+		"crawledAt":    crawl.CrawledAt,
+		"stale":        stale,
+		"warcFiles":    warcFiles,
+	})
+}
+
+// crawl recrawls the URI and compares it with the stored crawl when the incremental mode is asked,
+// crawls it again when a refresh is asked, otherwise the stored crawl is returned when there is one.
+func (h Handler) crawl(ctx context.Context, crawPageInfo crawPageInfo) (core.Crawl, *core.CrawlDiff, error) {
+	if crawPageInfo.Refresh && !crawPageInfo.Incremental {
+		crawl, err := h.service.Refresh(ctx, crawPageInfo.URI, crawPageInfo.Depth, crawPageInfo.options())
+
+		return crawl, nil, err
+	}
+	if !crawPageInfo.Incremental {
+		crawl, err := h.service.Craw(ctx, crawPageInfo.URI, crawPageInfo.Depth, crawPageInfo.options())
+
+		return crawl, nil, err
+	}
+
+	crawl, diff, err := h.service.Recraw(ctx, crawPageInfo.URI, crawPageInfo.Depth, crawPageInfo.options())
+	if err != nil {
+		return crawl, nil, err
+	}
+
+	return crawl, &diff, nil
 }
 
 func (h Handler) index(c *gin.Context) {
@@ -1,15 +1,21 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gavv/httpexpect/v2"
 	"github.com/gin-gonic/gin"
 	core "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/search"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/warc"
 	"github.com/hiago-balbino/web-crawler/v2/test/mocks"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -48,10 +54,75 @@ func TestGetPageCrawled(t *testing.T) {
 		})
 	})
 
+	t.Run("should return 4xx error when authentication profile is unknown", func(t *testing.T) {
+		opts := core.Options{AuthProfile: "unknown"}
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Craw", mock.Anything, givenURI, givenDepth, opts).Return(core.Crawl{}, pager.ErrUnknownAuthProfile)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler").
+			WithQuery("uri", givenURI).
+			WithQuery("depth", givenDepth).
+			WithQuery("profile", opts.AuthProfile).
+			Expect().
+			Status(http.StatusBadRequest).
+			Body().Contains(pager.ErrUnknownAuthProfile.Error())
+	})
+
+	t.Run("should return 4xx error when extraction rules are invalid", func(t *testing.T) {
+		handler := setupHandler(nil)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler").
+			WithQuery("uri", givenURI).
+			WithQuery("depth", givenDepth).
+			WithQuery("rules", `{"rules": [{"name": "r", "fields": [{"name": "price"}]}]}`).
+			Expect().
+			Status(http.StatusBadRequest).
+			Body().Contains(core.ErrInvalidExtractionRule.Error())
+	})
+
+	t.Run("should crawl with the extraction rules given", func(t *testing.T) {
+		crawl := core.Crawl{URI: givenURI, Depth: givenDepth, Links: []string{"https://firstlink.com"}, Pages: []core.Page{
+			{URI: givenURI, StatusCode: http.StatusOK, Fields: map[string][]string{"price": {"9.90"}}},
+		}}
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Craw", mock.MatchedBy(func(ctx context.Context) bool {
+			rules := core.ExtractionRulesFromContext(ctx)
+
+			return len(rules) == 1 && rules[0].Name == "products"
+		}), givenURI, givenDepth, core.Options{}).Return(crawl, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler").
+			WithQuery("uri", givenURI).
+			WithQuery("depth", givenDepth).
+			WithQuery("rules", "rules:\n  - name: products\n    fields: [{name: price, css: .price}]").
+			WithHeader("Accept", "application/json").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("pages").Array().Element(0).Object().
+			Value("fields").Object().Value("price").Array().Element(0).String().Equal("9.90")
+	})
+
 	t.Run("should return 5xx error when fail to perform HTTP request to fetch page", func(t *testing.T) {
 		unexpectedErr := errors.New("unexpected error")
 		crawlerService := new(mocks.CrawlerUsecaseMock)
-		crawlerService.On("Craw", mock.Anything, givenURI, givenDepth).Return(make([]string, 0), unexpectedErr)
+		crawlerService.On("Craw", mock.Anything, givenURI, givenDepth, core.Options{}).Return(core.Crawl{}, unexpectedErr)
 
 		handler := setupHandler(crawlerService)
 		server := httptest.NewServer(handler)
@@ -69,9 +140,9 @@ func TestGetPageCrawled(t *testing.T) {
 
 	t.Run("should return 2xx", func(t *testing.T) {
 		t.Run("when process did not return any results", func(t *testing.T) {
-			links := make([]string, 0)
+			crawl := core.Crawl{URI: givenURI, Depth: givenDepth, Links: make([]string, 0)}
 			crawlerService := new(mocks.CrawlerUsecaseMock)
-			crawlerService.On("Craw", mock.Anything, givenURI, givenDepth).Return(links, nil)
+			crawlerService.On("Craw", mock.Anything, givenURI, givenDepth, core.Options{}).Return(crawl, nil)
 
 			handler := setupHandler(crawlerService)
 			server := httptest.NewServer(handler)
@@ -88,8 +159,9 @@ func TestGetPageCrawled(t *testing.T) {
 		})
 		t.Run("when page is successfully crawled", func(t *testing.T) {
 			links := []string{"https://firstlink.com", "https://secondlink.com", "https://thirdlink.com"}
+			crawl := core.Crawl{URI: givenURI, Depth: givenDepth, Links: links}
 			crawlerService := new(mocks.CrawlerUsecaseMock)
-			crawlerService.On("Craw", mock.Anything, givenURI, givenDepth).Return(links, nil)
+			crawlerService.On("Craw", mock.Anything, givenURI, givenDepth, core.Options{}).Return(crawl, nil)
 
 			handler := setupHandler(crawlerService)
 			server := httptest.NewServer(handler)
@@ -107,6 +179,765 @@ func TestGetPageCrawled(t *testing.T) {
 				Contains(links[1]).
 				Contains(links[2])
 		})
+		t.Run("when page has metadata", func(t *testing.T) {
+			crawl := core.Crawl{
+				URI:   givenURI,
+				Depth: givenDepth,
+				Links: []string{"https://firstlink.com"},
+				Pages: []core.Page{{URI: givenURI, Metadata: core.Metadata{
+					Title:       "Any URI test",
+					Description: "The page of the tests",
+					H1:          []string{"Welcome"},
+				}}},
+			}
+			crawlerService := new(mocks.CrawlerUsecaseMock)
+			crawlerService.On("Craw", mock.Anything, givenURI, givenDepth, core.Options{}).Return(crawl, nil)
+
+			handler := setupHandler(crawlerService)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			e := httpexpect.Default(t, server.URL)
+
+			e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				Expect().
+				Status(http.StatusOK).
+				Body().
+				Contains("Any URI test").
+				Contains("The page of the tests").
+				Contains("H1: Welcome")
+
+			page := e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				WithHeader("Accept", "application/json").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().
+				Value("pages").Array().Element(0).Object()
+			page.Value("uri").String().Equal(givenURI)
+			page.Value("metadata").Object().Value("title").String().Equal("Any URI test")
+			page.Value("metadata").Object().Value("h1").Array().Element(0).String().Equal("Welcome")
+		})
+		t.Run("when page is recorded to WARC", func(t *testing.T) {
+			viper.Set("WARC_DIR", t.TempDir())
+			defer viper.Set("WARC_DIR", nil)
+			crawl := core.Crawl{URI: givenURI, Depth: givenDepth, Links: []string{"https://firstlink.com"}}
+			recorded := mock.MatchedBy(func(ctx context.Context) bool { return warc.FromContext(ctx) != nil })
+			crawlerService := new(mocks.CrawlerUsecaseMock)
+			crawlerService.On("Craw", recorded, givenURI, givenDepth, core.Options{}).Return(crawl, nil).Run(func(args mock.Arguments) {
+				writer := warc.FromContext(args.Get(0).(context.Context))
+				_ = writer.Write(warc.Record{Type: warc.ResponseType, TargetURI: givenURI, Date: time.Now()})
+			})
+
+			handler := setupHandler(crawlerService)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			e := httpexpect.Default(t, server.URL)
+
+			e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				WithQuery("warc", true).
+				Expect().
+				Status(http.StatusOK).
+				Body().
+				Contains("Recorded to WARC").
+				Contains(".warc.gz")
+
+			e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				WithQuery("warc", true).
+				WithHeader("Accept", "application/json").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().
+				Value("warcFiles").Array().Element(0).String().Contains(".warc.gz")
+		})
+		t.Run("when page has certificate expiring soon", func(t *testing.T) {
+			crawl := core.Crawl{
+				URI:   givenURI,
+				Depth: givenDepth,
+				Links: []string{"https://firstlink.com"},
+				Certificates: []pager.Certificate{{
+					Host:     "anyuritest.com",
+					Issuer:   "CN=Internal CA",
+					NotAfter: time.Now().Add(-time.Hour),
+				}},
+			}
+			crawlerService := new(mocks.CrawlerUsecaseMock)
+			crawlerService.On("Craw", mock.Anything, givenURI, givenDepth, core.Options{}).Return(crawl, nil)
+
+			handler := setupHandler(crawlerService)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			e := httpexpect.Default(t, server.URL)
+
+			e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				Expect().
+				Status(http.StatusOK).
+				Body().
+				Contains("Certificates expiring soon").
+				Contains("CN=Internal CA")
+
+			certificate := e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				WithHeader("Accept", "application/json").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().
+				Value("expiringCertificates").Array().Element(0).Object()
+			certificate.Value("host").String().Equal("anyuritest.com")
+			certificate.Value("issuer").String().Equal("CN=Internal CA")
+		})
+		t.Run("when page is crawled through redirects", func(t *testing.T) {
+			crawl := core.Crawl{
+				URI:   givenURI,
+				Depth: givenDepth,
+				Links: []string{"https://firstlink.com"},
+				Pages: []core.Page{{
+					URI:          "https://firstlink.com",
+					Redirects:    []pager.Redirect{{URI: "https://firstlink.com", StatusCode: http.StatusMovedPermanently, Location: "https://firstlink.com"}},
+					RedirectLoop: true,
+				}},
+			}
+			crawlerService := new(mocks.CrawlerUsecaseMock)
+			crawlerService.On("Craw", mock.Anything, givenURI, givenDepth, core.Options{}).Return(crawl, nil)
+
+			handler := setupHandler(crawlerService)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			e := httpexpect.Default(t, server.URL)
+
+			e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				Expect().
+				Status(http.StatusOK).
+				Body().
+				Contains("Redirects").
+				Contains("loop")
+
+			page := e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				WithHeader("Accept", "application/json").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().
+				Value("pages").Array().Element(0).Object()
+			page.Value("redirectLoop").Boolean().True()
+			page.Value("redirects").Array().Element(0).Object().Value("statusCode").Number().Equal(http.StatusMovedPermanently)
+		})
+		t.Run("when page is served from storage after becoming stale", func(t *testing.T) {
+			viper.Set("CRAWL_STALE_AFTER", "24h")
+			defer viper.Set("CRAWL_STALE_AFTER", nil)
+
+			crawl := core.Crawl{
+				URI:         givenURI,
+				Depth:       givenDepth,
+				Links:       []string{"https://firstlink.com"},
+				FromStorage: true,
+				CrawledAt:   time.Now().Add(-48 * time.Hour),
+			}
+			crawlerService := new(mocks.CrawlerUsecaseMock)
+			crawlerService.On("Craw", mock.Anything, givenURI, givenDepth, core.Options{}).Return(crawl, nil)
+
+			handler := setupHandler(crawlerService)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			e := httpexpect.Default(t, server.URL)
+
+			e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				Expect().
+				Status(http.StatusOK).
+				Body().
+				Contains("Served from storage").
+				Contains("stale").
+				Contains("refresh=true")
+
+			result := e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				WithHeader("Accept", "application/json").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object()
+			result.Value("fromStorage").Boolean().True()
+			result.Value("stale").Boolean().True()
+		})
+		t.Run("when page is crawled again ignoring the stored result", func(t *testing.T) {
+			crawl := core.Crawl{URI: givenURI, Depth: givenDepth, Links: []string{"https://firstlink.com"}, CrawledAt: time.Now()}
+			crawlerService := new(mocks.CrawlerUsecaseMock)
+			crawlerService.On("Refresh", mock.Anything, givenURI, givenDepth, core.Options{}).Return(crawl, nil)
+
+			handler := setupHandler(crawlerService)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			e := httpexpect.Default(t, server.URL)
+
+			body := e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				WithQuery("refresh", true).
+				Expect().
+				Status(http.StatusOK).
+				Body()
+			body.Contains("https://firstlink.com")
+			body.NotContains("Served from storage")
+			crawlerService.AssertNotCalled(t, "Craw", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+		t.Run("when page is crawled again in incremental mode", func(t *testing.T) {
+			crawl := core.Crawl{URI: givenURI, Depth: givenDepth, Links: []string{"https://firstlink.com"}}
+			diff := core.CrawlDiff{
+				URI:          givenURI,
+				Depth:        givenDepth,
+				ChangedPages: []string{givenURI},
+				AddedLinks:   []string{"https://firstlink.com"},
+				RemovedLinks: []string{"https://removedlink.com"},
+			}
+			crawlerService := new(mocks.CrawlerUsecaseMock)
+			crawlerService.On("Recraw", mock.Anything, givenURI, givenDepth, core.Options{}).Return(crawl, diff, nil)
+
+			handler := setupHandler(crawlerService)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			e := httpexpect.Default(t, server.URL)
+
+			e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				WithQuery("incremental", true).
+				Expect().
+				Status(http.StatusOK).
+				Body().
+				Contains("Changes since last crawl").
+				Contains("Page changed: " + givenURI).
+				Contains("Link removed: https://removedlink.com")
+
+			diffResult := e.GET("/crawler").
+				WithQuery("uri", givenURI).
+				WithQuery("depth", givenDepth).
+				WithQuery("incremental", true).
+				WithHeader("Accept", "application/json").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().
+				Value("diff").Object()
+			diffResult.Value("changedPages").Array().Element(0).String().Equal(givenURI)
+			diffResult.Value("removedLinks").Array().Element(0).String().Equal("https://removedlink.com")
+			crawlerService.AssertNotCalled(t, "Craw", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	})
+}
+
+func TestGetHistory(t *testing.T) {
+	givenURI := "https://anyuritest.com"
+
+	t.Run("should return 4xx error when empty URI query param", func(t *testing.T) {
+		handler := setupHandler(nil)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/history").
+			Expect().
+			Status(http.StatusBadRequest).
+			Body().Contains(errEmptyURI.Error())
+	})
+	t.Run("should return 5xx error when history cannot be listed", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("History", mock.Anything, givenURI, core.Options{}).Return([]core.Snapshot{}, errors.New("unexpected error"))
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/history").
+			WithQuery("uri", givenURI).
+			Expect().
+			Status(http.StatusInternalServerError)
+	})
+	t.Run("should return 2xx with the snapshots of the URI", func(t *testing.T) {
+		snapshots := []core.Snapshot{
+			{ID: "65a0f0f0f0f0f0f0f0f0f0f2", URI: givenURI, Depth: 2, CrawledAt: time.Now(), Links: 12},
+			{ID: "65a0f0f0f0f0f0f0f0f0f0f1", URI: givenURI, Depth: 1, CrawledAt: time.Now().Add(-time.Hour), Links: 3},
+		}
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("History", mock.Anything, givenURI, core.Options{AuthProfile: "staging"}).Return(snapshots, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/history").
+			WithQuery("uri", givenURI).
+			WithQuery("profile", "staging").
+			Expect().
+			Status(http.StatusOK).
+			Body().
+			Contains("/crawler/snapshot/" + snapshots[0].ID).
+			Contains("/crawler/snapshot/" + snapshots[1].ID).
+			Contains("12 links")
+	})
+	t.Run("should return 2xx with message when URI was never crawled", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("History", mock.Anything, givenURI, core.Options{}).Return([]core.Snapshot{}, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/history").
+			WithQuery("uri", givenURI).
+			Expect().
+			Status(http.StatusOK).
+			Body().Contains("The URI was never crawled")
+	})
+}
+
+func TestGetSnapshot(t *testing.T) {
+	id := "65a0f0f0f0f0f0f0f0f0f0f1"
+
+	t.Run("should return 4xx error when snapshot does not exist", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Snapshot", mock.Anything, id).Return(core.Crawl{}, core.ErrSnapshotNotFound)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/" + id).
+			Expect().
+			Status(http.StatusNotFound).
+			Body().Contains(core.ErrSnapshotNotFound.Error())
+	})
+	t.Run("should return 2xx with the links of the snapshot", func(t *testing.T) {
+		crawl := core.Crawl{
+			URI:         "https://anyuritest.com",
+			Depth:       1,
+			Links:       []string{"https://firstlink.com"},
+			FromStorage: true,
+			CrawledAt:   time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+		}
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Snapshot", mock.Anything, id).Return(crawl, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/" + id).
+			Expect().
+			Status(http.StatusOK).
+			Body().
+			Contains("https://firstlink.com").
+			Contains("crawled at 2024-01-02 03:04:05 UTC")
+	})
+}
+
+func TestReprocessSnapshot(t *testing.T) {
+	id := "65a0f0f0f0f0f0f0f0f0f0f1"
+
+	for err, status := range map[error]int{
+		core.ErrSnapshotNotFound:   http.StatusNotFound,
+		core.ErrNoBodyArchive:      http.StatusConflict,
+		errors.New("archive down"): http.StatusInternalServerError,
+	} {
+		t.Run("should return error when "+err.Error(), func(t *testing.T) {
+			crawlerService := new(mocks.CrawlerUsecaseMock)
+			crawlerService.On("Reprocess", mock.Anything, id).Return(core.Crawl{}, err)
+
+			handler := setupHandler(crawlerService)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			e := httpexpect.Default(t, server.URL)
+
+			e.POST("/crawler/snapshot/" + id + "/reprocess").
+				Expect().
+				Status(status).
+				Body().Contains(err.Error())
+		})
+	}
+	t.Run("should return 2xx with the links extracted again", func(t *testing.T) {
+		crawl := core.Crawl{URI: "https://anyuritest.com", Depth: 1, Links: []string{"https://reprocessedlink.com"}}
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Reprocess", mock.Anything, id).Return(crawl, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.POST("/crawler/snapshot/" + id + "/reprocess").
+			Expect().
+			Status(http.StatusOK).
+			Body().Contains("https://reprocessedlink.com")
+	})
+}
+
+func TestGetStructuredData(t *testing.T) {
+	id := "65a0f0f0f0f0f0f0f0f0f0f1"
+
+	t.Run("should return 4xx error when the type is missing", func(t *testing.T) {
+		handler := setupHandler(new(mocks.CrawlerUsecaseMock))
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/" + id + "/structured-data").
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+	t.Run("should return 4xx error when snapshot does not exist", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Snapshot", mock.Anything, id).Return(core.Crawl{}, core.ErrSnapshotNotFound)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/"+id+"/structured-data").
+			WithQuery("type", "Product").
+			Expect().
+			Status(http.StatusNotFound).
+			JSON().Object().Value("error").String().Equal(core.ErrSnapshotNotFound.Error())
+	})
+	t.Run("should return 2xx with the pages having the type", func(t *testing.T) {
+		crawl := core.Crawl{URI: "https://anyuritest.com", Depth: 1, Pages: []core.Page{
+			{URI: "https://anyuritest.com", StatusCode: http.StatusOK},
+			{URI: "https://anyuritest.com/product", StatusCode: http.StatusOK, StructuredData: []core.StructuredData{{
+				Format: core.JSONLDFormat,
+				Types:  []string{"Product"},
+				Data:   []byte(`{"@type":"Product","name":"Crawler"}`),
+			}}},
+		}}
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Snapshot", mock.Anything, id).Return(crawl, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		pages := e.GET("/crawler/snapshot/"+id+"/structured-data").
+			WithQuery("type", "https://schema.org/Product").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("pages").Array()
+		pages.Length().Equal(1)
+		page := pages.Element(0).Object()
+		page.Value("uri").String().Equal("https://anyuritest.com/product")
+		item := page.Value("structuredData").Array().Element(0).Object()
+		item.Value("format").String().Equal(core.JSONLDFormat)
+		item.Value("data").Object().Equal(map[string]any{"@type": "Product", "name": "Crawler"})
+	})
+}
+
+func TestGetContent(t *testing.T) {
+	id := "65a0f0f0f0f0f0f0f0f0f0f1"
+	crawl := core.Crawl{URI: "https://anyuritest.com", Depth: 1, Pages: []core.Page{
+		{URI: "https://anyuritest.com", StatusCode: http.StatusOK, Content: core.Content{Text: "Welcome", WordCount: 1, ReadingMinutes: 1}},
+		{URI: "https://anyuritest.com/changelog", StatusCode: http.StatusOK, Content: core.Content{
+			Text: "The old API is deprecated", WordCount: 5, ReadingMinutes: 1,
+		}},
+	}}
+
+	t.Run("should return 4xx error when snapshot does not exist", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Snapshot", mock.Anything, id).Return(core.Crawl{}, core.ErrSnapshotNotFound)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/" + id + "/content").
+			Expect().
+			Status(http.StatusNotFound).
+			JSON().Object().Value("error").String().Equal(core.ErrSnapshotNotFound.Error())
+	})
+	t.Run("should return 2xx with the content of every page", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Snapshot", mock.Anything, id).Return(crawl, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/" + id + "/content").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("pages").Array().Length().Equal(2)
+	})
+	t.Run("should return 2xx with the pages containing the text", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Snapshot", mock.Anything, id).Return(crawl, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		pages := e.GET("/crawler/snapshot/"+id+"/content").
+			WithQuery("contains", "Deprecated").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("pages").Array()
+		pages.Length().Equal(1)
+		page := pages.Element(0).Object()
+		page.Value("uri").String().Equal("https://anyuritest.com/changelog")
+		page.Value("content").Object().Value("wordCount").Number().Equal(5)
+	})
+}
+
+func TestSearchSnapshot(t *testing.T) {
+	id := "65a0f0f0f0f0f0f0f0f0f0f1"
+	results := []core.SearchResult{
+		{URI: "https://anyuritest.com/changelog", Title: "Changelog", Score: 1.5, Snippet: "The old API is deprecated"},
+	}
+
+	t.Run("should return 2xx with the search box only when there is no query", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/" + id + "/search").
+			Expect().
+			Status(http.StatusOK).
+			Body().NotContains("No pages found")
+		crawlerService.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("should return 2xx with the pages matching the query", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Search", mock.Anything, id, "deprecated").Return(results, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/"+id+"/search").
+			WithQuery("q", "deprecated").
+			Expect().
+			Status(http.StatusOK).
+			Body().Contains("https://anyuritest.com/changelog").Contains("The old API is deprecated")
+	})
+	t.Run("should return 2xx with the pages matching the query as JSON", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Search", mock.Anything, id, `"old API"`).Return(results, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		body := e.GET("/crawler/snapshot/"+id+"/search").
+			WithQuery("q", `"old API"`).
+			WithHeader("Accept", "application/json").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		body.Value("snapshot").String().Equal(id)
+		body.Value("query").String().Equal(`"old API"`)
+		found := body.Value("results").Array()
+		found.Length().Equal(1)
+		found.Element(0).Object().Value("uri").String().Equal("https://anyuritest.com/changelog")
+		found.Element(0).Object().Value("score").Number().Equal(1.5)
+	})
+
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+	}{
+		"the query has no words":          {err: search.ErrEmptyQuery, status: http.StatusBadRequest},
+		"the snapshot does not exist":     {err: core.ErrSnapshotNotFound, status: http.StatusNotFound},
+		"there is no index directory":     {err: core.ErrNoSearchIndex, status: http.StatusConflict},
+		"the snapshot cannot be searched": {err: errors.New("unexpected error"), status: http.StatusInternalServerError},
+	} {
+		t.Run("should return error when "+name, func(t *testing.T) {
+			crawlerService := new(mocks.CrawlerUsecaseMock)
+			crawlerService.On("Search", mock.Anything, id, "deprecated").Return([]core.SearchResult{}, tc.err)
+
+			handler := setupHandler(crawlerService)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			e := httpexpect.Default(t, server.URL)
+
+			e.GET("/crawler/snapshot/"+id+"/search").
+				WithQuery("q", "deprecated").
+				Expect().
+				Status(tc.status).
+				Body().Contains(tc.err.Error())
+		})
+	}
+}
+
+func TestSearchCrawl(t *testing.T) {
+	givenURI := "https://anyuritest.com"
+
+	t.Run("should return 4xx error when empty URI query param", func(t *testing.T) {
+		handler := setupHandler(nil)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/search").
+			WithQuery("q", "deprecated").
+			Expect().
+			Status(http.StatusBadRequest).
+			Body().Contains(errEmptyURI.Error())
+	})
+	t.Run("should return 2xx with message when the URI was never crawled", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("History", mock.Anything, givenURI, core.Options{}).Return([]core.Snapshot{}, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/search").
+			WithQuery("uri", givenURI).
+			WithQuery("q", "deprecated").
+			Expect().
+			Status(http.StatusOK).
+			Body().Contains("The URI was never crawled")
+	})
+	t.Run("should return 2xx with the pages of the latest snapshot matching the query", func(t *testing.T) {
+		snapshots := []core.Snapshot{{ID: "2", URI: givenURI, Depth: 1}, {ID: "1", URI: givenURI, Depth: 1}}
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("History", mock.Anything, givenURI, core.Options{AuthProfile: "staging"}).Return(snapshots, nil)
+		crawlerService.On("Search", mock.Anything, "2", "deprecated").Return([]core.SearchResult{
+			{URI: "https://anyuritest.com/changelog", Score: 1},
+		}, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/search").
+			WithQuery("uri", givenURI).
+			WithQuery("profile", "staging").
+			WithQuery("q", "deprecated").
+			WithHeader("Accept", "application/json").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("snapshot").String().Equal("2")
+	})
+}
+
+func TestExportFields(t *testing.T) {
+	id := "65a0f0f0f0f0f0f0f0f0f0f1"
+	crawl := core.Crawl{URI: "https://anyuritest.com", Depth: 1, Pages: []core.Page{
+		{URI: "https://anyuritest.com", StatusCode: http.StatusOK},
+		{URI: "https://anyuritest.com/product", StatusCode: http.StatusOK, Fields: map[string][]string{"price": {"9.90"}}},
+	}}
+
+	t.Run("should return 4xx error when the format is unknown", func(t *testing.T) {
+		handler := setupHandler(new(mocks.CrawlerUsecaseMock))
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/"+id+"/fields").
+			WithQuery("format", "xml").
+			Expect().
+			Status(http.StatusBadRequest).
+			Body().Contains(core.ErrUnknownExportFormat.Error())
+	})
+	t.Run("should return 4xx error when snapshot does not exist", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Snapshot", mock.Anything, id).Return(core.Crawl{}, core.ErrSnapshotNotFound)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/" + id + "/fields").
+			Expect().
+			Status(http.StatusNotFound)
+	})
+	t.Run("should return 2xx with the fields as CSV", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Snapshot", mock.Anything, id).Return(crawl, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		response := e.GET("/crawler/snapshot/"+id+"/fields").
+			WithQuery("format", "csv").
+			Expect().
+			Status(http.StatusOK)
+		response.Header("Content-Type").Equal("text/csv; charset=utf-8")
+		response.Body().Equal("uri,price\nhttps://anyuritest.com/product,9.90\n")
+	})
+	t.Run("should return 2xx with the fields as JSON Lines by default", func(t *testing.T) {
+		crawlerService := new(mocks.CrawlerUsecaseMock)
+		crawlerService.On("Snapshot", mock.Anything, id).Return(crawl, nil)
+
+		handler := setupHandler(crawlerService)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+
+		e.GET("/crawler/snapshot/" + id + "/fields").
+			Expect().
+			Status(http.StatusOK).
+			Body().Equal(`{"uri":"https://anyuritest.com/product","fields":{"price":["9.90"]}}` + "\n")
 	})
 }
 
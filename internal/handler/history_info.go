@@ -0,0 +1,20 @@
+package handler
+
+import "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+
+type historyInfo struct {
+	URI         string `form:"uri"`
+	AuthProfile string `form:"profile"`
+}
+
+func (h historyInfo) validate() error {
+	if h.URI == "" {
+		return errEmptyURI
+	}
+
+	return nil
+}
+
+func (h historyInfo) options() crawler.Options {
+	return crawler.Options{AuthProfile: h.AuthProfile}
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/httpcache"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/warc"
+	"github.com/spf13/viper"
+)
+
+func newHTTPClient() *http.Client {
+	redirectPolicy := pager.NewRedirectPolicy(
+		viper.GetInt("PAGER_MAX_REDIRECTS"),
+		viper.GetBool("PAGER_ALLOW_CROSS_HOST_REDIRECTS"),
+	)
+
+	transport, err := pager.NewTLSTransport(newTransport(), newTLSSettings())
+	if err != nil {
+		log.Fatal("error configuring TLS", logger.FieldError(err))
+	}
+
+	// The recording is behind the cache, so only the responses received from the network are recorded.
+	recorder := warc.NewTransport(transport, warc.WithMaxBodySize(viper.GetInt64("PAGER_MAX_BODY_SIZE")))
+
+	return &http.Client{
+		Transport:     withHTTPCache(recorder),
+		Timeout:       viper.GetDuration("API_REQUEST_TIMEOUT"),
+		CheckRedirect: redirectPolicy.CheckRedirect,
+	}
+}
+
+// withHTTPCache puts the on disk HTTP cache in front of the transport when enabled, offline mode
+// implies the cache.
+func withHTTPCache(transport http.RoundTripper) http.RoundTripper {
+	offline := viper.GetBool("HTTP_CACHE_OFFLINE")
+	if !viper.GetBool("HTTP_CACHE_ENABLED") && !offline {
+		return transport
+	}
+
+	cache, err := httpcache.NewDiskCache(viper.GetString("HTTP_CACHE_DIR"))
+	if err != nil {
+		log.Fatal("error configuring HTTP cache", logger.FieldError(err))
+	}
+
+	return httpcache.NewTransport(
+		transport,
+		cache,
+		httpcache.WithOffline(offline),
+		httpcache.WithMaxEntrySize(viper.GetInt64("HTTP_CACHE_MAX_ENTRY_SIZE")),
+	)
+}
+
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{
+		Timeout:   viper.GetDuration("PAGER_DIAL_TIMEOUT"),
+		KeepAlive: viper.GetDuration("PAGER_DIAL_KEEP_ALIVE"),
+	}
+	transport.DialContext = dialer.DialContext
+
+	proxySelector := newProxySelector()
+	if proxySelector.HasProxies() {
+		transport.Proxy = proxySelector.Proxy
+	}
+
+	if !viper.GetBool("PAGER_SSRF_PROTECTION") {
+		return transport
+	}
+
+	allowlist, err := pager.ParseAllowlist(viper.GetString("PAGER_SSRF_ALLOWLIST"))
+	if err != nil {
+		log.Fatal("error parsing SSRF allowlist", logger.FieldError(err))
+	}
+	guard := pager.NewDestinationGuard(allowlist)
+
+	proxyAddresses, err := pinProxyAddresses(context.Background(), proxySelector.Hosts())
+	if err != nil {
+		log.Fatal("error resolving proxy addresses", logger.FieldError(err))
+	}
+
+	guardedDialer := *dialer
+	guardedDialer.Control = guard.Control
+	transport.DialContext = guardedDialContext(dialer, &guardedDialer, proxyAddresses)
+
+	// The proxies of the environment are neither pinned nor checked against the guard, so they are left out.
+	transport.Proxy = nil
+	if proxySelector.HasProxies() {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			proxy, err := proxySelector.Proxy(req)
+			if err != nil || proxy == nil {
+				return proxy, err
+			}
+
+			// The proxy resolves the destination, so it is checked before the request leaves.
+			if err := guard.CheckHost(req.Context(), req.URL.Hostname()); err != nil {
+				return nil, err
+			}
+
+			return proxy, nil
+		}
+	}
+
+	return transport
+}
+
+// guardedDialContext dials the configured proxies without the guard, given that they are
+// trusted and usually live in internal networks, and every other address with it. The proxies
+// are dialed at the addresses they were resolved to on startup, tried in order, so a DNS answer
+// changing later cannot turn a proxy hostname into a way around the guard.
+func guardedDialContext(
+	dialer, guardedDialer *net.Dialer,
+	proxyAddresses map[string][]string,
+) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		pinned, found := proxyAddresses[address]
+		if !found {
+			return guardedDialer.DialContext(ctx, network, address)
+		}
+
+		var err error
+		for _, pinnedAddress := range pinned {
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, network, pinnedAddress); err == nil {
+				return conn, nil
+			}
+		}
+
+		return nil, err
+	}
+}
+
+// pinProxyAddresses resolves the host of every proxy once, mapping its host and port to all the
+// addresses it can be dialed at.
+func pinProxyAddresses(ctx context.Context, proxyHosts []string) (map[string][]string, error) {
+	addresses := make(map[string][]string, len(proxyHosts))
+	for _, hostPort := range proxyHosts {
+		host, port, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, &net.DNSError{Err: "no addresses found", Name: host, IsNotFound: true}
+		}
+		for _, addr := range addrs {
+			addresses[hostPort] = append(addresses[hostPort], net.JoinHostPort(addr.Unmap().String(), port))
+		}
+	}
+
+	return addresses, nil
+}
+
+func newProxySelector() pager.ProxySelector {
+	username := viper.GetString("PAGER_PROXY_USERNAME")
+	password := viper.GetString("PAGER_PROXY_PASSWORD")
+
+	defaultPool, err := pager.ParseProxyURLs(viper.GetString("PAGER_PROXY_URLS"), username, password)
+	if err != nil {
+		log.Fatal("error parsing proxy URLs", logger.FieldError(err))
+	}
+
+	rules, err := pager.ParseProxyRules(viper.GetString("PAGER_PROXY_RULES"), username, password)
+	if err != nil {
+		log.Fatal("error parsing proxy rules", logger.FieldError(err))
+	}
+
+	return pager.NewProxySelector(rules, defaultPool)
+}
+
+func newTLSSettings() pager.TLSSettings {
+	minVersion, err := pager.ParseTLSVersion(viper.GetString("TLS_MIN_VERSION"))
+	if err != nil {
+		log.Fatal("error parsing TLS minimum version", logger.FieldError(err))
+	}
+
+	settings := pager.TLSSettings{CAFile: viper.GetString("TLS_CA_FILE"), MinVersion: minVersion}
+	if file := viper.GetString("TLS_HOSTS_FILE"); file != "" {
+		hosts, err := pager.LoadTLSHosts(file)
+		if err != nil {
+			log.Fatal("error loading TLS hosts settings", logger.FieldError(err))
+		}
+		settings.Hosts = hosts
+	}
+
+	return settings
+}
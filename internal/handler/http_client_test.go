@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/warc"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTransport_Proxy(t *testing.T) {
+	requested := make([]string, 0)
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.RequestURI)
+		_, _ = w.Write([]byte("proxied"))
+	}))
+	defer proxyServer.Close()
+	proxyURL, err := url.Parse(proxyServer.URL)
+	assert.NoError(t, err)
+	proxyHost := net.JoinHostPort("localhost", proxyURL.Port())
+
+	viper.Set("PAGER_SSRF_PROTECTION", true)
+	viper.Set("PAGER_SSRF_ALLOWLIST", "192.0.2.10")
+	viper.Set("PAGER_PROXY_URLS", "http://"+proxyHost)
+	defer func() {
+		viper.Set("PAGER_SSRF_PROTECTION", nil)
+		viper.Set("PAGER_SSRF_ALLOWLIST", nil)
+		viper.Set("PAGER_PROXY_URLS", nil)
+	}()
+	transport := newTransport()
+	client := &http.Client{Transport: transport}
+
+	t.Run("should reach the allowed destination through the proxy in the internal network", func(t *testing.T) {
+		response, err := client.Get("http://192.0.2.10/page")
+
+		assert.NoError(t, err)
+		_ = response.Body.Close()
+		assert.Equal(t, []string{"http://192.0.2.10/page"}, requested)
+	})
+	t.Run("should refuse the internal destination before it reaches the proxy", func(t *testing.T) {
+		_, err := client.Get("http://127.0.0.1/admin")
+
+		assert.ErrorIs(t, err, pager.ErrForbiddenDestination)
+		assert.Len(t, requested, 1)
+	})
+	t.Run("should refuse the address of the proxy when it is not dialed as the proxy", func(t *testing.T) {
+		_, err := transport.DialContext(context.Background(), "tcp", proxyURL.Host)
+
+		assert.ErrorIs(t, err, pager.ErrForbiddenDestination)
+	})
+}
+
+func TestNewHTTPClient_WARC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("cached"))
+	}))
+	defer server.Close()
+
+	viper.Set("HTTP_CACHE_ENABLED", true)
+	viper.Set("HTTP_CACHE_DIR", t.TempDir())
+	defer func() {
+		viper.Set("HTTP_CACHE_ENABLED", nil)
+		viper.Set("HTTP_CACHE_DIR", nil)
+	}()
+	client := newHTTPClient()
+	writer, err := warc.NewWriter(t.TempDir(), "test", 0)
+	assert.NoError(t, err)
+	ctx := warc.NewContext(context.Background(), writer)
+
+	t.Run("should only record the responses received from the network", func(t *testing.T) {
+		for range 2 {
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+			assert.NoError(t, err)
+			response, err := client.Do(request)
+			assert.NoError(t, err)
+			_, _ = io.Copy(io.Discard, response.Body)
+			_ = response.Body.Close()
+		}
+		assert.NoError(t, writer.Close())
+
+		assert.Len(t, writer.Files(), 1)
+		file, err := os.Open(writer.Files()[0])
+		assert.NoError(t, err)
+		defer file.Close()
+		reader, err := gzip.NewReader(file)
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(content), "WARC-Type: response\r\n"))
+	})
+}
+
+func TestNewTransport_EnvironmentProxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://proxy.internal:3128")
+
+	t.Run("should not send the requests through the proxy of the environment when guarded", func(t *testing.T) {
+		viper.Set("PAGER_SSRF_PROTECTION", true)
+		defer viper.Set("PAGER_SSRF_PROTECTION", nil)
+
+		assert.Nil(t, newTransport().Proxy)
+	})
+	t.Run("should keep the proxy of the environment when not guarded", func(t *testing.T) {
+		assert.NotNil(t, newTransport().Proxy)
+	})
+}
+
+func TestGuardedDialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	unreachable := closed.Addr().String()
+	_ = closed.Close()
+
+	dialer := &net.Dialer{}
+	guardedDialer := &net.Dialer{Control: pager.NewDestinationGuard(nil).Control}
+	dial := guardedDialContext(dialer, guardedDialer, map[string][]string{
+		"proxy:3128": {unreachable, listener.Addr().String()},
+	})
+
+	t.Run("should try the next address of the proxy when one fails", func(t *testing.T) {
+		conn, err := dial(context.Background(), "tcp", "proxy:3128")
+
+		assert.NoError(t, err)
+		_ = conn.Close()
+	})
+	t.Run("should return error when no address of the proxy can be dialed", func(t *testing.T) {
+		dialUnreachable := guardedDialContext(dialer, guardedDialer, map[string][]string{"proxy:3128": {unreachable}})
+
+		_, err := dialUnreachable(context.Background(), "tcp", "proxy:3128")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestPinProxyAddresses(t *testing.T) {
+	t.Run("should resolve the hosts of the proxies keeping their ports", func(t *testing.T) {
+		addresses, err := pinProxyAddresses(context.Background(), []string{"10.0.0.1:3128", "localhost:1080"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.1:3128"}, addresses["10.0.0.1:3128"])
+		assert.Contains(t, addresses["localhost:1080"], "127.0.0.1:1080")
+	})
+	t.Run("should return error when the host cannot be resolved", func(t *testing.T) {
+		_, err := pinProxyAddresses(context.Background(), []string{"proxy.invalid:3128"})
+
+		assert.Error(t, err)
+	})
+}
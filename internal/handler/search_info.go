@@ -0,0 +1,21 @@
+package handler
+
+import "github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+
+type searchInfo struct {
+	URI         string `form:"uri"`
+	AuthProfile string `form:"profile"`
+	Query       string `form:"q"`
+}
+
+func (s searchInfo) validate() error {
+	if s.URI == "" {
+		return errEmptyURI
+	}
+
+	return nil
+}
+
+func (s searchInfo) options() crawler.Options {
+	return crawler.Options{AuthProfile: s.AuthProfile}
+}
@@ -3,14 +3,13 @@ package handler
 import (
 	"context"
 	"fmt"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hiago-balbino/web-crawler/v2/config"
 	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
 	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
 	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
-	"github.com/hiago-balbino/web-crawler/v2/internal/repository/storage"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/warc"
 	"github.com/penglongli/gin-metrics/ginmetrics"
 	"github.com/spf13/viper"
 )
@@ -19,16 +18,71 @@ var log = logger.GetLogger()
 
 type Server struct {
 	handler Handler
+	storage StorageStatus
 }
 
 func NewServer() Server {
 	config.InitConfigurations()
-	pagerService := pager.NewPagerService(&http.Client{Timeout: viper.GetDuration("API_REQUEST_TIMEOUT")})
-	crawlerDatabase := storage.NewCrawlerMongodbRepository(context.Background())
-	crawlerService := crawler.NewCrawlerService(pagerService, crawlerDatabase)
-	handler := NewHandler(crawlerService)
+	service, storage := NewCrawlerService()
 
-	return Server{handler: handler}
+	return Server{handler: NewHandler(service), storage: storage}
+}
+
+// NewCrawlerService wires the crawler with the pager and the storage as configured, being shared
+// by the API and the command line.
+func NewCrawlerService() (crawler.CrawlerService, StorageStatus) {
+	bodyArchive := openBodyArchive(context.Background())
+	pagerService := pager.NewPagerService(
+		newHTTPClient(),
+		pager.WithMaxBodySize(viper.GetInt64("PAGER_MAX_BODY_SIZE")),
+		pager.WithDecompression(viper.GetBool("PAGER_DECOMPRESSION")),
+		pager.WithCharsetDetection(viper.GetBool("PAGER_CHARSET_DETECTION")),
+		pager.WithAuthProfiles(loadAuthProfiles()),
+		pager.WithBodyArchive(bodyArchive),
+	)
+	crawlerDatabase, storage := openStorage(context.Background())
+
+	return crawler.NewCrawlerService(
+		pagerService,
+		crawlerDatabase,
+		crawler.WithBodyArchive(bodyArchive),
+		crawler.WithConcurrency(viper.GetInt("CRAWL_CONCURRENCY")),
+		crawler.WithExtractionRules(loadExtractionRules()),
+		crawler.WithSearchIndex(viper.GetString("SEARCH_INDEX_DIR"), viper.GetString("SEARCH_DEFAULT_LANGUAGE")),
+	), storage
+}
+
+// NewWARCWriter creates the writer a crawl is recorded by when asked, as configured.
+func NewWARCWriter() (*warc.Writer, error) {
+	return warc.NewWriter(viper.GetString("WARC_DIR"), viper.GetString("WARC_PREFIX"), viper.GetInt64("WARC_MAX_FILE_SIZE"))
+}
+
+func loadAuthProfiles() map[string]pager.AuthProfile {
+	file := viper.GetString("AUTH_PROFILES_FILE")
+	if file == "" {
+		return map[string]pager.AuthProfile{}
+	}
+
+	profiles, err := pager.LoadAuthProfiles(file)
+	if err != nil {
+		log.Fatal("error loading authentication profiles", logger.FieldError(err))
+	}
+
+	return profiles
+}
+
+func loadExtractionRules() []crawler.ExtractionRule {
+	file := viper.GetString("EXTRACTION_RULES_FILE")
+	if file == "" {
+		return nil
+	}
+
+	rules, err := crawler.LoadExtractionRules(file)
+	if err != nil {
+		log.Fatal("error loading extraction rules", logger.FieldError(err))
+	}
+
+	return rules
 }
 
 func (s Server) Start() {
@@ -47,8 +101,17 @@ func (s Server) setupRoutes(templatePath string) *gin.Engine {
 	router := gin.Default()
 	router.LoadHTMLGlob(templatePath)
 
+	router.GET("/health", s.health)
 	router.GET("/index", s.handler.index)
 	router.GET("/crawler", s.handler.getPageCrawled)
+	router.GET("/crawler/history", s.handler.getHistory)
+	router.GET("/crawler/snapshot/:id", s.handler.getSnapshot)
+	router.POST("/crawler/snapshot/:id/reprocess", s.handler.reprocessSnapshot)
+	router.GET("/crawler/snapshot/:id/structured-data", s.handler.getStructuredData)
+	router.GET("/crawler/snapshot/:id/fields", s.handler.exportFields)
+	router.GET("/crawler/snapshot/:id/content", s.handler.getContent)
+	router.GET("/crawler/snapshot/:id/search", s.handler.searchSnapshot)
+	router.GET("/crawler/search", s.handler.searchCrawl)
 
 	return router
 }
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
+	"github.com/hiago-balbino/web-crawler/v2/internal/repository/storage"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	mongodbBackend  = "mongodb"
+	sqliteBackend   = "sqlite"
+	postgresBackend = "postgres"
+	memoryBackend   = "memory"
+	noneBackend     = "none"
+
+	failFallback   = "fail"
+	memoryFallback = "memory"
+	noneFallback   = "none"
+
+	localArchive  = "local"
+	gridfsArchive = "gridfs"
+
+	persistentMode = "persistent"
+	ephemeralMode  = "ephemeral"
+	degradedMode   = "degraded"
+	disabledMode   = "disabled"
+)
+
+var (
+	errUnknownStorageBackend  = errors.New("unknown storage backend")
+	errUnknownStorageFallback = errors.New("unknown storage fallback")
+	errUnknownBodyArchive     = errors.New("unknown body archive")
+)
+
+// StorageStatus tells which storage the crawler is using and whether the crawls are being kept.
+type StorageStatus struct {
+	Backend string `json:"backend"`
+	Mode    string `json:"mode"`
+	Error   string `json:"error,omitempty"`
+	pinger  pinger
+}
+
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Persistent tells whether the crawls are kept by the configured backend.
+func (s StorageStatus) Persistent() bool {
+	return s.Mode == persistentMode
+}
+
+// health reports whether the API is up and the storage in use, pinging it when it is kept by a database,
+// so a storage that cannot be reached makes the API unavailable while a fallback only degrades it. A
+// storage chosen not to keep the crawls is reported as ok with its mode.
+func (s Server) health(c *gin.Context) {
+	status := http.StatusOK
+	state := "ok"
+	storage := s.storage
+	if storage.Mode == degradedMode {
+		state = degradedMode
+	}
+	if storage.pinger != nil {
+		if err := storage.pinger.Ping(c.Request.Context()); err != nil {
+			log.Error("error pinging crawler storage", logger.FieldError(err))
+			status, state, storage.Error = http.StatusServiceUnavailable, "unavailable", err.Error()
+		}
+	}
+
+	c.JSON(status, gin.H{"status": state, "storage": storage})
+}
+
+// openStorage opens the configured backend and, when it cannot be reached, fails or falls back to a storage
+// that keeps nothing beyond the process, as configured by STORAGE_FALLBACK.
+//
+//nolint:ireturn // the backend is chosen by configuration
+func openStorage(ctx context.Context) (crawler.CrawlerDatabase, StorageStatus) {
+	backend := viper.GetString("STORAGE_BACKEND")
+	fallback := viper.GetString("STORAGE_FALLBACK")
+	if fallback != failFallback && fallback != memoryFallback && fallback != noneFallback {
+		log.Fatal("error opening crawler storage", logger.FieldError(errUnknownStorageFallback))
+	}
+
+	if timeout := viper.GetDuration("STORAGE_CONNECT_TIMEOUT"); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	database, err := newCrawlerDatabase(ctx, backend)
+	if err == nil {
+		mode := persistentMode
+		switch backend {
+		case memoryBackend:
+			mode = ephemeralMode
+		case noneBackend:
+			mode = disabledMode
+		}
+		status := StorageStatus{Backend: backend, Mode: mode}
+		status.pinger, _ = database.(pinger)
+
+		return database, status
+	}
+	if errors.Is(err, errUnknownStorageBackend) || fallback == failFallback {
+		log.Fatal("error opening crawler storage", logger.FieldError(err))
+	}
+
+	log.Warn("crawler storage unavailable, crawls will not be kept",
+		zap.String("backend", backend), zap.String("fallback", fallback), logger.FieldError(err))
+	status := StorageStatus{Backend: fallback, Mode: degradedMode, Error: err.Error()}
+	if fallback == memoryFallback {
+		return storage.NewCrawlerMemoryRepository(), status
+	}
+
+	return storage.NewCrawlerNoopRepository(), status
+}
+
+// newCrawlerDatabase opens the storage backend chosen by configuration.
+//
+//nolint:ireturn // the backend is chosen by configuration
+func newCrawlerDatabase(ctx context.Context, backend string) (crawler.CrawlerDatabase, error) {
+	switch backend {
+	case mongodbBackend:
+		return storage.NewCrawlerMongodbRepository(ctx)
+	case sqliteBackend:
+		return storage.NewCrawlerSQLiteRepository(ctx, viper.GetString("SQLITE_PATH"))
+	case postgresBackend:
+		return storage.NewCrawlerPostgresRepository(ctx, viper.GetString("POSTGRES_DSN"))
+	case memoryBackend:
+		return storage.NewCrawlerMemoryRepository(), nil
+	case noneBackend:
+		return storage.NewCrawlerNoopRepository(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownStorageBackend, backend)
+	}
+}
+
+// openBodyArchive opens the archive the bodies of the pages fetched are kept in, returning nil when
+// archiving is disabled.
+//
+//nolint:ireturn // the archive is chosen by configuration
+func openBodyArchive(ctx context.Context) pager.BodyArchive {
+	if timeout := viper.GetDuration("STORAGE_CONNECT_TIMEOUT"); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var archive pager.BodyArchive
+	var err error
+	compression := viper.GetString("BODY_ARCHIVE_COMPRESSION")
+	switch kind := viper.GetString("BODY_ARCHIVE"); kind {
+	case "":
+		return nil
+	case localArchive:
+		archive, err = storage.NewLocalBodyArchive(viper.GetString("BODY_ARCHIVE_DIR"), compression)
+	case gridfsArchive:
+		archive, err = storage.NewMongodbBodyArchive(ctx, compression)
+	default:
+		err = fmt.Errorf("%w: %s", errUnknownBodyArchive, kind)
+	}
+	if err != nil {
+		log.Fatal("error opening body archive", logger.FieldError(err))
+	}
+
+	return archive
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/hiago-balbino/web-crawler/v2/internal/repository/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	t.Run("should return 2xx with the persistent storage reachable", func(t *testing.T) {
+		repository, err := storage.NewCrawlerSQLiteRepository(context.Background(), filepath.Join(t.TempDir(), "crawler.db"))
+		assert.NoError(t, err)
+		defer repository.Close()
+
+		server := httptest.NewServer(setupServer(StorageStatus{Backend: sqliteBackend, Mode: persistentMode, pinger: repository}))
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+		body := e.GET("/health").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		body.Value("status").String().Equal("ok")
+		body.Value("storage").Object().Value("backend").String().Equal(sqliteBackend)
+		body.Value("storage").Object().Value("mode").String().Equal(persistentMode)
+	})
+
+	t.Run("should return 2xx reporting the degraded storage", func(t *testing.T) {
+		status := StorageStatus{Backend: memoryBackend, Mode: degradedMode, Error: "server selection timeout"}
+		server := httptest.NewServer(setupServer(status))
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+		body := e.GET("/health").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		body.Value("status").String().Equal(degradedMode)
+		body.Value("storage").Object().Value("error").String().Equal("server selection timeout")
+	})
+
+	t.Run("should return 2xx reporting the storage chosen not to keep the crawls", func(t *testing.T) {
+		for backend, mode := range map[string]string{memoryBackend: ephemeralMode, noneBackend: disabledMode} {
+			server := httptest.NewServer(setupServer(StorageStatus{Backend: backend, Mode: mode}))
+
+			e := httpexpect.Default(t, server.URL)
+			body := e.GET("/health").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object()
+			body.Value("status").String().Equal("ok")
+			body.Value("storage").Object().Value("mode").String().Equal(mode)
+			server.Close()
+		}
+	})
+
+	t.Run("should return 5xx when the persistent storage cannot be reached", func(t *testing.T) {
+		repository, err := storage.NewCrawlerSQLiteRepository(context.Background(), filepath.Join(t.TempDir(), "crawler.db"))
+		assert.NoError(t, err)
+		assert.NoError(t, repository.Close())
+
+		server := httptest.NewServer(setupServer(StorageStatus{Backend: sqliteBackend, Mode: persistentMode, pinger: repository}))
+		defer server.Close()
+
+		e := httpexpect.Default(t, server.URL)
+		body := e.GET("/health").
+			Expect().
+			Status(http.StatusServiceUnavailable).
+			JSON().Object()
+		body.Value("status").String().Equal("unavailable")
+		body.Value("storage").Object().Value("error").String().NotEmpty()
+	})
+}
+
+func TestOpenStorage(t *testing.T) {
+	defer func() {
+		viper.Set("STORAGE_BACKEND", nil)
+		viper.Set("STORAGE_FALLBACK", nil)
+		viper.Set("SQLITE_PATH", nil)
+	}()
+
+	t.Run("should use the configured backend when it can be opened", func(t *testing.T) {
+		viper.Set("STORAGE_BACKEND", sqliteBackend)
+		viper.Set("STORAGE_FALLBACK", failFallback)
+		viper.Set("SQLITE_PATH", filepath.Join(t.TempDir(), "crawler.db"))
+
+		database, status := openStorage(context.Background())
+
+		assert.IsType(t, storage.CrawlerSQLRepository{}, database)
+		assert.Equal(t, sqliteBackend, status.Backend)
+		assert.True(t, status.Persistent())
+		assert.NoError(t, database.(storage.CrawlerSQLRepository).Close())
+	})
+
+	t.Run("should not report the crawls kept in memory as persistent", func(t *testing.T) {
+		viper.Set("STORAGE_BACKEND", memoryBackend)
+		viper.Set("STORAGE_FALLBACK", failFallback)
+
+		database, status := openStorage(context.Background())
+
+		assert.IsType(t, storage.CrawlerMemoryRepository{}, database)
+		assert.Equal(t, memoryBackend, status.Backend)
+		assert.Equal(t, ephemeralMode, status.Mode)
+		assert.False(t, status.Persistent())
+	})
+
+	t.Run("should keep nothing when the storage is disabled", func(t *testing.T) {
+		viper.Set("STORAGE_BACKEND", noneBackend)
+		viper.Set("STORAGE_FALLBACK", failFallback)
+
+		database, status := openStorage(context.Background())
+
+		assert.IsType(t, storage.CrawlerNoopRepository{}, database)
+		assert.Equal(t, disabledMode, status.Mode)
+		assert.False(t, status.Persistent())
+	})
+
+	for fallback, expected := range map[string]any{
+		memoryFallback: storage.CrawlerMemoryRepository{},
+		noneFallback:   storage.CrawlerNoopRepository{},
+	} {
+		t.Run("should fall back to "+fallback+" storage when the backend cannot be opened", func(t *testing.T) {
+			viper.Set("STORAGE_BACKEND", sqliteBackend)
+			viper.Set("STORAGE_FALLBACK", fallback)
+			viper.Set("SQLITE_PATH", filepath.Join(t.TempDir(), "missing", "crawler.db"))
+
+			database, status := openStorage(context.Background())
+
+			assert.IsType(t, expected, database)
+			assert.Equal(t, fallback, status.Backend)
+			assert.Equal(t, degradedMode, status.Mode)
+			assert.False(t, status.Persistent())
+			assert.NotEmpty(t, status.Error)
+		})
+	}
+}
+
+func setupServer(status StorageStatus) *gin.Engine {
+	server := Server{handler: NewHandler(nil), storage: status}
+
+	return server.setupRoutes("../../web/templates/*")
+}
@@ -0,0 +1,134 @@
+package httpcache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const heuristicFraction = 10
+
+// cacheControl holds the directives of a Cache-Control header, where directives without
+// argument are mapped to an empty value.
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	directives := cacheControl{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+
+			name, argument, _ := strings.Cut(directive, "=")
+			directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(argument), `"`)
+		}
+	}
+
+	return directives
+}
+
+func (c cacheControl) has(directive string) bool {
+	_, found := c[directive]
+
+	return found
+}
+
+func (c cacheControl) seconds(directive string) (time.Duration, bool) {
+	value, found := c[directive]
+	if !found {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// cacheableByDefault are the status codes that can be stored using heuristic freshness (RFC 7231 6.1).
+var cacheableByDefault = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// storable reports whether the response to the request can be stored by a private cache (RFC 7234 3). A 304
+// is never stored, as it only updates the stored response it validates.
+func storable(req *http.Request, response *http.Response) bool {
+	if req.Method != http.MethodGet || response.StatusCode == http.StatusNotModified {
+		return false
+	}
+
+	requestDirectives := parseCacheControl(req.Header)
+	responseDirectives := parseCacheControl(response.Header)
+	if requestDirectives.has("no-store") || responseDirectives.has("no-store") {
+		return false
+	}
+
+	if response.Header.Get("Vary") == "*" {
+		return false
+	}
+
+	if cacheableByDefault[response.StatusCode] {
+		return true
+	}
+
+	_, hasMaxAge := responseDirectives.seconds("max-age")
+
+	return hasMaxAge || response.Header.Get("Expires") != "" || responseDirectives.has("public")
+}
+
+// freshnessLifetime follows RFC 7234 4.2.1, ignoring s-maxage given that this is a private cache.
+func freshnessLifetime(header http.Header, statusCode int) time.Duration {
+	directives := parseCacheControl(header)
+	if maxAge, found := directives.seconds("max-age"); found {
+		return maxAge
+	}
+
+	date, dateErr := http.ParseTime(header.Get("Date"))
+	if expiresHeader := header.Get("Expires"); expiresHeader != "" {
+		expires, err := http.ParseTime(expiresHeader)
+		if err != nil || dateErr != nil {
+			return 0
+		}
+
+		return expires.Sub(date)
+	}
+
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil || dateErr != nil || !cacheableByDefault[statusCode] {
+		return 0
+	}
+
+	return date.Sub(lastModified) / heuristicFraction
+}
+
+// currentAge follows RFC 7234 4.2.3.
+func currentAge(header http.Header, requestTime, responseTime, now time.Time) time.Duration {
+	apparentAge := time.Duration(0)
+	if date, err := http.ParseTime(header.Get("Date")); err == nil && responseTime.After(date) {
+		apparentAge = responseTime.Sub(date)
+	}
+
+	ageValue := time.Duration(0)
+	if seconds, err := strconv.ParseInt(header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+
+	correctedAgeValue := ageValue + responseTime.Sub(requestTime)
+	correctedInitialAge := max(apparentAge, correctedAgeValue)
+
+	return correctedInitialAge + now.Sub(responseTime)
+}
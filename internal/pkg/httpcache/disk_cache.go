@@ -0,0 +1,163 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const cacheFileMode = 0o600
+
+// entry is a stored response together with the information needed to compute its age and to select it
+// among the variants of the same resource.
+type entry struct {
+	RequestTime  time.Time         `json:"request_time"`
+	ResponseTime time.Time         `json:"response_time"`
+	Vary         map[string]string `json:"vary,omitempty"`
+	Response     []byte            `json:"response"`
+}
+
+// matches reports whether the entry was stored for a request with the same values on the headers
+// nominated by the Vary response header (RFC 7234 4.1).
+func (e entry) matches(req *http.Request) bool {
+	for name, value := range e.Vary {
+		if req.Header.Get(name) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DiskCache keeps the variants of every cached resource in a JSON file named after the cache key.
+type DiskCache struct {
+	dir string
+	mu  *sync.Mutex
+}
+
+// NewDiskCache creates the cache directory when missing.
+func NewDiskCache(dir string) (DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return DiskCache{}, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	return DiskCache{dir: dir, mu: &sync.Mutex{}}, nil
+}
+
+func (c DiskCache) load(key string) ([]entry, error) {
+	content, err := os.ReadFile(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// store replaces the variant matching the same Vary values, keeping the other ones.
+func (c DiskCache) store(key string, stored entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.load(key)
+	if err != nil {
+		entries = nil
+	}
+
+	variants := []entry{stored}
+	for _, existing := range entries {
+		if !sameVary(existing.Vary, stored.Vary) {
+			variants = append(variants, existing)
+		}
+	}
+
+	return c.write(key, variants)
+}
+
+func (c DiskCache) remove(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Remove(c.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// write goes through a temporary file so a concurrent reader never sees a partial entry.
+func (c DiskCache) write(key string, entries []entry) error {
+	content, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), cacheFileMode); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), c.path(key))
+}
+
+func (c DiskCache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+func sameVary(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if other, found := b[name]; !found || other != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cacheKey identifies a resource by method and URL. The credentials sent by the client are part of the
+// key so that responses fetched under an authentication profile are never served to other crawls.
+func cacheKey(req *http.Request) string {
+	hash := sha256.New()
+	for _, part := range []string{
+		req.Method,
+		req.URL.String(),
+		req.Header.Get("Authorization"),
+		req.Header.Get("Cookie"),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
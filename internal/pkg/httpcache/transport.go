@@ -0,0 +1,376 @@
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
+)
+
+// CacheHeader is added to the responses served from the cache, with HIT when the stored response was
+// fresh and REVALIDATED when the origin server confirmed it with a 304.
+const CacheHeader = "X-Cache"
+
+var log = logger.GetLogger()
+
+// ErrCacheMiss is returned in offline mode when the requested resource has not been cached.
+var ErrCacheMiss = errors.New("resource not found in the http cache")
+
+// Transport is a private HTTP cache following RFC 7234 that stores the responses on disk.
+type Transport struct {
+	base         http.RoundTripper
+	cache        DiskCache
+	offline      bool
+	maxEntrySize int64
+	now          func() time.Time
+}
+
+// Option configures the cache Transport.
+type Option func(*Transport)
+
+// WithOffline makes the transport replay cached responses, regardless of their freshness,
+// without ever reaching the network.
+func WithOffline(enabled bool) Option {
+	return func(t *Transport) {
+		t.offline = enabled
+	}
+}
+
+// WithMaxEntrySize limits the size in bytes of the bodies that are stored, zero means unlimited.
+func WithMaxEntrySize(size int64) Option {
+	return func(t *Transport) {
+		t.maxEntrySize = size
+	}
+}
+
+// NewTransport creates the cache layer in front of the base round tripper.
+func NewTransport(base http.RoundTripper, cache DiskCache, opts ...Option) Transport {
+	transport := Transport{base: base, cache: cache, now: time.Now}
+	for _, opt := range opts {
+		opt(&transport)
+	}
+
+	return transport
+}
+
+// RoundTrip serves GET requests from the cache when possible and stores the responses allowed to be cached.
+// Requests carrying their own validators bypass the stored responses since the caller handles the 304, and
+// their responses are never stored, a 304 only refreshing the headers of the stored response.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.invalidate(req)
+	}
+
+	key := cacheKey(req)
+	stored, cached := t.lookup(req, key)
+
+	if t.offline {
+		if !cached {
+			return nil, ErrCacheMiss
+		}
+
+		return t.serve(req, stored, "HIT")
+	}
+
+	if isConditional(req) {
+		return t.forward(req, key, stored, cached)
+	}
+
+	var response *http.Response
+	if cached {
+		var err error
+		if response, err = stored.response(req); err != nil {
+			cached = false
+		} else {
+			response.Body.Close()
+		}
+	}
+
+	if cached && t.fresh(req, stored, response) {
+		return t.serve(req, stored, "HIT")
+	}
+
+	if parseCacheControl(req.Header).has("only-if-cached") {
+		return gatewayTimeout(req), nil
+	}
+
+	if cached {
+		return t.revalidate(req, key, stored, response)
+	}
+
+	return t.fetch(req, key)
+}
+
+func (t Transport) lookup(req *http.Request, key string) (entry, bool) {
+	entries, err := t.cache.load(key)
+	if err != nil {
+		log.Error("error reading http cache entry", logger.FieldError(err))
+
+		return entry{}, false
+	}
+
+	for _, stored := range entries {
+		if stored.matches(req) {
+			return stored, true
+		}
+	}
+
+	return entry{}, false
+}
+
+// fresh applies the freshness model of RFC 7234 4.2 together with the request and response directives
+// that force a validation.
+func (t Transport) fresh(req *http.Request, stored entry, response *http.Response) bool {
+	requestDirectives := parseCacheControl(req.Header)
+	responseDirectives := parseCacheControl(response.Header)
+	if requestDirectives.has("no-cache") || responseDirectives.has("no-cache") {
+		return false
+	}
+	if strings.Contains(strings.ToLower(req.Header.Get("Pragma")), "no-cache") && !requestDirectives.has("max-age") {
+		return false
+	}
+
+	lifetime := freshnessLifetime(response.Header, response.StatusCode)
+	age := currentAge(response.Header, stored.RequestTime, stored.ResponseTime, t.now())
+
+	if maxAge, found := requestDirectives.seconds("max-age"); found && age > maxAge {
+		return false
+	}
+	if minFresh, found := requestDirectives.seconds("min-fresh"); found {
+		age += minFresh
+	}
+
+	return lifetime > age
+}
+
+// revalidate sends the stored validators to the origin server, serving the stored response when it
+// answers 304 and replacing it otherwise.
+func (t Transport) revalidate(req *http.Request, key string, stored entry, response *http.Response) (*http.Response, error) {
+	etag, lastModified := response.Header.Get("ETag"), response.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return t.fetch(req, key)
+	}
+
+	conditional := req.Clone(req.Context())
+	if etag != "" {
+		conditional.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		conditional.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	requestTime := t.now()
+	validation, err := t.base.RoundTrip(conditional)
+	if err != nil {
+		return nil, err
+	}
+
+	if validation.StatusCode != http.StatusNotModified {
+		return t.store(req, key, validation, requestTime)
+	}
+	validation.Body.Close()
+
+	updated, err := t.update(stored, response, validation, requestTime)
+	if err != nil {
+		log.Error("error updating http cache entry", logger.FieldError(err))
+
+		return t.serve(req, stored, "REVALIDATED")
+	}
+	if err := t.cache.store(key, updated); err != nil {
+		log.Error("error writing http cache entry", logger.FieldError(err))
+	}
+
+	return t.serve(req, updated, "REVALIDATED")
+}
+
+// update merges the header fields of the 304 into the stored response (RFC 7234 4.3.4).
+func (t Transport) update(stored entry, response, validation *http.Response, requestTime time.Time) (entry, error) {
+	cached, err := stored.response(response.Request)
+	if err != nil {
+		return entry{}, err
+	}
+
+	for name, values := range validation.Header {
+		if name == "Content-Length" {
+			continue
+		}
+		cached.Header[name] = values
+	}
+
+	dump, err := httputil.DumpResponse(cached, true)
+	if err != nil {
+		return entry{}, err
+	}
+
+	return entry{RequestTime: requestTime, ResponseTime: t.now(), Vary: stored.Vary, Response: dump}, nil
+}
+
+// forward sends the conditional request of the caller to the origin server, merging the header fields of
+// a 304 into the stored response, if any, without storing the response itself.
+func (t Transport) forward(req *http.Request, key string, stored entry, cached bool) (*http.Response, error) {
+	requestTime := t.now()
+	response, err := t.base.RoundTrip(req)
+	if err != nil || response.StatusCode != http.StatusNotModified || !cached {
+		return response, err
+	}
+
+	previous, err := stored.response(req)
+	if err != nil {
+		log.Error("error reading http cache entry", logger.FieldError(err))
+
+		return response, nil
+	}
+	previous.Body.Close()
+
+	updated, err := t.update(stored, previous, response, requestTime)
+	if err != nil {
+		log.Error("error updating http cache entry", logger.FieldError(err))
+
+		return response, nil
+	}
+	if err := t.cache.store(key, updated); err != nil {
+		log.Error("error writing http cache entry", logger.FieldError(err))
+	}
+
+	return response, nil
+}
+
+func (t Transport) fetch(req *http.Request, key string) (*http.Response, error) {
+	requestTime := t.now()
+	response, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.store(req, key, response, requestTime)
+}
+
+// store keeps a copy of the response when it is allowed and its body fits the entry size limit,
+// the caller always receives the full body.
+func (t Transport) store(req *http.Request, key string, response *http.Response, requestTime time.Time) (*http.Response, error) {
+	if !storable(req, response) {
+		return response, nil
+	}
+
+	body := response.Body
+	reader := io.Reader(body)
+	if t.maxEntrySize > 0 {
+		reader = io.LimitReader(body, t.maxEntrySize+1)
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		body.Close()
+
+		return nil, err
+	}
+
+	if t.maxEntrySize > 0 && int64(len(content)) > t.maxEntrySize {
+		response.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(content), body), body}
+
+		return response, nil
+	}
+	body.Close()
+
+	response.Body = io.NopCloser(bytes.NewReader(content))
+	dump, err := httputil.DumpResponse(response, true)
+	if err != nil {
+		log.Error("error encoding http cache entry", logger.FieldError(err))
+
+		return response, nil
+	}
+
+	stored := entry{RequestTime: requestTime, ResponseTime: t.now(), Vary: varyValues(req, response), Response: dump}
+	if err := t.cache.store(key, stored); err != nil {
+		log.Error("error writing http cache entry", logger.FieldError(err))
+	}
+
+	return response, nil
+}
+
+// invalidate forwards the unsafe methods and drops the stored responses for the target URI (RFC 7234 4.4).
+func (t Transport) invalidate(req *http.Request) (*http.Response, error) {
+	if t.offline {
+		return nil, ErrCacheMiss
+	}
+
+	response, err := t.base.RoundTrip(req)
+	if err != nil || req.Method == http.MethodHead || req.Method == http.MethodOptions {
+		return response, err
+	}
+
+	if response.StatusCode < http.StatusBadRequest {
+		get := req.Clone(req.Context())
+		get.Method = http.MethodGet
+		if err := t.cache.remove(cacheKey(get)); err != nil {
+			log.Error("error removing http cache entry", logger.FieldError(err))
+		}
+	}
+
+	return response, nil
+}
+
+func (t Transport) serve(req *http.Request, stored entry, status string) (*http.Response, error) {
+	response, err := stored.response(req)
+	if err != nil {
+		return nil, err
+	}
+
+	age := currentAge(response.Header, stored.RequestTime, stored.ResponseTime, t.now())
+	response.Header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	response.Header.Set(CacheHeader, status)
+
+	return response, nil
+}
+
+func (e entry) response(req *http.Request) (*http.Response, error) {
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(e.Response)), req)
+}
+
+func varyValues(req *http.Request, response *http.Response) map[string]string {
+	values := map[string]string{}
+	for _, header := range response.Header.Values("Vary") {
+		for _, name := range strings.Split(header, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name != "" {
+				values[name] = req.Header.Get(name)
+			}
+		}
+	}
+
+	return values
+}
+
+func isConditional(req *http.Request) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if req.Header.Get(name) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// gatewayTimeout is the answer to only-if-cached requests that cannot be satisfied (RFC 7234 5.2.1.7).
+func gatewayTimeout(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     "504 Gateway Timeout",
+		StatusCode: http.StatusGatewayTimeout,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	}
+}
@@ -0,0 +1,283 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	current time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.current
+}
+
+func newTestTransport(t *testing.T, clock *clock, opts ...Option) (Transport, DiskCache) {
+	t.Helper()
+
+	cache, err := NewDiskCache(t.TempDir())
+	require.NoError(t, err)
+
+	transport := NewTransport(http.DefaultTransport, cache, opts...)
+	transport.now = clock.now
+
+	return transport, cache
+}
+
+func get(t *testing.T, client *http.Client, uri string, headers ...string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, uri, http.NoBody)
+	require.NoError(t, err)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	response, err := client.Do(req)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return response, string(body)
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/max-age":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		case "/expires":
+			w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+			w.Header().Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+			_, _ = w.Write([]byte(r.Header.Get("Accept-Language")))
+
+			return
+		case "/etag":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.Header().Set("X-Revalidated", "true")
+				w.WriteHeader(http.StatusNotModified)
+
+				return
+			}
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	testCases := map[string]func(t *testing.T, client *http.Client, clock *clock){
+		"should serve fresh response from cache": func(t *testing.T, client *http.Client, clock *clock) {
+			get(t, client, server.URL+"/max-age")
+			clock.current = clock.current.Add(30 * time.Second)
+			response, body := get(t, client, server.URL+"/max-age")
+
+			assert.Equal(t, int32(1), hits.Load())
+			assert.Equal(t, "HIT", response.Header.Get(CacheHeader))
+			assert.Equal(t, "30", response.Header.Get("Age"))
+			assert.Equal(t, "content", body)
+		},
+		"should fetch again when max-age is exceeded": func(t *testing.T, client *http.Client, clock *clock) {
+			get(t, client, server.URL+"/max-age")
+			clock.current = clock.current.Add(2 * time.Minute)
+			response, _ := get(t, client, server.URL+"/max-age")
+
+			assert.Equal(t, int32(2), hits.Load())
+			assert.Empty(t, response.Header.Get(CacheHeader))
+		},
+		"should not store response with no-store": func(t *testing.T, client *http.Client, _ *clock) {
+			get(t, client, server.URL+"/no-store")
+			get(t, client, server.URL+"/no-store")
+
+			assert.Equal(t, int32(2), hits.Load())
+		},
+		"should honour Expires header": func(t *testing.T, client *http.Client, _ *clock) {
+			get(t, client, server.URL+"/expires")
+			response, _ := get(t, client, server.URL+"/expires")
+
+			assert.Equal(t, int32(1), hits.Load())
+			assert.Equal(t, "HIT", response.Header.Get(CacheHeader))
+		},
+		"should keep one variant per Vary header value": func(t *testing.T, client *http.Client, _ *clock) {
+			get(t, client, server.URL+"/vary", "Accept-Language", "en")
+			_, pt := get(t, client, server.URL+"/vary", "Accept-Language", "pt")
+			response, en := get(t, client, server.URL+"/vary", "Accept-Language", "en")
+
+			assert.Equal(t, int32(2), hits.Load())
+			assert.Equal(t, "pt", pt)
+			assert.Equal(t, "en", en)
+			assert.Equal(t, "HIT", response.Header.Get(CacheHeader))
+		},
+		"should revalidate stale response and merge 304 headers": func(t *testing.T, client *http.Client, clock *clock) {
+			get(t, client, server.URL+"/etag")
+			clock.current = clock.current.Add(2 * time.Minute)
+			response, body := get(t, client, server.URL+"/etag")
+
+			assert.Equal(t, int32(2), hits.Load())
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, "REVALIDATED", response.Header.Get(CacheHeader))
+			assert.Equal(t, "true", response.Header.Get("X-Revalidated"))
+			assert.Equal(t, "content", body)
+		},
+		"should revalidate when request has no-cache": func(t *testing.T, client *http.Client, _ *clock) {
+			get(t, client, server.URL+"/etag")
+			response, _ := get(t, client, server.URL+"/etag", "Cache-Control", "no-cache")
+
+			assert.Equal(t, int32(2), hits.Load())
+			assert.Equal(t, "REVALIDATED", response.Header.Get(CacheHeader))
+		},
+		"should bypass cache when request has its own validators": func(t *testing.T, client *http.Client, _ *clock) {
+			get(t, client, server.URL+"/etag")
+			response, _ := get(t, client, server.URL+"/etag", "If-None-Match", `"v1"`)
+
+			assert.Equal(t, int32(2), hits.Load())
+			assert.Equal(t, http.StatusNotModified, response.StatusCode)
+		},
+		"should keep the stored response when a request with its own validators gets a 304": func(t *testing.T, client *http.Client, _ *clock) {
+			get(t, client, server.URL+"/etag")
+			get(t, client, server.URL+"/etag", "If-None-Match", `"v1"`)
+			response, body := get(t, client, server.URL+"/etag")
+
+			assert.Equal(t, int32(2), hits.Load())
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, "HIT", response.Header.Get(CacheHeader))
+			assert.Equal(t, "true", response.Header.Get("X-Revalidated"))
+			assert.Equal(t, "content", body)
+		},
+		"should not store the 304 to a request with its own validators": func(t *testing.T, client *http.Client, _ *clock) {
+			get(t, client, server.URL+"/etag", "If-None-Match", `"v1"`)
+			response, body := get(t, client, server.URL+"/etag")
+
+			assert.Equal(t, int32(2), hits.Load())
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Empty(t, response.Header.Get(CacheHeader))
+			assert.Equal(t, "content", body)
+		},
+		"should answer only-if-cached miss with gateway timeout": func(t *testing.T, client *http.Client, _ *clock) {
+			response, _ := get(t, client, server.URL+"/max-age", "Cache-Control", "only-if-cached")
+
+			assert.Equal(t, int32(0), hits.Load())
+			assert.Equal(t, http.StatusGatewayTimeout, response.StatusCode)
+		},
+	}
+
+	for name, run := range testCases {
+		t.Run(name, func(t *testing.T) {
+			hits.Store(0)
+			clock := &clock{current: time.Now()}
+			transport, _ := newTestTransport(t, clock)
+
+			run(t, &http.Client{Transport: transport}, clock)
+		})
+	}
+}
+
+func TestTransport_Offline(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=1")
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	clock := &clock{current: time.Now()}
+	online, cache := newTestTransport(t, clock)
+	get(t, &http.Client{Transport: online}, server.URL+"/cached")
+
+	offline := NewTransport(http.DefaultTransport, cache, WithOffline(true))
+	offline.now = clock.now
+	client := &http.Client{Transport: offline}
+
+	t.Run("should replay stale response without reaching the network", func(t *testing.T) {
+		clock.current = clock.current.Add(time.Hour)
+		response, body := get(t, client, server.URL+"/cached")
+
+		assert.Equal(t, int32(1), hits.Load())
+		assert.Equal(t, "HIT", response.Header.Get(CacheHeader))
+		assert.Equal(t, "content", body)
+	})
+	t.Run("should return cache miss error when resource was never fetched", func(t *testing.T) {
+		_, err := client.Get(server.URL + "/missing")
+
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.Equal(t, int32(1), hits.Load())
+	})
+}
+
+func TestTransport_MaxEntrySize(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("content larger than the limit"))
+	}))
+	defer server.Close()
+
+	transport, _ := newTestTransport(t, &clock{current: time.Now()}, WithMaxEntrySize(8))
+	client := &http.Client{Transport: transport}
+
+	_, body := get(t, client, server.URL)
+	get(t, client, server.URL)
+
+	assert.Equal(t, "content larger than the limit", body)
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+func TestFreshnessLifetime(t *testing.T) {
+	date := time.Date(2024, time.January, 10, 0, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		header   http.Header
+		expected time.Duration
+	}{
+		"should prefer max-age over Expires": {
+			header: http.Header{
+				"Cache-Control": {"public, max-age=120"},
+				"Date":          {date.Format(http.TimeFormat)},
+				"Expires":       {date.Add(time.Hour).Format(http.TimeFormat)},
+			},
+			expected: 2 * time.Minute,
+		},
+		"should use Expires minus Date": {
+			header: http.Header{
+				"Date":    {date.Format(http.TimeFormat)},
+				"Expires": {date.Add(time.Hour).Format(http.TimeFormat)},
+			},
+			expected: time.Hour,
+		},
+		"should treat invalid Expires as already expired": {
+			header:   http.Header{"Date": {date.Format(http.TimeFormat)}, "Expires": {"0"}},
+			expected: 0,
+		},
+		"should use a tenth of the time since Last-Modified as heuristic": {
+			header: http.Header{
+				"Date":          {date.Format(http.TimeFormat)},
+				"Last-Modified": {date.Add(-10 * time.Hour).Format(http.TimeFormat)},
+			},
+			expected: time.Hour,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, freshnessLifetime(tc.header, http.StatusOK))
+		})
+	}
+}
@@ -17,6 +17,22 @@ var (
 		Name: "crawler_links_error_count_total",
 		Help: "Count of links returned in error",
 	})
+	RedirectsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crawler_redirects_count_total",
+		Help: "Count of redirect hops followed",
+	})
+	RedirectLoopsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crawler_redirect_loops_count_total",
+		Help: "Count of redirect loops detected",
+	})
+	NotModifiedPagesCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crawler_not_modified_pages_count_total",
+		Help: "Count of pages not modified since the previous crawl",
+	})
+	DeduplicatedCrawlsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crawler_deduplicated_crawls_count_total",
+		Help: "Count of crawls answered by an identical crawl already in progress",
+	})
 	DeltaTimeToProcessLinks = prometheus.NewHistogram(prometheus.HistogramOpts{
 		Name:    "crawler_delta_time_to_process_links",
 		Help:    "Delta time to process links",
@@ -27,5 +43,9 @@ var (
 func init() {
 	prometheus.MustRegister(LinksCounter)
 	prometheus.MustRegister(LinksErrorCounter)
+	prometheus.MustRegister(RedirectsCounter)
+	prometheus.MustRegister(RedirectLoopsCounter)
+	prometheus.MustRegister(NotModifiedPagesCounter)
+	prometheus.MustRegister(DeduplicatedCrawlsCounter)
 	prometheus.MustRegister(DeltaTimeToProcessLinks)
 }
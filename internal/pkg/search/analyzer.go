@@ -0,0 +1,199 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Languages the terms are stemmed for, any other language being indexed without stemming.
+const (
+	English    = "en"
+	Portuguese = "pt"
+	Spanish    = "es"
+)
+
+// token is a term of a text along with where it was found, so snippets can be cut from the text.
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// stemmer reduces a lowercase word to its stem.
+type stemmer func(string) string
+
+var stemmers = map[string]stemmer{
+	English:    stemEnglish,
+	Portuguese: stemPortuguese,
+	Spanish:    stemSpanish,
+}
+
+// Language returns the language a text tagged with the BCP 47 tag is indexed in, like pt for pt-BR.
+func Language(tag string) string {
+	language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	language, _, _ = strings.Cut(language, "_")
+
+	return language
+}
+
+// analyze splits the text in words, being a word a run of letters and digits, lowercased and stemmed
+// in the language.
+func analyze(language, text string) []token {
+	stem := stemmers[language]
+	tokens := make([]token, 0)
+	start := -1
+	add := func(end int) {
+		term := strings.ToLower(text[start:end])
+		if stem != nil {
+			term = stem(term)
+		}
+		tokens = append(tokens, token{term: term, start: start, end: end})
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			if start < 0 {
+				start = i
+			}
+
+			continue
+		}
+		if start >= 0 {
+			add(i)
+		}
+	}
+	if start >= 0 {
+		add(len(text))
+	}
+
+	return tokens
+}
+
+// terms returns the terms of the text, in order.
+func terms(language, text string) []string {
+	tokens := analyze(language, text)
+	terms := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		terms = append(terms, token.term)
+	}
+
+	return terms
+}
+
+// stemEnglish is a light English stemmer, removing the plural and the most common verbal and adverbial
+// suffixes, so that "crawling", "crawled" and "crawls" share the stem "crawl".
+func stemEnglish(word string) string {
+	if utf8.RuneCountInString(word) <= 3 {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") &&
+		!strings.HasSuffix(word, "is"):
+		word = strings.TrimSuffix(word, "s")
+	}
+
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed"} {
+		stem, found := strings.CutSuffix(word, suffix)
+		if !found || len(stem) < 3 || !hasVowel(stem) {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "bl"), strings.HasSuffix(stem, "iz"):
+			stem += "e"
+		case doubleConsonant(stem):
+			stem = stem[:len(stem)-1]
+		}
+
+		return stem
+	}
+
+	for _, suffix := range []string{"fulness", "ousness", "iveness", "ness", "ly"} {
+		if stem, found := strings.CutSuffix(word, suffix); found && len(stem) >= 4 {
+			return stem
+		}
+	}
+
+	return word
+}
+
+func hasVowel(word string) bool {
+	return strings.ContainsAny(word, "aeiouy")
+}
+
+func doubleConsonant(word string) bool {
+	if len(word) < 2 || word[len(word)-1] != word[len(word)-2] {
+		return false
+	}
+
+	return !strings.ContainsRune("aeioulsz", rune(word[len(word)-1]))
+}
+
+// stemPortuguese is a light Portuguese stemmer, removing the plural and the feminine and folding the
+// accents, so that "páginas" and "página" share the stem "pagin".
+func stemPortuguese(word string) string {
+	if utf8.RuneCountInString(word) < 4 {
+		return foldAccents(word)
+	}
+
+	for _, rule := range []struct{ suffix, replacement string }{
+		{"ões", "ão"}, {"ães", "ão"}, {"ais", "al"}, {"éis", "el"}, {"eis", "el"}, {"óis", "ol"},
+		{"ns", "m"}, {"res", "r"}, {"les", "l"}, {"zes", "z"}, {"s", ""},
+	} {
+		if stem, found := strings.CutSuffix(word, rule.suffix); found {
+			word = stem + rule.replacement
+
+			break
+		}
+	}
+
+	word = foldAccents(word)
+	for _, suffix := range []string{"a", "o", "e"} {
+		if stem, found := strings.CutSuffix(word, suffix); found && len(stem) >= 3 {
+			return stem
+		}
+	}
+
+	return word
+}
+
+// stemSpanish is a light Spanish stemmer, removing the plural and the gender and folding the accents,
+// so that "páginas" and "página" share the stem "pagin".
+func stemSpanish(word string) string {
+	word = foldAccents(word)
+	if len(word) < 5 {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "eses"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ces"):
+		return strings.TrimSuffix(word, "ces") + "z"
+	case strings.HasSuffix(word, "os"), strings.HasSuffix(word, "as"), strings.HasSuffix(word, "es"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "o"), strings.HasSuffix(word, "a"), strings.HasSuffix(word, "e"):
+		return word[:len(word)-1]
+	}
+
+	return word
+}
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+func foldAccents(word string) string {
+	return accents.Replace(word)
+}
@@ -0,0 +1,314 @@
+package search
+
+import (
+	"encoding/gob"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// BM25 parameters, as commonly used.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// snippetWords is how many words around the first match are taken to the snippet.
+const snippetWords = 30
+
+// ErrEmptyQuery is returned when the query has no words to search for.
+var ErrEmptyQuery = errors.New("the query has no words to search for")
+
+// Document is a page added to the index, kept along with its text so snippets can be shown.
+type Document struct {
+	URI   string
+	Title string
+	Text  string
+}
+
+// Hit is a document matching a query, with its BM25 score and a snippet of the text around the first match.
+type Hit struct {
+	Document
+	Score   float64
+	Snippet string
+}
+
+// Index is an inverted index of the documents of a crawl, where the terms of the documents, stemmed in the
+// language of the index, point to the positions they are found at in each document.
+type Index struct {
+	language    string
+	documents   []Document
+	lengths     []int
+	totalLength int
+	postings    map[string][]posting
+}
+
+type posting struct {
+	Document  int
+	Positions []int
+}
+
+// indexFile is how the index is persisted, its fields being exported to be encoded.
+type indexFile struct {
+	Language    string
+	Documents   []Document
+	Lengths     []int
+	TotalLength int
+	Postings    map[string][]posting
+}
+
+// NewIndex creates an empty index of the language, being the terms of the languages without a stemmer
+// indexed as they are.
+func NewIndex(language string) *Index {
+	return &Index{language: language, postings: make(map[string][]posting)}
+}
+
+// Add indexes the title and the text of the document, the title being taken as its first paragraph.
+func (i *Index) Add(document Document) {
+	id := len(i.documents)
+	positions := make(map[string][]int)
+	length := 0
+	for _, text := range []string{document.Title, document.Text} {
+		for _, term := range terms(i.language, text) {
+			positions[term] = append(positions[term], length)
+			length++
+		}
+		// leaves a gap so phrases do not match across the title and the text
+		length++
+	}
+
+	for term, found := range positions {
+		i.postings[term] = append(i.postings[term], posting{Document: id, Positions: found})
+	}
+	i.documents = append(i.documents, document)
+	i.lengths = append(i.lengths, length)
+	i.totalLength += length
+}
+
+// Len returns how many documents were indexed.
+func (i *Index) Len() int {
+	return len(i.documents)
+}
+
+// Search returns the documents matching the query, best first. The words of the query are searched for
+// in any document, ranked by BM25, while the phrases in double quotes must be found in the document.
+func (i *Index) Search(query string) ([]Hit, error) {
+	words, phrases := parseQuery(i.language, query)
+	if len(words) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	candidates := make(map[int]bool)
+	for _, term := range words {
+		for _, posting := range i.postings[term] {
+			candidates[posting.Document] = true
+		}
+	}
+	for _, phrase := range phrases {
+		for document := range candidates {
+			if !i.hasPhrase(document, phrase) {
+				delete(candidates, document)
+			}
+		}
+	}
+
+	scores := i.score(words, candidates)
+	hits := make([]Hit, 0, len(candidates))
+	for document := range candidates {
+		hits = append(hits, Hit{
+			Document: i.documents[document],
+			Score:    scores[document],
+			Snippet:  i.snippet(document, words),
+		})
+	}
+	sort.Slice(hits, func(x, y int) bool {
+		if hits[x].Score != hits[y].Score {
+			return hits[x].Score > hits[y].Score
+		}
+
+		return hits[x].URI < hits[y].URI
+	})
+
+	return hits, nil
+}
+
+// score ranks the candidates by BM25 over the distinct words of the query.
+func (i *Index) score(words []string, candidates map[int]bool) map[int]float64 {
+	scores := make(map[int]float64, len(candidates))
+	total := float64(len(i.documents))
+	averageLength := float64(i.totalLength) / math.Max(total, 1)
+	seen := make(map[string]bool)
+	for _, term := range words {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := i.postings[term]
+		frequency := float64(len(postings))
+		idf := math.Log(1 + (total-frequency+0.5)/(frequency+0.5))
+		for _, posting := range postings {
+			if !candidates[posting.Document] {
+				continue
+			}
+			tf := float64(len(posting.Positions))
+			length := float64(i.lengths[posting.Document])
+			scores[posting.Document] += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*length/averageLength))
+		}
+	}
+
+	return scores
+}
+
+// hasPhrase reports whether the terms of the phrase are found one after the other in the document.
+func (i *Index) hasPhrase(document int, phrase []string) bool {
+	positions := make([]map[int]bool, len(phrase))
+	for n, term := range phrase {
+		positions[n] = make(map[int]bool)
+		for _, posting := range i.postings[term] {
+			if posting.Document != document {
+				continue
+			}
+			for _, position := range posting.Positions {
+				positions[n][position] = true
+			}
+		}
+	}
+
+	for start := range positions[0] {
+		found := true
+		for n := 1; n < len(phrase) && found; n++ {
+			found = positions[n][start+n]
+		}
+		if found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// snippet cuts the text of the document around the first word of the query found in it.
+func (i *Index) snippet(document int, words []string) string {
+	text := i.documents[document].Text
+	tokens := analyze(i.language, text)
+	wanted := make(map[string]bool, len(words))
+	for _, word := range words {
+		wanted[word] = true
+	}
+
+	for n, token := range tokens {
+		if !wanted[token.term] {
+			continue
+		}
+		first := max(n-snippetWords/3, 0)
+		last := min(first+snippetWords, len(tokens)) - 1
+		snippet := strings.Join(strings.Fields(text[tokens[first].start:tokens[last].end]), " ")
+		if first > 0 {
+			snippet = "…" + snippet
+		}
+		if last < len(tokens)-1 {
+			snippet += "…"
+		}
+
+		return snippet
+	}
+
+	return ""
+}
+
+// parseQuery splits the query in its words and its phrases, the words of the phrases being words of
+// the query as well.
+func parseQuery(language, query string) ([]string, [][]string) {
+	words := make([]string, 0)
+	phrases := make([][]string, 0)
+	for n, part := range strings.Split(query, `"`) {
+		found := terms(language, part)
+		words = append(words, found...)
+		if n%2 == 1 && len(found) > 1 {
+			phrases = append(phrases, found)
+		}
+	}
+
+	return words, phrases
+}
+
+// Save writes the index to the file, compressed, replacing it at once so a partial index is never read.
+func (i *Index) Save(file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o750); err != nil {
+		return err
+	}
+	temporary, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(temporary.Name())
+	}()
+
+	if err := i.encode(temporary); err != nil {
+		_ = temporary.Close()
+
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary.Name(), file)
+}
+
+func (i *Index) encode(file *os.File) error {
+	encoder, err := zstd.NewWriter(file)
+	if err != nil {
+		return err
+	}
+
+	err = gob.NewEncoder(encoder).Encode(indexFile{
+		Language:    i.language,
+		Documents:   i.documents,
+		Lengths:     i.lengths,
+		TotalLength: i.totalLength,
+		Postings:    i.postings,
+	})
+	if err != nil {
+		_ = encoder.Close()
+
+		return err
+	}
+
+	return encoder.Close()
+}
+
+// Load reads the index saved to the file.
+func Load(file string) (*Index, error) {
+	opened, err := os.Open(file) //nolint:gosec // the file is named by the index directory configured
+	if err != nil {
+		return nil, err
+	}
+	defer opened.Close()
+
+	decoder, err := zstd.NewReader(opened)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+
+	var saved indexFile
+	if err := gob.NewDecoder(decoder).Decode(&saved); err != nil {
+		return nil, err
+	}
+
+	return &Index{
+		language:    saved.Language,
+		documents:   saved.Documents,
+		lengths:     saved.Lengths,
+		totalLength: saved.TotalLength,
+		postings:    saved.Postings,
+	}, nil
+}
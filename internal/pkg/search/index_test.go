@@ -0,0 +1,132 @@
+package search
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uris(hits []Hit) []string {
+	found := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		found = append(found, hit.URI)
+	}
+
+	return found
+}
+
+func newTestIndex() *Index {
+	index := NewIndex(English)
+	index.Add(Document{
+		URI:   "https://anyurl.com/changelog",
+		Title: "Changelog",
+		Text:  "The old API was deprecated in this release. Clients calling the deprecated API should migrate.",
+	})
+	index.Add(Document{
+		URI:   "https://anyurl.com/api",
+		Title: "API reference",
+		Text:  "Every endpoint of the API is listed here. Nothing is deprecated.",
+	})
+	index.Add(Document{
+		URI:   "https://anyurl.com/about",
+		Title: "About",
+		Text:  "We crawl pages for a living.",
+	})
+
+	return index
+}
+
+func TestAnalyze(t *testing.T) {
+	for language, cases := range map[string]map[string]string{
+		English: {
+			"Crawling": "crawl", "crawled": "crawl", "crawls": "crawl", "deprecated": "deprecate",
+			"deprecates": "deprecate", "stopped": "stop", "queries": "query", "classes": "class", "API": "api",
+		},
+		Portuguese: {"Páginas": "pagin", "página": "pagin", "informações": "informaca", "papéis": "papel"},
+		Spanish:    {"Páginas": "pagin", "página": "pagin", "luces": "luz", "meses": "mes"},
+		"de":       {"Seiten": "seiten"},
+	} {
+		for word, stem := range cases {
+			assert.Equal(t, []string{stem}, terms(language, word), "%s in %s", word, language)
+		}
+	}
+
+	assert.Equal(t, []string{"don", "t", "stop", "2024"}, terms(English, "Don't stop, 2024!"))
+}
+
+func TestLanguage(t *testing.T) {
+	assert.Equal(t, Portuguese, Language("pt-BR"))
+	assert.Equal(t, English, Language(" EN_us "))
+	assert.Equal(t, Spanish, Language("es"))
+	assert.Empty(t, Language(""))
+}
+
+func TestIndex_Search(t *testing.T) {
+	index := newTestIndex()
+
+	t.Run("should rank the documents mentioning the words more often first", func(t *testing.T) {
+		hits, err := index.Search("deprecating")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"https://anyurl.com/changelog", "https://anyurl.com/api"}, uris(hits))
+		assert.Greater(t, hits[0].Score, hits[1].Score)
+	})
+	t.Run("should match any word of the query", func(t *testing.T) {
+		hits, err := index.Search("crawling reference")
+
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"https://anyurl.com/about", "https://anyurl.com/api"}, uris(hits))
+	})
+	t.Run("should only return the documents having the phrase", func(t *testing.T) {
+		hits, err := index.Search(`"deprecated API"`)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"https://anyurl.com/changelog"}, uris(hits))
+		assert.Equal(t, "The old API was deprecated in this release. Clients calling the deprecated API should migrate", hits[0].Snippet)
+	})
+	t.Run("should not match phrases across the title and the text", func(t *testing.T) {
+		hits, err := index.Search(`"reference every"`)
+
+		require.NoError(t, err)
+		assert.Empty(t, hits)
+	})
+	t.Run("should return error when the query has no words", func(t *testing.T) {
+		_, err := index.Search(` "" !? `)
+
+		assert.ErrorIs(t, err, ErrEmptyQuery)
+	})
+}
+
+func TestIndex_Snippet(t *testing.T) {
+	index := NewIndex(English)
+	index.Add(Document{URI: "https://anyurl.com", Text: "one two three four five six seven eight nine ten eleven twelve " +
+		"thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty crawler twenty-one twenty-two twenty-three " +
+		"twenty-four twenty-five twenty-six twenty-seven twenty-eight twenty-nine thirty thirty-one thirty-two thirty-three"})
+
+	hits, err := index.Search("crawler")
+
+	require.NoError(t, err)
+	assert.Equal(t, "…eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty crawler twenty-one "+
+		"twenty-two twenty-three twenty-four twenty-five twenty-six twenty-seven twenty-eight twenty-nine thirty…", hits[0].Snippet)
+}
+
+func TestIndex_SaveAndLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "indexes", "snapshot.idx")
+	index := newTestIndex()
+
+	require.NoError(t, index.Save(file))
+	loaded, err := Load(file)
+	require.NoError(t, err)
+
+	assert.Equal(t, index.Len(), loaded.Len())
+	expected, err := index.Search(`deprecated "old API"`)
+	require.NoError(t, err)
+	found, err := loaded.Search(`deprecated "old API"`)
+	require.NoError(t, err)
+	assert.Equal(t, expected, found)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.idx"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package warc
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // SHA-1 is the digest expected by the replay tools, not used for security
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const version = "WARC/1.1"
+
+// Record types written by the crawler.
+const (
+	WarcinfoType = "warcinfo"
+	RequestType  = "request"
+	ResponseType = "response"
+)
+
+// Record is a WARC record whose block is the HTTP message, or the fields of a warcinfo record. The payload
+// is the body of the HTTP message, digested apart from the block.
+type Record struct {
+	ID           string
+	Type         string
+	Date         time.Time
+	TargetURI    string
+	ConcurrentTo string
+	Filename     string
+	ContentType  string
+	Block        []byte
+	Payload      []byte
+}
+
+func newRecordID() string {
+	return "<urn:uuid:" + uuid.NewString() + ">"
+}
+
+// bytes formats the record as defined by the WARC 1.1 specification, the header fields followed by the
+// block and two line breaks.
+func (r Record) bytes() []byte {
+	fields := [][2]string{
+		{"WARC-Type", r.Type},
+		{"WARC-Record-ID", r.ID},
+		{"WARC-Date", r.Date.UTC().Format(time.RFC3339Nano)},
+	}
+	if r.TargetURI != "" {
+		fields = append(fields, [2]string{"WARC-Target-URI", r.TargetURI})
+	}
+	if r.ConcurrentTo != "" {
+		fields = append(fields, [2]string{"WARC-Concurrent-To", r.ConcurrentTo})
+	}
+	if r.Filename != "" {
+		fields = append(fields, [2]string{"WARC-Filename", r.Filename})
+	}
+	fields = append(fields, [2]string{"WARC-Block-Digest", digest(r.Block)})
+	if r.Payload != nil {
+		fields = append(fields, [2]string{"WARC-Payload-Digest", digest(r.Payload)})
+	}
+	fields = append(fields,
+		[2]string{"Content-Type", r.ContentType},
+		[2]string{"Content-Length", strconv.Itoa(len(r.Block))},
+	)
+
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(version + "\r\n")
+	for _, field := range fields {
+		fmt.Fprintf(buffer, "%s: %s\r\n", field[0], field[1])
+	}
+	buffer.WriteString("\r\n")
+	buffer.Write(r.Block)
+	buffer.WriteString("\r\n\r\n")
+
+	return buffer.Bytes()
+}
+
+func digest(content []byte) string {
+	sum := sha1.Sum(content) //nolint:gosec // SHA-1 is the digest expected by the replay tools, not used for security
+
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// newRequestRecord records the request as sent, with its request line and header fields.
+func newRequestRecord(request *http.Request, date time.Time, concurrentTo string) Record {
+	block := &bytes.Buffer{}
+	fmt.Fprintf(block, "%s %s HTTP/1.1\r\n", request.Method, request.URL.RequestURI())
+	fmt.Fprintf(block, "Host: %s\r\n", request.Host)
+	_ = request.Header.Write(block)
+	block.WriteString("\r\n")
+
+	return Record{
+		ID:           newRecordID(),
+		Type:         RequestType,
+		Date:         date,
+		TargetURI:    request.URL.String(),
+		ConcurrentTo: concurrentTo,
+		ContentType:  "application/http;msgtype=request",
+		Block:        block.Bytes(),
+	}
+}
+
+// newResponseRecord records the response as received, with its status line, header fields and body.
+func newResponseRecord(request *http.Request, response *http.Response, body []byte, date time.Time) Record {
+	block := &bytes.Buffer{}
+	fmt.Fprintf(block, "HTTP/%d.%d %s\r\n", response.ProtoMajor, response.ProtoMinor, response.Status)
+	_ = response.Header.Write(block)
+	block.WriteString("\r\n")
+	block.Write(body)
+
+	return Record{
+		ID:          newRecordID(),
+		Type:        ResponseType,
+		Date:        date,
+		TargetURI:   request.URL.String(),
+		ContentType: "application/http;msgtype=response",
+		Block:       block.Bytes(),
+		Payload:     body,
+	}
+}
+
+// newWarcinfoRecord describes the file it opens and the software that wrote it.
+func newWarcinfoRecord(filename string, date time.Time) Record {
+	return Record{
+		ID:          newRecordID(),
+		Type:        WarcinfoType,
+		Date:        date,
+		Filename:    filename,
+		ContentType: "application/warc-fields",
+		Block:       []byte("software: web-crawler\r\nformat: WARC File Format 1.1\r\n"),
+	}
+}
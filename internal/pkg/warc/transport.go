@@ -0,0 +1,103 @@
+package warc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var log = logger.GetLogger()
+
+type writerKey struct{}
+
+// NewContext returns a context whose requests are recorded by the writer.
+func NewContext(ctx context.Context, writer *Writer) context.Context {
+	return context.WithValue(ctx, writerKey{}, writer)
+}
+
+// FromContext returns the writer the requests of the context are recorded by, nil when not recorded.
+func FromContext(ctx context.Context) *Writer {
+	writer, _ := ctx.Value(writerKey{}).(*Writer)
+
+	return writer
+}
+
+// Transport records the request and response of every round trip whose context carries a writer, so a
+// crawl is recorded by giving it such a context.
+type Transport struct {
+	base        http.RoundTripper
+	maxBodySize int64
+}
+
+// Option configures the recording Transport.
+type Option func(*Transport)
+
+// WithMaxBodySize limits the size in bytes of the bodies read to be recorded, zero means unlimited. The
+// responses whose body is larger are handed to the caller without being recorded.
+func WithMaxBodySize(size int64) Option {
+	return func(t *Transport) {
+		t.maxBodySize = size
+	}
+}
+
+// NewTransport creates the recording layer in front of the base round tripper.
+func NewTransport(base http.RoundTripper, opts ...Option) Transport {
+	transport := Transport{base: base}
+	for _, opt := range opts {
+		opt(&transport)
+	}
+
+	return transport
+}
+
+// RoundTrip reads the whole response body to record it, handing a copy of it to the caller. A failure
+// to record is logged and does not fail the request.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	writer := FromContext(req.Context())
+	if writer == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	date := writer.now()
+	response, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader = response.Body
+	if t.maxBodySize > 0 {
+		reader = io.LimitReader(response.Body, t.maxBodySize+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		_ = response.Body.Close()
+
+		return nil, err
+	}
+	if t.maxBodySize > 0 && int64(len(body)) > t.maxBodySize {
+		log.Warn("response body too large to be recorded", zap.String("uri", req.URL.String()))
+		response.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), response.Body), Closer: response.Body}
+
+		return response, nil
+	}
+	_ = response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	responseRecord := newResponseRecord(req, response, body, date)
+	requestRecord := newRequestRecord(req, date, responseRecord.ID)
+	if err := writer.Write(responseRecord, requestRecord); err != nil {
+		log.Error("error writing warc records", logger.FieldError(err))
+	}
+
+	return response, nil
+}
+
+// readCloser hands the part of the body already read followed by the rest of it, closing the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,141 @@
+package warc
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readRecords(t *testing.T, path string) []string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	records := strings.Split(string(content), version+"\r\n")
+
+	return records[1:]
+}
+
+func get(t *testing.T, client *http.Client, ctx context.Context, uri string) string {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	require.NoError(t, err)
+
+	response, err := client.Do(req)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<a href="http://recorded-crawler.com">link</a>`))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: NewTransport(http.DefaultTransport)}
+
+	t.Run("should record the request and response with their digests", func(t *testing.T) {
+		writer, err := NewWriter(t.TempDir(), "crawl", 0)
+		require.NoError(t, err)
+
+		body := get(t, client, NewContext(context.Background(), writer), server.URL+"/page?q=1")
+		require.NoError(t, writer.Close())
+
+		assert.Equal(t, `<a href="http://recorded-crawler.com">link</a>`, body)
+		require.Len(t, writer.Files(), 1)
+		records := readRecords(t, writer.Files()[0])
+		require.Len(t, records, 3)
+		assert.Contains(t, records[0], "WARC-Type: warcinfo\r\n")
+		assert.Contains(t, records[1], "WARC-Type: response\r\n")
+		assert.Contains(t, records[1], "WARC-Target-URI: "+server.URL+"/page?q=1\r\n")
+		assert.Contains(t, records[1], "WARC-Payload-Digest: sha1:")
+		assert.Contains(t, records[1], "Content-Type: application/http;msgtype=response\r\n")
+		assert.Contains(t, records[1], "HTTP/1.1 200 OK\r\n")
+		assert.Contains(t, records[1], "Content-Type: text/html\r\n")
+		assert.Contains(t, records[1], "\r\n\r\n"+body+"\r\n\r\n")
+		assert.Contains(t, records[2], "WARC-Type: request\r\n")
+		assert.Contains(t, records[2], "GET /page?q=1 HTTP/1.1\r\n")
+		assert.Contains(t, records[2], "WARC-Concurrent-To: <urn:uuid:")
+	})
+
+	t.Run("should pass through requests without writer", func(t *testing.T) {
+		body := get(t, client, context.Background(), server.URL)
+
+		assert.Equal(t, `<a href="http://recorded-crawler.com">link</a>`, body)
+		assert.Nil(t, FromContext(context.Background()))
+	})
+
+	t.Run("should roll to a new file once the maximum size is reached", func(t *testing.T) {
+		writer, err := NewWriter(t.TempDir(), "crawl", 1)
+		require.NoError(t, err)
+		ctx := NewContext(context.Background(), writer)
+
+		get(t, client, ctx, server.URL)
+		get(t, client, ctx, server.URL)
+		require.NoError(t, writer.Close())
+
+		require.Len(t, writer.Files(), 2)
+		for _, file := range writer.Files() {
+			assert.Len(t, readRecords(t, file), 3)
+		}
+	})
+
+	t.Run("should hand the whole body without recording it when it exceeds the maximum size", func(t *testing.T) {
+		writer, err := NewWriter(t.TempDir(), "crawl", 0)
+		require.NoError(t, err)
+		limited := &http.Client{Transport: NewTransport(http.DefaultTransport, WithMaxBodySize(10))}
+
+		body := get(t, limited, NewContext(context.Background(), writer), server.URL)
+		require.NoError(t, writer.Close())
+
+		assert.Equal(t, `<a href="http://recorded-crawler.com">link</a>`, body)
+		assert.Empty(t, writer.Files())
+	})
+
+	t.Run("should write to files of their own when writers start at the same time", func(t *testing.T) {
+		dir := t.TempDir()
+		now := func() time.Time { return time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC) }
+		first, err := NewWriter(dir, "crawl", 0)
+		require.NoError(t, err)
+		second, err := NewWriter(dir, "crawl", 0)
+		require.NoError(t, err)
+		first.now, second.now = now, now
+
+		get(t, client, NewContext(context.Background(), first), server.URL)
+		get(t, client, NewContext(context.Background(), second), server.URL)
+		require.NoError(t, first.Close())
+		require.NoError(t, second.Close())
+
+		require.Len(t, first.Files(), 1)
+		require.Len(t, second.Files(), 1)
+		assert.NotEqual(t, first.Files()[0], second.Files()[0])
+		assert.Len(t, readRecords(t, first.Files()[0]), 3)
+		assert.Len(t, readRecords(t, second.Files()[0]), 3)
+	})
+}
+
+func TestRecord_Digest(t *testing.T) {
+	assert.Equal(t, "sha1:3I42H3S6NNFQ2MSVX7XZKYAYSCX5QBYJ", digest([]byte("")))
+}
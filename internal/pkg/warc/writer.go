@@ -0,0 +1,130 @@
+package warc
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Writer writes the records to gzip compressed WARC files, each record being a gzip member of its own, and
+// starts a new file once the current one reaches the maximum size, so a record is never split between files.
+type Writer struct {
+	mu      *sync.Mutex
+	dir     string
+	prefix  string
+	id      string
+	maxSize int64
+	now     func() time.Time
+	file    *os.File
+	size    int64
+	files   []string
+}
+
+// NewWriter creates the writer of the files named by the prefix in the directory, creating it when missing.
+// A maximum size of zero means a single file. The files are named with a random id of the writer as well, so
+// writers started at the same time never write to the same file.
+func NewWriter(dir, prefix string, maxSize int64) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	id := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+
+	return &Writer{mu: &sync.Mutex{}, dir: dir, prefix: prefix, id: id, maxSize: maxSize, now: time.Now}, nil
+}
+
+// Write appends the records to the current file, keeping them in the same file.
+func (w *Writer) Write(records ...Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil || (w.maxSize > 0 && w.size >= w.maxSize) {
+		if err := w.roll(); err != nil {
+			return err
+		}
+	}
+
+	for _, record := range records {
+		if err := w.write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Files returns the paths of the files written so far.
+func (w *Writer) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]string{}, w.files...)
+}
+
+// Close closes the current file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+// roll closes the current file and creates the next one, starting it with a warcinfo record. An existing
+// file is never written over.
+func (w *Writer) roll() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+	}
+
+	now := w.now().UTC()
+	filename := fmt.Sprintf("%s-%s-%s-%05d.warc.gz", w.prefix, now.Format("20060102150405"), w.id, len(w.files))
+	file, err := os.OpenFile(filepath.Join(w.dir, filename), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+	w.file, w.size = file, 0
+	w.files = append(w.files, file.Name())
+
+	return w.write(newWarcinfoRecord(filename, now))
+}
+
+func (w *Writer) write(record Record) error {
+	counter := &countingWriter{writer: w.file}
+	compressor := gzip.NewWriter(counter)
+	if _, err := compressor.Write(record.bytes()); err != nil {
+		return err
+	}
+	if err := compressor.Close(); err != nil {
+		return err
+	}
+	w.size += counter.count
+
+	return nil
+}
+
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	c.count += int64(n)
+
+	return n, err
+}
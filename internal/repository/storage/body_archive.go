@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	ZstdCompression = "zstd"
+	GzipCompression = "gzip"
+)
+
+// ErrUnknownCompression is returned when the bodies are asked to be archived with an unsupported compression.
+var ErrUnknownCompression = errors.New("unknown body compression")
+
+// compressionExtensions are the file extensions of the compressions supported.
+var compressionExtensions = map[string]string{ZstdCompression: ".zst", GzipCompression: ".gz"}
+
+// LocalBodyArchive keeps the bodies compressed in a local directory, in a file named by the digest
+// under a subdirectory named by its first two characters, so no directory grows too large.
+type LocalBodyArchive struct {
+	dir         string
+	compression string
+}
+
+// NewLocalBodyArchive creates the archive in the directory, creating it when missing.
+func NewLocalBodyArchive(dir, compression string) (LocalBodyArchive, error) {
+	if _, found := compressionExtensions[compression]; !found {
+		return LocalBodyArchive{}, fmt.Errorf("%w: %s", ErrUnknownCompression, compression)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return LocalBodyArchive{}, err
+	}
+
+	return LocalBodyArchive{dir: dir, compression: compression}, nil
+}
+
+// Store keeps the body unless there is one archived with the same digest, being written to a temporary
+// file first so a body being written is never read.
+func (l LocalBodyArchive) Store(_ context.Context, digest string, body []byte) error {
+	if _, _, err := l.find(digest); err == nil {
+		return nil
+	}
+
+	compressed, err := compressBody(l.compression, body)
+	if err != nil {
+		return err
+	}
+
+	path := l.path(digest, l.compression)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	temporary, err := os.CreateTemp(filepath.Dir(path), digest+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(temporary.Name())
+	}()
+
+	if _, err := temporary.Write(compressed); err != nil {
+		_ = temporary.Close()
+
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary.Name(), path)
+}
+
+// Load returns the body archived with the digest, whatever the compression it was archived with.
+func (l LocalBodyArchive) Load(_ context.Context, digest string) ([]byte, error) {
+	path, compression, err := l.find(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	compressed, err := os.ReadFile(path) //nolint:gosec // the path is made of the digest in the configured directory
+	if err != nil {
+		return nil, err
+	}
+
+	return decompressBody(compression, compressed)
+}
+
+func (l LocalBodyArchive) find(digest string) (string, string, error) {
+	for _, compression := range []string{ZstdCompression, GzipCompression} {
+		path := l.path(digest, compression)
+		if _, err := os.Stat(path); err == nil {
+			return path, compression, nil
+		}
+	}
+
+	return "", "", pager.ErrBodyNotArchived
+}
+
+func (l LocalBodyArchive) path(digest, compression string) string {
+	prefix := digest
+	if len(prefix) > 2 {
+		prefix = prefix[:2]
+	}
+
+	return filepath.Join(l.dir, prefix, digest+compressionExtensions[compression])
+}
+
+func compressBody(compression string, body []byte) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+
+	var writer io.WriteCloser
+	switch compression {
+	case ZstdCompression:
+		encoder, err := zstd.NewWriter(buffer)
+		if err != nil {
+			return nil, err
+		}
+		writer = encoder
+	case GzipCompression:
+		writer = gzip.NewWriter(buffer)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCompression, compression)
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func decompressBody(compression string, compressed []byte) ([]byte, error) {
+	switch compression {
+	case ZstdCompression:
+		decoder, err := zstd.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+
+		return io.ReadAll(decoder)
+	case GzipCompression:
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, err
+		}
+
+		return io.ReadAll(reader)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCompression, compression)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalBodyArchive(t *testing.T) {
+	ctx := context.Background()
+	digest := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	body := []byte(`<html><body><a href="http://archived-crawler.com">link</a></body></html>`)
+
+	for _, compression := range []string{ZstdCompression, GzipCompression} {
+		t.Run("should load the body stored with "+compression, func(t *testing.T) {
+			dir := t.TempDir()
+			archive, err := NewLocalBodyArchive(dir, compression)
+			assert.NoError(t, err)
+
+			assert.NoError(t, archive.Store(ctx, digest, body))
+			loaded, err := archive.Load(ctx, digest)
+
+			assert.NoError(t, err)
+			assert.Equal(t, body, loaded)
+			assert.FileExists(t, filepath.Join(dir, "9f", digest+compressionExtensions[compression]))
+		})
+	}
+
+	t.Run("should keep a single file for identical bodies", func(t *testing.T) {
+		dir := t.TempDir()
+		archive, err := NewLocalBodyArchive(dir, ZstdCompression)
+		assert.NoError(t, err)
+
+		assert.NoError(t, archive.Store(ctx, digest, body))
+		assert.NoError(t, archive.Store(ctx, digest, body))
+
+		entries, err := os.ReadDir(filepath.Join(dir, "9f"))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("should load bodies stored with another compression", func(t *testing.T) {
+		dir := t.TempDir()
+		gzipArchive, err := NewLocalBodyArchive(dir, GzipCompression)
+		assert.NoError(t, err)
+		assert.NoError(t, gzipArchive.Store(ctx, digest, body))
+		zstdArchive, err := NewLocalBodyArchive(dir, ZstdCompression)
+		assert.NoError(t, err)
+
+		loaded, err := zstdArchive.Load(ctx, digest)
+
+		assert.NoError(t, err)
+		assert.Equal(t, body, loaded)
+	})
+
+	t.Run("should return error when the body was not archived", func(t *testing.T) {
+		archive, err := NewLocalBodyArchive(t.TempDir(), ZstdCompression)
+		assert.NoError(t, err)
+
+		_, err = archive.Load(ctx, digest)
+
+		assert.ErrorIs(t, err, pager.ErrBodyNotArchived)
+	})
+
+	t.Run("should return error when the compression is unknown", func(t *testing.T) {
+		_, err := NewLocalBodyArchive(t.TempDir(), "lz4")
+
+		assert.ErrorIs(t, err, ErrUnknownCompression)
+	})
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type bodyMetadata struct {
+	Compression string `bson:"compression"`
+}
+
+// MongodbBodyArchive keeps the bodies compressed in a GridFS bucket, each one being a file whose id is
+// the digest, so the same body is never written twice.
+type MongodbBodyArchive struct {
+	bucket      *gridfs.Bucket
+	compression string
+}
+
+// NewMongodbBodyArchive connects to MongoDB and opens the bucket given by MONGODB_ARCHIVE_BUCKET.
+func NewMongodbBodyArchive(ctx context.Context, compression string) (MongodbBodyArchive, error) {
+	if _, found := compressionExtensions[compression]; !found {
+		return MongodbBodyArchive{}, fmt.Errorf("%w: %s", ErrUnknownCompression, compression)
+	}
+
+	client, err := connectMongodb(ctx)
+	if err != nil {
+		return MongodbBodyArchive{}, err
+	}
+
+	database := client.Database(viper.GetString("MONGODB_DATABASE"))
+	bucket, err := gridfs.NewBucket(database, options.GridFSBucket().SetName(viper.GetString("MONGODB_ARCHIVE_BUCKET")))
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+
+		return MongodbBodyArchive{}, err
+	}
+
+	return MongodbBodyArchive{bucket: bucket, compression: compression}, nil
+}
+
+// Store keeps the body unless there is one archived with the same digest, including when it is written
+// concurrently by another crawl.
+func (m MongodbBodyArchive) Store(ctx context.Context, digest string, body []byte) error {
+	err := m.bucket.GetFilesCollection().FindOne(ctx, bson.D{{Key: "_id", Value: digest}}).Err()
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	compressed, err := compressBody(m.compression, body)
+	if err != nil {
+		return err
+	}
+
+	opts := options.GridFSUpload().SetMetadata(bodyMetadata{Compression: m.compression})
+	err = m.bucket.UploadFromStreamWithID(digest, digest+compressionExtensions[m.compression], bytes.NewReader(compressed), opts)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Load returns the body archived with the digest, whatever the compression it was archived with.
+func (m MongodbBodyArchive) Load(_ context.Context, digest string) ([]byte, error) {
+	stream, err := m.bucket.OpenDownloadStream(digest)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, pager.ErrBodyNotArchived
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	compressed, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := bodyMetadata{}
+	if err := bson.Unmarshal(stream.GetFile().Metadata, &metadata); err != nil {
+		return nil, err
+	}
+
+	return decompressBody(metadata.Compression, compressed)
+}
@@ -0,0 +1,346 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// CrawlerDatabaseConformanceSuite holds the behavior every storage backend must have to be used by the crawler.
+type CrawlerDatabaseConformanceSuite struct {
+	suite.Suite
+	database crawler.CrawlerDatabase
+}
+
+// runConformance runs the suite against a backend, which must be empty of the URIs used by the suite.
+func runConformance(t *testing.T, database crawler.CrawlerDatabase) {
+	t.Helper()
+	suite.Run(t, &CrawlerDatabaseConformanceSuite{database: database})
+}
+
+func TestMemoryConformance(t *testing.T) {
+	runConformance(t, NewCrawlerMemoryRepository())
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	repository, err := NewCrawlerSQLiteRepository(context.Background(), filepath.Join(t.TempDir(), "crawler.db"))
+	assert.NoError(t, err)
+	defer repository.Close()
+
+	runConformance(t, repository)
+}
+
+func TestPostgresConformanceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	req := testcontainers.ContainerRequest{
+		Image:        "postgres:16-alpine",
+		ExposedPorts: []string{"5432/tcp"},
+		Env:          map[string]string{"POSTGRES_PASSWORD": "postgres"},
+		WaitingFor:   wait.ForLog("database system is ready to accept connections").WithOccurrence(2),
+	}
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, container.Terminate(ctx))
+	}()
+
+	host, err := container.Host(ctx)
+	assert.NoError(t, err)
+	port, err := container.MappedPort(ctx, "5432")
+	assert.NoError(t, err)
+
+	dsn := fmt.Sprintf("postgres://postgres:postgres@%s:%s/postgres?sslmode=disable", host, port.Port())
+	repository, err := NewCrawlerPostgresRepository(ctx, dsn)
+	assert.NoError(t, err)
+	defer repository.Close()
+
+	runConformance(t, repository)
+}
+
+func (suite *CrawlerDatabaseConformanceSuite) TestInsertAndFind() {
+	ctx := context.Background()
+	uri := "http://conformance-crawler.com"
+	stored := crawler.Crawl{
+		URI:       uri,
+		Depth:     1,
+		CrawledAt: time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Links:     []string{"http://conformance-crawler.com/home"},
+		Pages: []crawler.Page{{
+			URI:          uri,
+			StatusCode:   http.StatusOK,
+			Redirects:    []pager.Redirect{{URI: uri, StatusCode: http.StatusMovedPermanently, Location: "/home"}},
+			ETag:         `"v1"`,
+			LastModified: "Tue, 02 Jan 2024 03:04:05 GMT",
+			ContentHash:  "hash",
+			BodyKey:      "hash",
+			Charset:      "windows-1252",
+			Links:        []string{"http://conformance-crawler.com/home"},
+			Metadata: crawler.Metadata{
+				Title:      "Conformance",
+				H1:         []string{"Conformance"},
+				Canonical:  "http://conformance-crawler.com",
+				Icon:       "http://conformance-crawler.com/favicon.ico",
+				Lang:       "en",
+				Alternates: []crawler.Alternate{{Lang: "pt", URI: "http://conformance-crawler.com/pt"}},
+				OpenGraph:  map[string]string{"og:title": "Conformance"},
+			},
+			StructuredData: []crawler.StructuredData{{
+				Format: crawler.JSONLDFormat,
+				Types:  []string{"WebSite"},
+				Data:   json.RawMessage(`{"@type":"WebSite","name":"Conformance"}`),
+			}},
+			Fields:  map[string][]string{"title": {"Conformance"}, "tags": {"crawler", "conformance"}},
+			Content: crawler.Content{Text: "Conformance\n\nEvery backend behaves the same.", WordCount: 6, ReadingMinutes: 1},
+		}},
+		Certificates: []pager.Certificate{{
+			Host:      "conformance-crawler.com",
+			Subject:   "CN=conformance-crawler.com",
+			Issuer:    "CN=Internal CA",
+			NotBefore: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			NotAfter:  time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			SANs:      []string{"conformance-crawler.com"},
+		}},
+	}
+
+	suite.T().Run("should return error when the crawl was never stored", func(t *testing.T) {
+		_, err := suite.database.Find(ctx, uri, 1, crawler.Options{})
+
+		assert.Error(t, err)
+	})
+
+	suite.T().Run("should return the crawl as stored", func(t *testing.T) {
+		assert.NoError(t, suite.database.Insert(ctx, stored))
+
+		crawl, err := suite.database.Find(ctx, uri, 1, crawler.Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, stored, crawl)
+	})
+
+	suite.T().Run("should keep crawls with other depths and options apart", func(t *testing.T) {
+		_, err := suite.database.Find(ctx, uri, 2, crawler.Options{})
+		assert.Error(t, err)
+
+		_, err = suite.database.Find(ctx, uri, 1, crawler.Options{AuthProfile: "staging"})
+		assert.Error(t, err)
+	})
+}
+
+func (suite *CrawlerDatabaseConformanceSuite) TestLatestCrawl() {
+	ctx := context.Background()
+	uri := "http://conformance-latest-crawler.com"
+	first := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	suite.T().Run("should return the latest crawl when crawled again", func(t *testing.T) {
+		older := crawler.Crawl{URI: uri, Depth: 1, CrawledAt: first, Links: []string{"http://older.com"}}
+		newer := crawler.Crawl{URI: uri, Depth: 1, CrawledAt: first.Add(time.Hour), Links: []string{"http://newer.com"}}
+		assert.NoError(t, suite.database.Insert(ctx, older))
+		assert.NoError(t, suite.database.Insert(ctx, newer))
+
+		crawl, err := suite.database.Find(ctx, uri, 1, crawler.Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, newer.Links, crawl.Links)
+	})
+
+	suite.T().Run("should return the crawl made with the same options", func(t *testing.T) {
+		opts := crawler.Options{AuthProfile: "staging"}
+		authenticated := crawler.Crawl{URI: uri, Depth: 1, Options: opts, CrawledAt: first, Links: []string{"http://private.com"}}
+		assert.NoError(t, suite.database.Insert(ctx, authenticated))
+
+		crawl, err := suite.database.Find(ctx, uri, 1, opts)
+
+		assert.NoError(t, err)
+		assert.Equal(t, authenticated.Links, crawl.Links)
+	})
+}
+
+func (suite *CrawlerDatabaseConformanceSuite) TestExpiration() {
+	ctx := context.Background()
+	uri := "http://conformance-expiration-crawler.com"
+	now := time.Now().UTC()
+	ttl := viper.Get("CRAWL_TTL")
+	viper.Set("CRAWL_TTL", time.Hour)
+	defer viper.Set("CRAWL_TTL", ttl)
+
+	for name, tc := range map[string]struct {
+		crawledAt time.Time
+		found     bool
+	}{
+		"should find the crawl younger than the TTL":     {crawledAt: now.Add(-time.Minute), found: true},
+		"should not find the crawl older than the TTL":   {crawledAt: now.Add(-2 * time.Hour), found: false},
+		"should find the crawl stored without timestamp": {crawledAt: time.Time{}, found: true},
+	} {
+		suite.T().Run(name, func(t *testing.T) {
+			crawlURI := fmt.Sprintf("%s/%d", uri, tc.crawledAt.Unix())
+			crawl := crawler.Crawl{
+				URI:       crawlURI,
+				Depth:     1,
+				CrawledAt: tc.crawledAt,
+				Links:     []string{crawlURI + "/home"},
+				Hops:      map[string]uint{crawlURI + "/home": 1},
+			}
+			assert.NoError(t, suite.database.Insert(ctx, crawl))
+
+			_, err := suite.database.Find(ctx, crawlURI, 1, crawler.Options{})
+			assert.Equal(t, tc.found, err == nil)
+			_, err = suite.database.FindShallower(ctx, crawlURI, 2, crawler.Options{})
+			assert.Equal(t, tc.found, err == nil)
+			assert.Equal(t, !tc.found, crawl.Expired(now, time.Hour))
+		})
+	}
+}
+
+func (suite *CrawlerDatabaseConformanceSuite) TestHops() {
+	ctx := context.Background()
+	uri := "http://conformance-hops-crawler.com"
+	internalURI := "http://conformance-hops-crawler.com/internal"
+	deepURI := "http://conformance-hops-crawler.com/deep"
+	deep := crawler.Crawl{
+		URI:   uri,
+		Depth: 3,
+		Links: []string{internalURI, deepURI},
+		Hops:  map[string]uint{internalURI: 1, deepURI: 2},
+		Pages: []crawler.Page{
+			{URI: uri, Depth: 0, Links: []string{internalURI}},
+			{URI: internalURI, Depth: 1, Links: []string{deepURI}},
+			{URI: deepURI, Depth: 2},
+		},
+	}
+	assert.NoError(suite.T(), suite.database.Insert(ctx, deep))
+
+	suite.T().Run("should serve shallower request from deeper crawl", func(t *testing.T) {
+		crawl, err := suite.database.Find(ctx, uri, 1, crawler.Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, deep.Truncate(1), crawl)
+	})
+
+	suite.T().Run("should not serve shallower request from deeper crawl without hops", func(t *testing.T) {
+		legacyURI := "http://conformance-legacy-crawler.com"
+		assert.NoError(t, suite.database.Insert(ctx, crawler.Crawl{URI: legacyURI, Depth: 3, Links: []string{internalURI}}))
+
+		_, err := suite.database.Find(ctx, legacyURI, 1, crawler.Options{})
+
+		assert.Error(t, err)
+	})
+
+	suite.T().Run("should return deepest shallower crawl to be extended", func(t *testing.T) {
+		assert.NoError(t, suite.database.Insert(ctx, deep.Truncate(1)))
+
+		crawl, err := suite.database.FindShallower(ctx, uri, 5, crawler.Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, deep, crawl)
+	})
+
+	suite.T().Run("should return error when there is no shallower crawl", func(t *testing.T) {
+		_, err := suite.database.FindShallower(ctx, uri, 1, crawler.Options{})
+
+		assert.Error(t, err)
+	})
+}
+
+func (suite *CrawlerDatabaseConformanceSuite) TestFindPage() {
+	ctx := context.Background()
+	uri := "http://conformance-page-crawler.com"
+	pageURI := "http://conformance-page-crawler.com/about"
+
+	suite.T().Run("should return error when page was never stored", func(t *testing.T) {
+		_, err := suite.database.FindPage(ctx, pageURI, crawler.Options{})
+
+		assert.Error(t, err)
+	})
+
+	suite.T().Run("should return the page stored by the latest crawl", func(t *testing.T) {
+		first := crawler.Crawl{URI: uri, Depth: 1, Pages: []crawler.Page{{URI: pageURI, ETag: `"v1"`}}}
+		latest := crawler.Crawl{URI: uri, Depth: 2, Pages: []crawler.Page{
+			{URI: uri, ETag: `"home"`},
+			{URI: pageURI, ETag: `"v2"`, ContentHash: "hash", Links: []string{"http://subcrawler.com"}},
+		}}
+		assert.NoError(t, suite.database.Insert(ctx, first))
+		assert.NoError(t, suite.database.Insert(ctx, latest))
+
+		page, err := suite.database.FindPage(ctx, pageURI, crawler.Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, latest.Pages[1], page)
+	})
+}
+
+func (suite *CrawlerDatabaseConformanceSuite) TestSnapshots() {
+	ctx := context.Background()
+	uri := "http://conformance-snapshot-crawler.com"
+	first := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	viper.Set("CRAWL_SNAPSHOT_RETENTION", 2)
+	defer viper.Set("CRAWL_SNAPSHOT_RETENTION", nil)
+
+	for day := range 3 {
+		crawl := crawler.Crawl{
+			URI:       uri,
+			Depth:     1,
+			CrawledAt: first.AddDate(0, 0, day),
+			Links:     []string{fmt.Sprintf("http://conformance-snapshot-crawler.com/%d", day)},
+		}
+		assert.NoError(suite.T(), suite.database.Insert(ctx, crawl))
+	}
+
+	suite.T().Run("should list the retained snapshots newest first", func(t *testing.T) {
+		snapshots, err := suite.database.History(ctx, uri, crawler.Options{})
+
+		assert.NoError(t, err)
+		assert.Len(t, snapshots, 2)
+		assert.Equal(t, first.AddDate(0, 0, 2), snapshots[0].CrawledAt)
+		assert.Equal(t, first.AddDate(0, 0, 1), snapshots[1].CrawledAt)
+		assert.Equal(t, 1, snapshots[0].Links)
+	})
+
+	suite.T().Run("should return the crawl stored by a snapshot", func(t *testing.T) {
+		snapshots, err := suite.database.History(ctx, uri, crawler.Options{})
+		assert.NoError(t, err)
+
+		crawl, err := suite.database.FindSnapshot(ctx, snapshots[1].ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"http://conformance-snapshot-crawler.com/1"}, crawl.Links)
+	})
+
+	suite.T().Run("should return not found when snapshot does not exist", func(t *testing.T) {
+		_, err := suite.database.FindSnapshot(ctx, "invalid")
+
+		assert.ErrorIs(t, err, crawler.ErrSnapshotNotFound)
+	})
+}
+
+func (suite *CrawlerDatabaseConformanceSuite) TestInsertDiff() {
+	diff := crawler.CrawlDiff{
+		URI:        "http://conformance-diff-crawler.com",
+		Depth:      1,
+		CreatedAt:  time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		AddedPages: []string{"http://conformance-diff-crawler.com/new"},
+		AddedLinks: []string{"http://conformance-diff-crawler.com/new"},
+	}
+
+	assert.NoError(suite.T(), suite.database.InsertDiff(context.Background(), diff))
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/spf13/viper"
+)
+
+// ErrNotStored is returned by the in-memory storage when there is nothing stored matching the search.
+var ErrNotStored = errors.New("nothing stored matching the search")
+
+type memorySnapshot struct {
+	id    int
+	crawl crawler.Crawl
+}
+
+// CrawlerMemoryRepository keeps the crawls in memory while the process runs, with the same behavior
+// of the databases, so the crawler can run and be tested without one.
+type CrawlerMemoryRepository struct {
+	mu    *sync.RWMutex
+	store *memoryStore
+}
+
+type memoryStore struct {
+	lastID    int
+	snapshots []memorySnapshot
+	diffs     []crawler.CrawlDiff
+}
+
+func NewCrawlerMemoryRepository() CrawlerMemoryRepository {
+	return CrawlerMemoryRepository{mu: &sync.RWMutex{}, store: &memoryStore{}}
+}
+
+// Insert keeps the crawl as a new snapshot, making it the current crawl.
+func (c CrawlerMemoryRepository) Insert(_ context.Context, crawl crawler.Crawl) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store.lastID++
+	c.store.snapshots = append(c.store.snapshots, memorySnapshot{id: c.store.lastID, crawl: crawl})
+	c.pruneSnapshots(crawl, c.store.lastID, time.Now().UTC())
+
+	return nil
+}
+
+// pruneSnapshots applies the retention policy to the snapshots of the same URI, depth and options, keeping
+// the newest ones up to the configured count and age, where zero means unlimited, along with the current one.
+func (c CrawlerMemoryRepository) pruneSnapshots(crawl crawler.Crawl, current int, now time.Time) {
+	maxAge := viper.GetDuration("CRAWL_SNAPSHOT_MAX_AGE")
+	retention := viper.GetInt("CRAWL_SNAPSHOT_RETENTION")
+
+	pruned := make(map[int]bool)
+	kept := 1
+	for _, snapshot := range c.latestFirst() {
+		if snapshot.id == current || !sameCrawl(snapshot.crawl, crawl.URI, crawl.Options) ||
+			snapshot.crawl.Depth != crawl.Depth {
+			continue
+		}
+
+		expired := maxAge > 0 && snapshot.crawl.CrawledAt.Before(now.Add(-maxAge))
+		if expired || (retention > 0 && kept >= retention) {
+			pruned[snapshot.id] = true
+
+			continue
+		}
+		kept++
+	}
+
+	snapshots := make([]memorySnapshot, 0, len(c.store.snapshots))
+	for _, snapshot := range c.store.snapshots {
+		if !pruned[snapshot.id] {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	c.store.snapshots = snapshots
+}
+
+// Find returns the latest crawl of the URI made with the depth or, when it has hop distances stored,
+// with a greater depth truncated to the depth asked.
+func (c CrawlerMemoryRepository) Find(_ context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := time.Now().UTC()
+	ttl := viper.GetDuration("CRAWL_TTL")
+	for _, snapshot := range c.latestFirst() {
+		crawl := snapshot.crawl
+		if !sameCrawl(crawl, uri, opts) || crawl.Expired(now, ttl) {
+			continue
+		}
+		if crawl.Depth == depth || (crawl.Depth > depth && crawl.Hops != nil) {
+			return crawl.Truncate(depth), nil
+		}
+	}
+
+	return crawler.Crawl{}, ErrNotStored
+}
+
+// FindShallower returns the deepest crawl of the URI made with a smaller depth that has hop distances
+// stored, so it can be extended to the depth.
+func (c CrawlerMemoryRepository) FindShallower(_ context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := time.Now().UTC()
+	ttl := viper.GetDuration("CRAWL_TTL")
+	found := false
+	shallower := crawler.Crawl{}
+	for _, snapshot := range c.latestFirst() {
+		crawl := snapshot.crawl
+		if !sameCrawl(crawl, uri, opts) || crawl.Depth >= depth || crawl.Hops == nil || crawl.Expired(now, ttl) {
+			continue
+		}
+		if !found || crawl.Depth > shallower.Depth {
+			found, shallower = true, crawl
+		}
+	}
+	if !found {
+		return crawler.Crawl{}, ErrNotStored
+	}
+
+	return shallower, nil
+}
+
+// FindPage returns the page as stored by the latest crawl that fetched it with the same options.
+func (c CrawlerMemoryRepository) FindPage(_ context.Context, uri string, opts crawler.Options) (crawler.Page, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, snapshot := range c.latestFirst() {
+		if snapshot.crawl.Options != opts {
+			continue
+		}
+		for _, page := range snapshot.crawl.Pages {
+			if page.URI == uri {
+				return page, nil
+			}
+		}
+	}
+
+	return crawler.Page{}, ErrNotStored
+}
+
+// InsertDiff keeps the changes found by an incremental crawl.
+func (c CrawlerMemoryRepository) InsertDiff(_ context.Context, diff crawler.CrawlDiff) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store.diffs = append(c.store.diffs, diff)
+
+	return nil
+}
+
+// History lists the snapshots of the crawls of the URI made with the options, newest first.
+func (c CrawlerMemoryRepository) History(_ context.Context, uri string, opts crawler.Options) ([]crawler.Snapshot, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshots := make([]crawler.Snapshot, 0)
+	for _, snapshot := range c.latestFirst() {
+		if !sameCrawl(snapshot.crawl, uri, opts) {
+			continue
+		}
+		snapshots = append(snapshots, crawler.Snapshot{
+			ID:        strconv.Itoa(snapshot.id),
+			URI:       snapshot.crawl.URI,
+			Depth:     snapshot.crawl.Depth,
+			Options:   snapshot.crawl.Options,
+			CrawledAt: snapshot.crawl.CrawledAt,
+			Links:     len(snapshot.crawl.Links),
+		})
+	}
+
+	return snapshots, nil
+}
+
+// FindSnapshot returns the crawl kept by the snapshot with the given id.
+func (c CrawlerMemoryRepository) FindSnapshot(_ context.Context, id string) (crawler.Crawl, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, snapshot := range c.store.snapshots {
+		if strconv.Itoa(snapshot.id) == id {
+			return snapshot.crawl, nil
+		}
+	}
+
+	return crawler.Crawl{}, crawler.ErrSnapshotNotFound
+}
+
+// latestFirst sorts the snapshots by the time they were crawled, and by the order they were kept
+// when crawled at the same time.
+func (c CrawlerMemoryRepository) latestFirst() []memorySnapshot {
+	snapshots := append(make([]memorySnapshot, 0, len(c.store.snapshots)), c.store.snapshots...)
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if !snapshots[i].crawl.CrawledAt.Equal(snapshots[j].crawl.CrawledAt) {
+			return snapshots[i].crawl.CrawledAt.After(snapshots[j].crawl.CrawledAt)
+		}
+
+		return snapshots[i].id > snapshots[j].id
+	})
+
+	return snapshots
+}
+
+func sameCrawl(crawl crawler.Crawl, uri string, opts crawler.Options) bool {
+	return crawl.URI == uri && crawl.Options == opts
+}
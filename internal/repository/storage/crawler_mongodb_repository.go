@@ -2,23 +2,182 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"time"
 
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
 	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
 	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 var log = logger.GetLogger()
 
+// latestFirst sorts the crawls by the time they were made, given that a crawl replaced by a newer one
+// keeps its original _id.
+var latestFirst = bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+
+const (
+	ttlIndexName      = "created_at_ttl"
+	uniqueIndexName   = "uri_depth_options_unique"
+	snapshotIndexName = "uri_options_depth_created_at"
+	crawlIndexName    = "crawl_id_position"
+	pageIndexName     = "uri_options_created_at"
+
+	indexNotFoundCode        = 27
+	indexOptionsConflictCode = 85
+	namespaceNotFoundCode    = 26
+)
+
 type CrawlerMongodbRepository struct {
 	client *mongo.Client
 }
 
-func NewCrawlerMongodbRepository(ctx context.Context) CrawlerMongodbRepository {
+// NewCrawlerMongodbRepository connects to MongoDB and creates the indexes, failing when the server
+// cannot be reached before the context is done.
+func NewCrawlerMongodbRepository(ctx context.Context) (CrawlerMongodbRepository, error) {
+	client, err := connectMongodb(ctx)
+	if err != nil {
+		return CrawlerMongodbRepository{}, err
+	}
+
+	repository := CrawlerMongodbRepository{client}
+	if err := repository.ensureTTLIndexes(ctx, viper.GetDuration("CRAWL_TTL")); err != nil {
+		log.Error("error configuring crawl expiration", logger.FieldError(err))
+	}
+	if err := repository.ensureUniqueIndex(ctx); err != nil {
+		log.Error("error creating unique index, duplicated crawls must be removed", logger.FieldError(err))
+	}
+	if err := repository.ensureSnapshotIndex(ctx); err != nil {
+		log.Error("error creating snapshot index", logger.FieldError(err))
+	}
+	if err := repository.ensurePageAndLinkIndexes(ctx); err != nil {
+		log.Error("error creating page and link indexes", logger.FieldError(err))
+	}
+
+	return repository, nil
+}
+
+// Ping checks that the server can be reached.
+func (c CrawlerMongodbRepository) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx, readpref.Primary())
+}
+
+// ensureUniqueIndex allows a single crawl for each URI, depth and options.
+func (c CrawlerMongodbRepository) ensureUniqueIndex(ctx context.Context) error {
+	_, err := c.getCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "uri", Value: 1}, {Key: "depth", Value: 1}, {Key: "auth_profile", Value: 1}},
+		Options: options.Index().SetName(uniqueIndexName).SetUnique(true),
+	})
+
+	return err
+}
+
+// ensureSnapshotIndex supports listing the history of a URI and pruning it, newest first.
+func (c CrawlerMongodbRepository) ensureSnapshotIndex(ctx context.Context) error {
+	_, err := c.getSnapshotCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "uri", Value: 1},
+			{Key: "auth_profile", Value: 1},
+			{Key: "depth", Value: 1},
+			{Key: "created_at", Value: -1},
+		},
+		Options: options.Index().SetName(snapshotIndexName),
+	})
+
+	return err
+}
+
+// ensurePageAndLinkIndexes supports reading the pages and links of a crawl in the order they were found,
+// a range of positions at a time, and finding the latest version of a page.
+func (c CrawlerMongodbRepository) ensurePageAndLinkIndexes(ctx context.Context) error {
+	byCrawl := mongo.IndexModel{
+		Keys:    bson.D{{Key: "crawl_id", Value: 1}, {Key: "position", Value: 1}},
+		Options: options.Index().SetName(crawlIndexName).SetUnique(true),
+	}
+	byPage := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "uri", Value: 1},
+			{Key: "auth_profile", Value: 1},
+			{Key: "created_at", Value: -1},
+		},
+		Options: options.Index().SetName(pageIndexName),
+	}
+
+	if _, err := c.getPagesCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{byCrawl, byPage}); err != nil {
+		return err
+	}
+	_, err := c.getLinksCollection().Indexes().CreateOne(ctx, byCrawl)
+
+	return err
+}
+
+// ensureTTLIndexes makes MongoDB remove the crawls once they are older than the TTL, along with their
+// snapshots, pages, links and diffs, so nothing written for an expired crawl is left behind.
+func (c CrawlerMongodbRepository) ensureTTLIndexes(ctx context.Context, ttl time.Duration) error {
+	for _, collection := range []*mongo.Collection{
+		c.getCollection(),
+		c.getSnapshotCollection(),
+		c.getPagesCollection(),
+		c.getLinksCollection(),
+		c.getDiffCollection(),
+	} {
+		if err := ensureTTLIndex(ctx, collection, ttl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureTTLIndex creates the TTL index of the collection, updating the expiration of an existing index
+// and dropping it when the TTL is zero.
+func ensureTTLIndex(ctx context.Context, collection *mongo.Collection, ttl time.Duration) error {
+	indexes := collection.Indexes()
+	if ttl <= 0 {
+		_, err := indexes.DropOne(ctx, ttlIndexName)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	expireAfter := int32(ttl.Seconds())
+	_, err := indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetName(ttlIndexName).SetExpireAfterSeconds(expireAfter),
+	})
+
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == indexOptionsConflictCode {
+		collMod := bson.D{
+			{Key: "collMod", Value: collection.Name()},
+			{Key: "index", Value: bson.D{{Key: "name", Value: ttlIndexName}, {Key: "expireAfterSeconds", Value: expireAfter}}},
+		}
+
+		return collection.Database().RunCommand(ctx, collMod).Err()
+	}
+
+	return err
+}
+
+func isNotFound(err error) bool {
+	var commandErr mongo.CommandError
+
+	return errors.As(err, &commandErr) &&
+		(commandErr.Code == namespaceNotFoundCode || commandErr.Code == indexNotFoundCode)
+}
+
+// connectMongodb connects to the configured MongoDB, failing when the server cannot be reached
+// before the context is done.
+func connectMongodb(ctx context.Context) (*mongo.Client, error) {
 	username := viper.GetString("MONGODB_USERNAME")
 	password := viper.GetString("MONGODB_PASSWORD")
 	host := viper.GetString("MONGODB_HOST")
@@ -32,42 +191,331 @@ func NewCrawlerMongodbRepository(ctx context.Context) CrawlerMongodbRepository {
 	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		log.Error("error connecting to mongodb", logger.FieldError(err))
+
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		log.Error("error reaching mongodb", logger.FieldError(err))
+		_ = client.Disconnect(context.Background())
+
+		return nil, err
 	}
 
-	return CrawlerMongodbRepository{client}
+	return client, nil
 }
 
 func noUserInformation(username, password string) bool {
 	return username == "" && password == ""
 }
 
-func (c CrawlerMongodbRepository) Insert(ctx context.Context, uri string, depth uint, uris []string) error {
-	pageDataInfo := pageDataInfo{
-		URI:   uri,
-		Depth: depth,
-		URIs:  uris,
+// Insert stores the pages and links of the crawl as a new version, kept as a snapshot, and makes it the
+// crawl of the URI, depth and options replacing the previous one, so writing the same crawl twice leaves
+// a single current crawl.
+func (c CrawlerMongodbRepository) Insert(ctx context.Context, crawl crawler.Crawl) error {
+	crawlID := primitive.NewObjectID()
+	if err := insertMany(ctx, c.getPagesCollection(), newCrawlPageInfos(crawl, crawlID)); err != nil {
+		log.Error("error while inserting pages into collection", logger.FieldError(err))
+
+		return err
+	}
+	if err := insertMany(ctx, c.getLinksCollection(), newLinkInfos(crawl, crawlID)); err != nil {
+		log.Error("error while inserting links into collection", logger.FieldError(err))
+
+		return err
+	}
+
+	crawlInfo := newCrawlInfo(crawl, crawlID)
+	snapshotInfo := crawlInfo
+	snapshotInfo.ID = crawlID
+	if _, err := c.getSnapshotCollection().InsertOne(ctx, snapshotInfo); err != nil {
+		log.Error("error while inserting crawl snapshot into collection", logger.FieldError(err))
+
+		return err
+	}
+
+	filter := bson.D{{Key: "uri", Value: crawl.URI}, {Key: "depth", Value: crawl.Depth}, optionsFilter(crawl.Options)}
+	replaceOptions := options.Replace().SetUpsert(true)
+	_, err := c.getCollection().ReplaceOne(ctx, filter, crawlInfo, replaceOptions)
+	if mongo.IsDuplicateKeyError(err) {
+		// Two upserts racing to insert the same document, the second one finds it on retry.
+		_, err = c.getCollection().ReplaceOne(ctx, filter, crawlInfo, replaceOptions)
 	}
-	_, err := c.getCollection().InsertOne(ctx, pageDataInfo)
 	if err != nil {
 		log.Error("error while inserting new data into collection", logger.FieldError(err))
 
 		return err
 	}
 
+	return c.pruneSnapshots(ctx, crawl, crawlID, time.Now().UTC())
+}
+
+// insertMany writes the documents in unordered bulk writes, leaving to the driver splitting them into
+// batches within the server limits.
+func insertMany[T any](ctx context.Context, collection *mongo.Collection, infos []T) error {
+	if len(infos) == 0 {
+		return nil
+	}
+
+	documents := make([]any, 0, len(infos))
+	for _, info := range infos {
+		documents = append(documents, info)
+	}
+	_, err := collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+
+	return err
+}
+
+// pruneSnapshots applies the retention policy to the snapshots of the same URI, depth and options, keeping
+// the newest ones up to the configured count and age, where zero means unlimited, along with the current one.
+// The pages and links of the snapshots pruned are removed with them.
+func (c CrawlerMongodbRepository) pruneSnapshots(
+	ctx context.Context,
+	crawl crawler.Crawl,
+	current primitive.ObjectID,
+	now time.Time,
+) error {
+	filter := bson.D{
+		{Key: "_id", Value: bson.D{{Key: "$ne", Value: current}}},
+		{Key: "uri", Value: crawl.URI},
+		{Key: "depth", Value: crawl.Depth},
+		optionsFilter(crawl.Options),
+	}
+
+	if maxAge := viper.GetDuration("CRAWL_SNAPSHOT_MAX_AGE"); maxAge > 0 {
+		expired := append(bson.D{{Key: "created_at", Value: bson.D{{Key: "$lt", Value: now.Add(-maxAge)}}}}, filter...)
+		if err := c.deleteSnapshots(ctx, expired, options.Find()); err != nil {
+			log.Error("error while pruning expired crawl snapshots", logger.FieldError(err))
+
+			return err
+		}
+	}
+
+	retention := viper.GetInt64("CRAWL_SNAPSHOT_RETENTION")
+	if retention <= 0 {
+		return nil
+	}
+
+	// The current snapshot is left out of the filter, so it takes one of the places retained.
+	if err := c.deleteSnapshots(ctx, filter, options.Find().SetSort(latestFirst).SetSkip(retention-1)); err != nil {
+		log.Error("error while pruning crawl snapshots", logger.FieldError(err))
+
+		return err
+	}
+
 	return nil
 }
 
-func (c CrawlerMongodbRepository) Find(ctx context.Context, uri string, depth uint) ([]string, error) {
-	filter := bson.D{{Key: "uri", Value: uri}, {Key: "depth", Value: depth}}
-	pageDataInfo := pageDataInfo{}
-	err := c.getCollection().FindOne(ctx, filter).Decode(&pageDataInfo)
+// deleteSnapshots removes the snapshots found by the query along with their pages and links.
+func (c CrawlerMongodbRepository) deleteSnapshots(ctx context.Context, filter bson.D, findOptions *options.FindOptions) error {
+	cursor, err := c.getSnapshotCollection().Find(ctx, filter, findOptions.SetProjection(bson.D{{Key: "_id", Value: 1}}))
 	if err != nil {
-		log.Error("error while fetching data from collection", logger.FieldError(err))
+		return err
+	}
+
+	snapshots := make([]crawlInfo, 0)
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	ids := make(bson.A, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		ids = append(ids, snapshot.ID)
+	}
+	byCrawl := bson.D{{Key: "crawl_id", Value: bson.D{{Key: "$in", Value: ids}}}}
+	if _, err := c.getPagesCollection().DeleteMany(ctx, byCrawl); err != nil {
+		return err
+	}
+	if _, err := c.getLinksCollection().DeleteMany(ctx, byCrawl); err != nil {
+		return err
+	}
+	_, err = c.getSnapshotCollection().DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}})
+
+	return err
+}
+
+// History lists the snapshots of the crawls of the URI made with the options, newest first.
+func (c CrawlerMongodbRepository) History(ctx context.Context, uri string, opts crawler.Options) ([]crawler.Snapshot, error) {
+	filter := bson.D{{Key: "uri", Value: uri}, optionsFilter(opts)}
+	findOptions := options.Find().
+		SetSort(latestFirst).
+		SetProjection(bson.D{{Key: "certificates", Value: 0}})
+
+	cursor, err := c.getSnapshotCollection().Find(ctx, filter, findOptions)
+	if err != nil {
+		log.Error("error while fetching crawl snapshots from collection", logger.FieldError(err))
 
 		return nil, err
 	}
 
-	return pageDataInfo.URIs, nil
+	infos := make([]crawlInfo, 0)
+	if err := cursor.All(ctx, &infos); err != nil {
+		log.Error("error while decoding crawl snapshots", logger.FieldError(err))
+
+		return nil, err
+	}
+
+	snapshots := make([]crawler.Snapshot, 0, len(infos))
+	for _, info := range infos {
+		snapshots = append(snapshots, info.toSnapshot())
+	}
+
+	return snapshots, nil
+}
+
+// FindSnapshot returns the crawl stored by the snapshot with the given id.
+func (c CrawlerMongodbRepository) FindSnapshot(ctx context.Context, id string) (crawler.Crawl, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return crawler.Crawl{}, crawler.ErrSnapshotNotFound
+	}
+
+	info := crawlInfo{}
+	err = c.getSnapshotCollection().FindOne(ctx, bson.D{{Key: "_id", Value: objectID}}).Decode(&info)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return crawler.Crawl{}, crawler.ErrSnapshotNotFound
+	}
+	if err != nil {
+		log.Error("error while fetching crawl snapshot from collection", logger.FieldError(err))
+
+		return crawler.Crawl{}, err
+	}
+
+	return c.load(ctx, info, info.Depth)
+}
+
+// Find returns the latest crawl of the URI made with the depth or, when it has hop distances stored,
+// with a greater depth truncated to the depth asked.
+func (c CrawlerMongodbRepository) Find(ctx context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, error) {
+	filter := bson.D{
+		{Key: "uri", Value: uri},
+		optionsFilter(opts),
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "depth", Value: depth}},
+			bson.D{{Key: "depth", Value: bson.D{{Key: "$gt", Value: depth}}}, {Key: "hops", Value: true}},
+		}},
+	}
+	filter = append(filter, notExpiredFilter(time.Now().UTC())...)
+	findOptions := options.FindOne().SetSort(latestFirst)
+	info := crawlInfo{}
+	err := c.getCollection().FindOne(ctx, filter, findOptions).Decode(&info)
+	if err != nil {
+		log.Error("error while fetching data from collection", logger.FieldError(err))
+
+		return crawler.Crawl{}, err
+	}
+
+	crawl, err := c.load(ctx, info, depth)
+	if err != nil {
+		log.Error("error while fetching pages and links from collection", logger.FieldError(err))
+
+		return crawler.Crawl{}, err
+	}
+
+	return crawl.Truncate(depth), nil
+}
+
+// FindShallower returns the deepest crawl of the URI made with a smaller depth that has hop distances
+// stored, so it can be extended to the depth.
+func (c CrawlerMongodbRepository) FindShallower(ctx context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, error) {
+	filter := bson.D{
+		{Key: "uri", Value: uri},
+		optionsFilter(opts),
+		{Key: "depth", Value: bson.D{{Key: "$lt", Value: depth}}},
+		{Key: "hops", Value: true},
+	}
+	filter = append(filter, notExpiredFilter(time.Now().UTC())...)
+	findOptions := options.FindOne().SetSort(append(bson.D{{Key: "depth", Value: -1}}, latestFirst...))
+	info := crawlInfo{}
+	err := c.getCollection().FindOne(ctx, filter, findOptions).Decode(&info)
+	if err != nil {
+		return crawler.Crawl{}, err
+	}
+
+	return c.load(ctx, info, info.Depth)
+}
+
+// load reads the pages and links of the crawl in the order they were found, leaving out the ones beyond
+// the depth when a deeper crawl is read.
+func (c CrawlerMongodbRepository) load(ctx context.Context, info crawlInfo, depth uint) (crawler.Crawl, error) {
+	byPosition := options.Find().SetSort(bson.D{{Key: "position", Value: 1}})
+
+	pagesFilter := bson.D{{Key: "crawl_id", Value: info.CrawlID}}
+	linksFilter := bson.D{{Key: "crawl_id", Value: info.CrawlID}}
+	if info.Hops && depth < info.Depth {
+		pagesFilter = append(pagesFilter, bson.E{Key: "depth", Value: bson.D{{Key: "$lt", Value: depth}}})
+		linksFilter = append(linksFilter, bson.E{Key: "hop", Value: bson.D{{Key: "$lte", Value: depth}}})
+	}
+
+	pages := make([]crawlPageInfo, 0, info.Pages)
+	cursor, err := c.getPagesCollection().Find(ctx, pagesFilter, byPosition)
+	if err != nil {
+		return crawler.Crawl{}, err
+	}
+	if err := cursor.All(ctx, &pages); err != nil {
+		return crawler.Crawl{}, err
+	}
+
+	links := make([]linkInfo, 0, info.Links)
+	cursor, err = c.getLinksCollection().Find(ctx, linksFilter, byPosition)
+	if err != nil {
+		return crawler.Crawl{}, err
+	}
+	if err := cursor.All(ctx, &links); err != nil {
+		return crawler.Crawl{}, err
+	}
+
+	return info.toCrawl(pages, links), nil
+}
+
+// FindPage returns the page as stored by the latest crawl that fetched it with the same options.
+func (c CrawlerMongodbRepository) FindPage(ctx context.Context, uri string, opts crawler.Options) (crawler.Page, error) {
+	filter := bson.D{{Key: "uri", Value: uri}, optionsFilter(opts)}
+	findOptions := options.FindOne().SetSort(latestFirst)
+
+	info := crawlPageInfo{}
+	err := c.getPagesCollection().FindOne(ctx, filter, findOptions).Decode(&info)
+	if err != nil {
+		return crawler.Page{}, err
+	}
+
+	return info.toPage(), nil
+}
+
+// InsertDiff stores the changes found by an incremental crawl in their own collection.
+func (c CrawlerMongodbRepository) InsertDiff(ctx context.Context, diff crawler.CrawlDiff) error {
+	_, err := c.getDiffCollection().InsertOne(ctx, newCrawlDiffInfo(diff))
+	if err != nil {
+		log.Error("error while inserting crawl diff into collection", logger.FieldError(err))
+
+		return err
+	}
+
+	return nil
+}
+
+// notExpiredFilter leaves out the crawls older than the TTL not removed by the TTL index yet, keeping the
+// ones stored without timestamp as crawler.Crawl.Expired does.
+func notExpiredFilter(now time.Time) bson.D {
+	ttl := viper.GetDuration("CRAWL_TTL")
+	if ttl <= 0 {
+		return bson.D{}
+	}
+
+	return bson.D{{Key: "created_at", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$lt", Value: now.Add(-ttl)}}}}}}
+}
+
+// optionsFilter matches the documents crawled with the same options, where the options left
+// empty are not stored at all.
+func optionsFilter(opts crawler.Options) bson.E {
+	if opts.AuthProfile == "" {
+		return bson.E{Key: "auth_profile", Value: bson.D{{Key: "$exists", Value: false}}}
+	}
+
+	return bson.E{Key: "auth_profile", Value: opts.AuthProfile}
 }
 
 func (c CrawlerMongodbRepository) getCollection() *mongo.Collection {
@@ -76,3 +524,31 @@ func (c CrawlerMongodbRepository) getCollection() *mongo.Collection {
 
 	return c.client.Database(databaseName).Collection(collectionName)
 }
+
+func (c CrawlerMongodbRepository) getPagesCollection() *mongo.Collection {
+	databaseName := viper.GetString("MONGODB_DATABASE")
+	collectionName := viper.GetString("MONGODB_PAGES_COLLECTION")
+
+	return c.client.Database(databaseName).Collection(collectionName)
+}
+
+func (c CrawlerMongodbRepository) getLinksCollection() *mongo.Collection {
+	databaseName := viper.GetString("MONGODB_DATABASE")
+	collectionName := viper.GetString("MONGODB_LINKS_COLLECTION")
+
+	return c.client.Database(databaseName).Collection(collectionName)
+}
+
+func (c CrawlerMongodbRepository) getSnapshotCollection() *mongo.Collection {
+	databaseName := viper.GetString("MONGODB_DATABASE")
+	collectionName := viper.GetString("MONGODB_SNAPSHOT_COLLECTION")
+
+	return c.client.Database(databaseName).Collection(collectionName)
+}
+
+func (c CrawlerMongodbRepository) getDiffCollection() *mongo.Collection {
+	databaseName := viper.GetString("MONGODB_DATABASE")
+	collectionName := viper.GetString("MONGODB_DIFF_COLLECTION")
+
+	return c.client.Database(databaseName).Collection(collectionName)
+}
@@ -2,13 +2,19 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -41,7 +47,7 @@ func (suite *MongodbRepositoryIntegrationTestSuite) SetupSuite() {
 		Started:          true,
 	}
 	container, err := testcontainers.GenericContainer(ctx, genericRequest)
-	assert.NoError(suite.T(), err)
+	suite.Require().NoError(err)
 
 	host, err := container.Host(ctx)
 	assert.NoError(suite.T(), err)
@@ -52,7 +58,8 @@ func (suite *MongodbRepositoryIntegrationTestSuite) SetupSuite() {
 	viper.Set("MONGODB_PORT", string(port))
 
 	suite.Container = container
-	suite.repository = NewCrawlerMongodbRepository(ctx)
+	suite.repository, err = NewCrawlerMongodbRepository(ctx)
+	assert.NoError(suite.T(), err)
 }
 
 func (suite *MongodbRepositoryIntegrationTestSuite) TearDownSuite() {
@@ -62,6 +69,61 @@ func (suite *MongodbRepositoryIntegrationTestSuite) TearDownSuite() {
 	viper.Reset()
 }
 
+func (suite *MongodbRepositoryIntegrationTestSuite) TestNewCrawlerMongodbRepository() {
+	suite.Suite.T().Run("should return error when mongodb cannot be reached", func(t *testing.T) {
+		port := viper.GetString("MONGODB_PORT")
+		defer func() {
+			viper.Set("MONGODB_PORT", port)
+		}()
+		viper.Set("MONGODB_PORT", "1")
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		_, err := NewCrawlerMongodbRepository(ctx)
+
+		assert.Error(t, err)
+	})
+
+	suite.Suite.T().Run("should ping mongodb when reachable", func(t *testing.T) {
+		assert.NoError(t, suite.repository.Ping(context.Background()))
+	})
+}
+
+func (suite *MongodbRepositoryIntegrationTestSuite) TestBodyArchive() {
+	ctx := context.Background()
+	digest := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	body := []byte(`<html><body>archived</body></html>`)
+	archive, err := NewMongodbBodyArchive(ctx, ZstdCompression)
+	assert.NoError(suite.T(), err)
+
+	suite.Suite.T().Run("should return error when the body was not archived", func(t *testing.T) {
+		_, err := archive.Load(ctx, digest)
+
+		assert.ErrorIs(t, err, pager.ErrBodyNotArchived)
+	})
+
+	suite.Suite.T().Run("should keep a single file for identical bodies", func(t *testing.T) {
+		assert.NoError(t, archive.Store(ctx, digest, body))
+		assert.NoError(t, archive.Store(ctx, digest, body))
+
+		count, err := archive.bucket.GetFilesCollection().CountDocuments(ctx, bson.D{{Key: "_id", Value: digest}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	suite.Suite.T().Run("should load the body stored", func(t *testing.T) {
+		loaded, err := archive.Load(ctx, digest)
+
+		assert.NoError(t, err)
+		assert.Equal(t, body, loaded)
+	})
+}
+
+func (suite *MongodbRepositoryIntegrationTestSuite) TestConformance() {
+	runConformance(suite.T(), suite.repository)
+}
+
 func (suite *MongodbRepositoryIntegrationTestSuite) TestInsert() {
 	ctx := context.Background()
 	uri := "http://crawler.com"
@@ -74,17 +136,43 @@ func (suite *MongodbRepositoryIntegrationTestSuite) TestInsert() {
 		}()
 		viper.Set("MONGODB_DATABASE", "")
 
-		repository := NewCrawlerMongodbRepository(ctx)
-		err := repository.Insert(ctx, uri, depth, uris)
+		repository, err := NewCrawlerMongodbRepository(ctx)
+		assert.NoError(suite.T(), err)
+
+		err = repository.Insert(ctx, crawler.Crawl{URI: uri, Depth: depth, Links: uris})
 
 		assert.NotNil(suite.T(), err)
 	})
 
 	suite.Suite.T().Run("should insert data page with success", func(t *testing.T) {
-		err := suite.repository.Insert(ctx, uri, depth, uris)
+		err := suite.repository.Insert(ctx, crawler.Crawl{URI: uri, Depth: depth, Links: uris})
 
 		assert.NoError(suite.T(), err)
 	})
+
+	suite.Suite.T().Run("should keep a single document when the same crawl is inserted concurrently", func(t *testing.T) {
+		duplicatedURI := "http://duplicated-crawler.com"
+		errs := make(chan error, 5)
+		for range 5 {
+			go func() {
+				errs <- suite.repository.Insert(ctx, crawler.Crawl{URI: duplicatedURI, Depth: depth, Links: uris})
+			}()
+		}
+		for range 5 {
+			assert.NoError(suite.T(), <-errs)
+		}
+
+		count, err := suite.repository.getCollection().CountDocuments(ctx, bson.D{{Key: "uri", Value: duplicatedURI}})
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), int64(1), count)
+	})
+
+	suite.Suite.T().Run("should refuse a duplicated document written without upsert", func(t *testing.T) {
+		_, err := suite.repository.getCollection().InsertOne(ctx, crawlInfo{URI: uri, Depth: depth, Links: len(uris)})
+
+		assert.True(suite.T(), mongo.IsDuplicateKeyError(err))
+	})
 }
 
 func (suite *MongodbRepositoryIntegrationTestSuite) TestFind() {
@@ -99,33 +187,462 @@ func (suite *MongodbRepositoryIntegrationTestSuite) TestFind() {
 		}()
 		viper.Set("MONGODB_DATABASE", "")
 
-		repository := NewCrawlerMongodbRepository(ctx)
-		storedURIs, err := repository.Find(ctx, uri, depth)
+		repository, err := NewCrawlerMongodbRepository(ctx)
+		assert.NoError(suite.T(), err)
+
+		crawl, err := repository.Find(ctx, uri, depth, crawler.Options{})
 
 		assert.NotNil(suite.T(), err)
-		assert.Empty(suite.T(), storedURIs)
+		assert.Empty(suite.T(), crawl.Links)
 	})
 
 	suite.Suite.T().Run("should return empty slice when try to find URIs stored", func(t *testing.T) {
-		storedURIs, err := suite.repository.Find(ctx, uri, depth)
+		crawl, err := suite.repository.Find(ctx, uri, depth, crawler.Options{})
 
 		assert.EqualError(suite.T(), err, mongo.ErrNoDocuments.Error())
-		assert.Empty(suite.T(), storedURIs)
+		assert.Empty(suite.T(), crawl.Links)
 	})
 
 	suite.Suite.T().Run("should return stored URIs with success", func(t *testing.T) {
-		dataPage := pageDataInfo{URI: uri, Depth: depth, URIs: uris}
-		_, err := suite.repository.getCollection().InsertOne(ctx, dataPage)
+		err := suite.repository.Insert(ctx, crawler.Crawl{URI: uri, Depth: depth, Links: uris})
+		assert.NoError(suite.T(), err)
+
+		crawl, err := suite.repository.Find(ctx, uri, depth, crawler.Options{})
+
+		assert.NoError(suite.T(), err)
+		assert.ElementsMatch(suite.T(), uris, crawl.Links)
+	})
+
+	suite.Suite.T().Run("should return stored pages with redirect chain and certificates", func(t *testing.T) {
+		redirectedURI := "http://redirected-crawler.com"
+		stored := crawler.Crawl{
+			URI:       redirectedURI,
+			Depth:     depth,
+			CrawledAt: time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+			Links:     uris,
+			Pages: []crawler.Page{{
+				URI:        redirectedURI,
+				StatusCode: http.StatusOK,
+				Redirects: []pager.Redirect{
+					{URI: redirectedURI, StatusCode: http.StatusMovedPermanently, Location: "/home"},
+				},
+			}},
+			Certificates: []pager.Certificate{{
+				Host:      "redirected-crawler.com",
+				Subject:   "CN=redirected-crawler.com",
+				Issuer:    "CN=Internal CA",
+				NotBefore: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+				NotAfter:  time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+				SANs:      []string{"redirected-crawler.com"},
+			}},
+		}
+		err := suite.repository.Insert(ctx, stored)
+		assert.NoError(suite.T(), err)
+
+		crawl, err := suite.repository.Find(ctx, redirectedURI, depth, crawler.Options{})
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), stored, crawl)
+	})
+
+	suite.Suite.T().Run("should return stored URIs only for the same authentication profile", func(t *testing.T) {
+		authenticatedURI := "http://authenticated-crawler.com"
+		opts := crawler.Options{AuthProfile: "staging"}
+		err := suite.repository.Insert(ctx, crawler.Crawl{URI: authenticatedURI, Depth: depth, Options: opts, Links: uris})
+		assert.NoError(suite.T(), err)
+
+		crawl, err := suite.repository.Find(ctx, authenticatedURI, depth, opts)
+		assert.NoError(suite.T(), err)
+		assert.ElementsMatch(suite.T(), uris, crawl.Links)
+
+		_, err = suite.repository.Find(ctx, authenticatedURI, depth, crawler.Options{})
+		assert.EqualError(suite.T(), err, mongo.ErrNoDocuments.Error())
+	})
+
+	suite.Suite.T().Run("should return the latest crawl when the URI was crawled again", func(t *testing.T) {
+		recrawledURI := "http://recrawled-crawler.com"
+		err := suite.repository.Insert(ctx, crawler.Crawl{URI: recrawledURI, Depth: depth, Links: uris})
+		assert.NoError(suite.T(), err)
+		latest := []string{"http://another-subcrawler.com"}
+		err = suite.repository.Insert(ctx, crawler.Crawl{URI: recrawledURI, Depth: depth, Links: latest})
+		assert.NoError(suite.T(), err)
+
+		crawl, err := suite.repository.Find(ctx, recrawledURI, depth, crawler.Options{})
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), latest, crawl.Links)
+	})
+}
+
+func (suite *MongodbRepositoryIntegrationTestSuite) TestNormalizedSchema() {
+	ctx := context.Background()
+	uri := "http://normalized-crawler.com"
+	aboutURI := "http://normalized-crawler.com/about"
+	crawl := crawler.Crawl{
+		URI:   uri,
+		Depth: 2,
+		Links: []string{aboutURI, "http://external.com"},
+		Hops:  map[string]uint{aboutURI: 1, "http://external.com": 2},
+		Pages: []crawler.Page{
+			{URI: uri, Depth: 0, StatusCode: http.StatusOK, Links: []string{aboutURI}},
+			{URI: aboutURI, Depth: 1, StatusCode: http.StatusOK, Links: []string{uri, "http://external.com"}},
+		},
+	}
+	assert.NoError(suite.T(), suite.repository.Insert(ctx, crawl))
+
+	current := crawlInfo{}
+	err := suite.repository.getCollection().FindOne(ctx, bson.D{{Key: "uri", Value: uri}}).Decode(&current)
+	assert.NoError(suite.T(), err)
+
+	suite.Suite.T().Run("should store crawl metadata without pages and links", func(t *testing.T) {
+		assert.Equal(suite.T(), 2, current.Links)
+		assert.Equal(suite.T(), 2, current.Pages)
+		assert.True(suite.T(), current.Hops)
+	})
+
+	suite.Suite.T().Run("should store one document for each page and link of the crawl", func(t *testing.T) {
+		byCrawl := bson.D{{Key: "crawl_id", Value: current.CrawlID}}
+
+		pages, err := suite.repository.getPagesCollection().CountDocuments(ctx, byCrawl)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), int64(2), pages)
+
+		link := linkInfo{}
+		err = suite.repository.getLinksCollection().
+			FindOne(ctx, bson.D{{Key: "crawl_id", Value: current.CrawlID}, {Key: "uri", Value: "http://external.com"}}).
+			Decode(&link)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), aboutURI, link.Source)
+		assert.Equal(suite.T(), uint(2), link.Hop)
+	})
+
+	suite.Suite.T().Run("should rebuild the crawl from its pages and links", func(t *testing.T) {
+		found, err := suite.repository.Find(ctx, uri, 2, crawler.Options{})
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), crawl, found)
+	})
+
+	suite.Suite.T().Run("should remove pages and links of pruned snapshots", func(t *testing.T) {
+		viper.Set("CRAWL_SNAPSHOT_RETENTION", 1)
+		defer viper.Set("CRAWL_SNAPSHOT_RETENTION", nil)
+
+		assert.NoError(suite.T(), suite.repository.Insert(ctx, crawl))
+
+		byCrawl := bson.D{{Key: "crawl_id", Value: current.CrawlID}}
+		pages, err := suite.repository.getPagesCollection().CountDocuments(ctx, byCrawl)
+		assert.NoError(suite.T(), err)
+		assert.Zero(suite.T(), pages)
+		links, err := suite.repository.getLinksCollection().CountDocuments(ctx, byCrawl)
+		assert.NoError(suite.T(), err)
+		assert.Zero(suite.T(), links)
+	})
+}
+
+func (suite *MongodbRepositoryIntegrationTestSuite) TestFindByHops() {
+	ctx := context.Background()
+	uri := "http://hops-crawler.com"
+	internalURI := "http://hops-crawler.com/internal"
+	deepURI := "http://hops-crawler.com/deep"
+	deep := crawler.Crawl{
+		URI:   uri,
+		Depth: 3,
+		Links: []string{internalURI, deepURI},
+		Hops:  map[string]uint{internalURI: 1, deepURI: 2},
+		Pages: []crawler.Page{{URI: uri, Depth: 0}, {URI: internalURI, Depth: 1}, {URI: deepURI, Depth: 2}},
+	}
+	assert.NoError(suite.T(), suite.repository.Insert(ctx, deep))
+
+	suite.Suite.T().Run("should serve shallower request from deeper crawl", func(t *testing.T) {
+		crawl, err := suite.repository.Find(ctx, uri, 1, crawler.Options{})
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), uint(1), crawl.Depth)
+		assert.Equal(suite.T(), []string{internalURI}, crawl.Links)
+		assert.Len(suite.T(), crawl.Pages, 1)
+	})
+
+	suite.Suite.T().Run("should not serve shallower request from deeper crawl without hops", func(t *testing.T) {
+		legacyURI := "http://legacy-hops-crawler.com"
+		err := suite.repository.Insert(ctx, crawler.Crawl{URI: legacyURI, Depth: 3, Links: []string{internalURI}})
+		assert.NoError(suite.T(), err)
+
+		_, err = suite.repository.Find(ctx, legacyURI, 1, crawler.Options{})
+
+		assert.EqualError(suite.T(), err, mongo.ErrNoDocuments.Error())
+	})
+
+	suite.Suite.T().Run("should return deepest shallower crawl to be extended", func(t *testing.T) {
+		shallow := deep.Truncate(1)
+		assert.NoError(suite.T(), suite.repository.Insert(ctx, shallow))
+
+		crawl, err := suite.repository.FindShallower(ctx, uri, 5, crawler.Options{})
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), deep, crawl)
+	})
+
+	suite.Suite.T().Run("should return error when there is no shallower crawl", func(t *testing.T) {
+		_, err := suite.repository.FindShallower(ctx, uri, 1, crawler.Options{})
+
+		assert.EqualError(suite.T(), err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+func (suite *MongodbRepositoryIntegrationTestSuite) TestSnapshots() {
+	ctx := context.Background()
+	uri := "http://snapshot-crawler.com"
+	first := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	viper.Set("CRAWL_SNAPSHOT_RETENTION", 2)
+	defer viper.Set("CRAWL_SNAPSHOT_RETENTION", nil)
+
+	for day := range 3 {
+		crawl := crawler.Crawl{
+			URI:       uri,
+			Depth:     1,
+			CrawledAt: first.AddDate(0, 0, day),
+			Links:     []string{fmt.Sprintf("http://snapshot-crawler.com/%d", day)},
+		}
+		assert.NoError(suite.T(), suite.repository.Insert(ctx, crawl))
+	}
+
+	suite.Suite.T().Run("should list the retained snapshots newest first", func(t *testing.T) {
+		snapshots, err := suite.repository.History(ctx, uri, crawler.Options{})
+
+		assert.NoError(suite.T(), err)
+		assert.Len(suite.T(), snapshots, 2)
+		assert.Equal(suite.T(), first.AddDate(0, 0, 2), snapshots[0].CrawledAt)
+		assert.Equal(suite.T(), first.AddDate(0, 0, 1), snapshots[1].CrawledAt)
+		assert.Equal(suite.T(), 1, snapshots[0].Links)
+	})
+
+	suite.Suite.T().Run("should return the crawl stored by a snapshot", func(t *testing.T) {
+		snapshots, err := suite.repository.History(ctx, uri, crawler.Options{})
+		assert.NoError(suite.T(), err)
+
+		crawl, err := suite.repository.FindSnapshot(ctx, snapshots[1].ID)
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), []string{"http://snapshot-crawler.com/1"}, crawl.Links)
+	})
+
+	suite.Suite.T().Run("should return not found when snapshot does not exist", func(t *testing.T) {
+		_, err := suite.repository.FindSnapshot(ctx, "65a0f0f0f0f0f0f0f0f0f0f1")
+		assert.ErrorIs(suite.T(), err, crawler.ErrSnapshotNotFound)
+
+		_, err = suite.repository.FindSnapshot(ctx, "invalid")
+		assert.ErrorIs(suite.T(), err, crawler.ErrSnapshotNotFound)
+	})
+
+	suite.Suite.T().Run("should keep the latest crawl as the current one", func(t *testing.T) {
+		crawl, err := suite.repository.Find(ctx, uri, 1, crawler.Options{})
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), []string{"http://snapshot-crawler.com/2"}, crawl.Links)
+	})
+}
+
+func (suite *MongodbRepositoryIntegrationTestSuite) TestInsertDiff() {
+	ctx := context.Background()
+	diff := crawler.CrawlDiff{
+		URI:          "http://diff-crawler.com",
+		Depth:        1,
+		CreatedAt:    time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		AddedPages:   []string{"http://diff-crawler.com/new"},
+		RemovedPages: []string{"http://diff-crawler.com/old"},
+		ChangedPages: []string{"http://diff-crawler.com"},
+		AddedLinks:   []string{"http://diff-crawler.com/new"},
+		RemovedLinks: []string{"http://diff-crawler.com/old"},
+	}
+
+	suite.Suite.T().Run("should insert crawl diff with success", func(t *testing.T) {
+		err := suite.repository.InsertDiff(ctx, diff)
+		assert.NoError(suite.T(), err)
+
+		stored := crawlDiffInfo{}
+		err = suite.repository.getDiffCollection().FindOne(ctx, bson.D{{Key: "uri", Value: diff.URI}}).Decode(&stored)
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), newCrawlDiffInfo(diff), stored)
+	})
+}
+
+func (suite *MongodbRepositoryIntegrationTestSuite) TestFindPage() {
+	ctx := context.Background()
+	uri := "http://page-crawler.com"
+	pageURI := "http://page-crawler.com/about"
+
+	suite.Suite.T().Run("should return error when page was never stored", func(t *testing.T) {
+		_, err := suite.repository.FindPage(ctx, pageURI, crawler.Options{})
+
+		assert.EqualError(suite.T(), err, mongo.ErrNoDocuments.Error())
+	})
+
+	suite.Suite.T().Run("should return the page stored by the latest crawl", func(t *testing.T) {
+		first := crawler.Crawl{URI: uri, Depth: 1, Pages: []crawler.Page{{URI: pageURI, ETag: `"v1"`}}}
+		latest := crawler.Crawl{URI: uri, Depth: 2, Pages: []crawler.Page{
+			{URI: uri, ETag: `"home"`},
+			{URI: pageURI, ETag: `"v2"`, ContentHash: "hash", Links: []string{"http://subcrawler.com"}},
+		}}
+		assert.NoError(suite.T(), suite.repository.Insert(ctx, first))
+		assert.NoError(suite.T(), suite.repository.Insert(ctx, latest))
+
+		page, err := suite.repository.FindPage(ctx, pageURI, crawler.Options{})
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), `"v2"`, page.ETag)
+		assert.Equal(suite.T(), "hash", page.ContentHash)
+		assert.Equal(suite.T(), []string{"http://subcrawler.com"}, page.Links)
+	})
+}
+
+func (suite *MongodbRepositoryIntegrationTestSuite) TestMigrations() {
+	ctx := context.Background()
+	defer suite.defaultDBEnviroments()
+	viper.Set("MONGODB_COLLECTION", "migration_crawls_test")
+	viper.Set("MONGODB_PAGES_COLLECTION", "migration_pages_test")
+	viper.Set("MONGODB_LINKS_COLLECTION", "migration_links_test")
+	viper.Set("MONGODB_SNAPSHOT_COLLECTION", "migration_snapshots_test")
+	viper.Set("MONGODB_LEGACY_COLLECTION", "migration_legacy_test")
+	viper.Set("MONGODB_MIGRATIONS_COLLECTION", "migration_schema_test")
+
+	uri := "http://legacy-crawler.com"
+	aboutURI := "http://legacy-crawler.com/about"
+	legacy := crawler.Crawl{
+		URI:       uri,
+		Depth:     2,
+		CrawledAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Links:     []string{aboutURI},
+		Hops:      map[string]uint{aboutURI: 1},
+		Pages:     []crawler.Page{{URI: uri, StatusCode: http.StatusOK, Links: []string{aboutURI}}},
+	}
+	_, err := suite.repository.getLegacyCollection().InsertOne(ctx, newEmbeddedCrawlInfo(legacy))
+	assert.NoError(suite.T(), err)
+	_, err = suite.repository.getSnapshotCollection().InsertOne(ctx, newEmbeddedCrawlInfo(legacy))
+	assert.NoError(suite.T(), err)
+
+	migrator := NewMongodbMigrator(suite.repository)
+
+	suite.Suite.T().Run("should only list pending migrations on dry run", func(t *testing.T) {
+		pending, err := migrator.Up(ctx, true)
+
 		assert.NoError(suite.T(), err)
+		assert.Len(suite.T(), pending, len(migrations))
+		count, err := suite.repository.getLegacyCollection().CountDocuments(ctx, bson.D{})
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), int64(1), count)
+	})
+
+	suite.Suite.T().Run("should move legacy crawls and snapshots to the normalized schema", func(t *testing.T) {
+		applied, err := migrator.Up(ctx, false)
+		assert.NoError(suite.T(), err)
+		assert.Len(suite.T(), applied, len(migrations))
 
-		storedURIs, err := suite.repository.Find(ctx, uri, depth)
+		crawl, err := suite.repository.Find(ctx, uri, 2, crawler.Options{})
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), legacy, crawl)
+
+		snapshots, err := suite.repository.History(ctx, uri, crawler.Options{})
+		assert.NoError(suite.T(), err)
+		assert.Len(suite.T(), snapshots, 1)
+		snapshot, err := suite.repository.FindSnapshot(ctx, snapshots[0].ID)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), legacy, snapshot)
+
+		count, err := suite.repository.getLegacyCollection().CountDocuments(ctx, bson.D{})
+		assert.NoError(suite.T(), err)
+		assert.Zero(suite.T(), count)
+	})
+
+	suite.Suite.T().Run("should not apply migrations twice", func(t *testing.T) {
+		applied, err := migrator.Up(ctx, false)
+		assert.NoError(suite.T(), err)
+		assert.Empty(suite.T(), applied)
 
+		statuses, err := migrator.Status(ctx)
 		assert.NoError(suite.T(), err)
-		assert.ElementsMatch(suite.T(), uris, storedURIs)
+		for _, status := range statuses {
+			assert.True(suite.T(), status.Applied)
+		}
+	})
+
+	suite.Suite.T().Run("should move crawls back to the legacy schema", func(t *testing.T) {
+		reverted, err := migrator.Down(ctx, 1, false)
+		assert.NoError(suite.T(), err)
+		assert.Len(suite.T(), reverted, 1)
+
+		embedded := embeddedCrawlInfo{}
+		err = suite.repository.getLegacyCollection().FindOne(ctx, bson.D{{Key: "uri", Value: uri}}).Decode(&embedded)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), legacy, embedded.toCrawl())
+
+		_, err = suite.repository.Find(ctx, uri, 2, crawler.Options{})
+		assert.EqualError(suite.T(), err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+func (suite *MongodbRepositoryIntegrationTestSuite) TestEnsureTTLIndexes() {
+	ctx := context.Background()
+	collections := []*mongo.Collection{
+		suite.repository.getCollection(),
+		suite.repository.getSnapshotCollection(),
+		suite.repository.getPagesCollection(),
+		suite.repository.getLinksCollection(),
+		suite.repository.getDiffCollection(),
+	}
+	expireAfter := func(collection *mongo.Collection) (int32, bool) {
+		specifications, err := collection.Indexes().ListSpecifications(ctx)
+		assert.NoError(suite.T(), err)
+		for _, specification := range specifications {
+			if specification.Name == ttlIndexName && specification.ExpireAfterSeconds != nil {
+				return *specification.ExpireAfterSeconds, true
+			}
+		}
+
+		return 0, false
+	}
+
+	suite.Suite.T().Run("should create TTL index on the timestamp of every collection of the crawl", func(t *testing.T) {
+		err := suite.repository.ensureTTLIndexes(ctx, time.Hour)
+
+		assert.NoError(suite.T(), err)
+		for _, collection := range collections {
+			seconds, found := expireAfter(collection)
+			assert.True(suite.T(), found, collection.Name())
+			assert.Equal(suite.T(), int32(3600), seconds, collection.Name())
+		}
+	})
+
+	suite.Suite.T().Run("should update TTL index when expiration changes", func(t *testing.T) {
+		err := suite.repository.ensureTTLIndexes(ctx, 2*time.Hour)
+
+		assert.NoError(suite.T(), err)
+		for _, collection := range collections {
+			seconds, _ := expireAfter(collection)
+			assert.Equal(suite.T(), int32(7200), seconds, collection.Name())
+		}
+	})
+
+	suite.Suite.T().Run("should drop TTL index when expiration is disabled", func(t *testing.T) {
+		assert.NoError(suite.T(), suite.repository.ensureTTLIndexes(ctx, 0))
+		assert.NoError(suite.T(), suite.repository.ensureTTLIndexes(ctx, 0))
+
+		for _, collection := range collections {
+			_, found := expireAfter(collection)
+			assert.False(suite.T(), found, collection.Name())
+		}
 	})
 }
 
 func (suite *MongodbRepositoryIntegrationTestSuite) defaultDBEnviroments() {
 	viper.Set("MONGODB_DATABASE", "database_test")
 	viper.Set("MONGODB_COLLECTION", "collection_test")
+	viper.Set("MONGODB_PAGES_COLLECTION", "pages_collection_test")
+	viper.Set("MONGODB_LINKS_COLLECTION", "links_collection_test")
+	viper.Set("MONGODB_DIFF_COLLECTION", "diff_collection_test")
+	viper.Set("MONGODB_SNAPSHOT_COLLECTION", "snapshot_collection_test")
+	viper.Set("MONGODB_LEGACY_COLLECTION", "legacy_collection_test")
+	viper.Set("MONGODB_MIGRATIONS_COLLECTION", "migrations_collection_test")
+	viper.Set("MONGODB_ARCHIVE_BUCKET", "bodies_test")
 }
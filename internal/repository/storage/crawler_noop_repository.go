@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+)
+
+// CrawlerNoopRepository stores nothing, so every crawl is made again and there is no history.
+type CrawlerNoopRepository struct{}
+
+func NewCrawlerNoopRepository() CrawlerNoopRepository {
+	return CrawlerNoopRepository{}
+}
+
+func (CrawlerNoopRepository) Insert(context.Context, crawler.Crawl) error {
+	return nil
+}
+
+func (CrawlerNoopRepository) Find(context.Context, string, uint, crawler.Options) (crawler.Crawl, error) {
+	return crawler.Crawl{}, ErrNotStored
+}
+
+func (CrawlerNoopRepository) FindShallower(context.Context, string, uint, crawler.Options) (crawler.Crawl, error) {
+	return crawler.Crawl{}, ErrNotStored
+}
+
+func (CrawlerNoopRepository) FindPage(context.Context, string, crawler.Options) (crawler.Page, error) {
+	return crawler.Page{}, ErrNotStored
+}
+
+func (CrawlerNoopRepository) InsertDiff(context.Context, crawler.CrawlDiff) error {
+	return nil
+}
+
+func (CrawlerNoopRepository) History(context.Context, string, crawler.Options) ([]crawler.Snapshot, error) {
+	return []crawler.Snapshot{}, nil
+}
+
+func (CrawlerNoopRepository) FindSnapshot(context.Context, string) (crawler.Crawl, error) {
+	return crawler.Crawl{}, crawler.ErrSnapshotNotFound
+}
@@ -0,0 +1,702 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
+	_ "github.com/lib/pq" // registers the postgres driver
+	"github.com/spf13/viper"
+	_ "modernc.org/sqlite" // registers the sqlite driver
+)
+
+// sqlDialect holds what differs between the SQL databases supported.
+type sqlDialect struct {
+	driver   string
+	identity string
+	numbered bool
+}
+
+var (
+	sqliteDialect   = sqlDialect{driver: "sqlite", identity: "INTEGER PRIMARY KEY AUTOINCREMENT"}
+	postgresDialect = sqlDialect{driver: "postgres", identity: "BIGSERIAL PRIMARY KEY", numbered: true}
+)
+
+// rebind replaces the ? placeholders by the numbered ones when the database asks for them.
+func (d sqlDialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	builder := strings.Builder{}
+	position := 0
+	for _, char := range query {
+		if char != '?' {
+			builder.WriteRune(char)
+
+			continue
+		}
+		position++
+		builder.WriteString("$" + strconv.Itoa(position))
+	}
+
+	return builder.String()
+}
+
+func (d sqlDialect) schema() []string {
+	return []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS crawl_snapshots (
+			id %s,
+			uri TEXT NOT NULL,
+			depth BIGINT NOT NULL,
+			auth_profile TEXT NOT NULL,
+			created_at BIGINT NOT NULL,
+			links BIGINT NOT NULL,
+			pages BIGINT NOT NULL,
+			hops BOOLEAN NOT NULL,
+			certificates TEXT NOT NULL
+		)`, d.identity),
+		`CREATE INDEX IF NOT EXISTS crawl_snapshots_uri_options_depth_created_at
+			ON crawl_snapshots (uri, auth_profile, depth, created_at)`,
+		`CREATE TABLE IF NOT EXISTS crawl_pages (
+			crawl_id BIGINT NOT NULL,
+			position BIGINT NOT NULL,
+			auth_profile TEXT NOT NULL,
+			created_at BIGINT NOT NULL,
+			uri TEXT NOT NULL,
+			depth BIGINT NOT NULL,
+			status_code BIGINT NOT NULL,
+			redirects TEXT NOT NULL,
+			redirect_loop BOOLEAN NOT NULL,
+			error TEXT NOT NULL,
+			etag TEXT NOT NULL,
+			last_modified TEXT NOT NULL,
+			content_hash TEXT NOT NULL,
+			not_modified BOOLEAN NOT NULL,
+			links TEXT NOT NULL,
+			PRIMARY KEY (crawl_id, position)
+		)`,
+		`CREATE INDEX IF NOT EXISTS crawl_pages_uri_options_created_at ON crawl_pages (uri, auth_profile, created_at)`,
+		`CREATE TABLE IF NOT EXISTS crawl_links (
+			crawl_id BIGINT NOT NULL,
+			position BIGINT NOT NULL,
+			source TEXT NOT NULL,
+			uri TEXT NOT NULL,
+			hop BIGINT NOT NULL,
+			PRIMARY KEY (crawl_id, position)
+		)`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS crawl_diffs (
+			id %s,
+			uri TEXT NOT NULL,
+			depth BIGINT NOT NULL,
+			auth_profile TEXT NOT NULL,
+			created_at BIGINT NOT NULL,
+			first_crawl BOOLEAN NOT NULL,
+			added_pages TEXT NOT NULL,
+			removed_pages TEXT NOT NULL,
+			changed_pages TEXT NOT NULL,
+			added_links TEXT NOT NULL,
+			removed_links TEXT NOT NULL
+		)`, d.identity),
+	}
+}
+
+// sqlColumn is a column added to a table after it was first created, so it is added to the tables
+// created by previous versions when missing.
+type sqlColumn struct {
+	table      string
+	name       string
+	definition string
+}
+
+var addedColumns = []sqlColumn{
+	{table: "crawl_pages", name: "body_key", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "crawl_pages", name: "metadata", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "crawl_pages", name: "structured_data", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "crawl_pages", name: "fields", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "crawl_pages", name: "content", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "crawl_pages", name: "charset", definition: "TEXT NOT NULL DEFAULT ''"},
+}
+
+const snapshotColumns = "id, uri, depth, auth_profile, created_at, links, pages, hops, certificates"
+
+const pageColumns = `uri, depth, status_code, redirects, redirect_loop, error, etag, last_modified, content_hash,
+	not_modified, links, body_key, charset, metadata, structured_data, fields, content`
+
+// CrawlerSQLRepository stores the crawls in a SQL database, where every crawl is kept as a snapshot
+// and the latest one of each URI, depth and options is the current crawl.
+type CrawlerSQLRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// NewCrawlerSQLiteRepository opens the SQLite database in the given file, creating it when missing.
+func NewCrawlerSQLiteRepository(ctx context.Context, path string) (CrawlerSQLRepository, error) {
+	// A single connection avoids the database being locked by concurrent writes.
+	db, err := sql.Open(sqliteDialect.driver, fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)", path))
+	if err != nil {
+		return CrawlerSQLRepository{}, err
+	}
+	db.SetMaxOpenConns(1)
+
+	return newCrawlerSQLRepository(ctx, db, sqliteDialect)
+}
+
+// NewCrawlerPostgresRepository connects to the PostgreSQL database given by the connection string.
+func NewCrawlerPostgresRepository(ctx context.Context, dsn string) (CrawlerSQLRepository, error) {
+	db, err := sql.Open(postgresDialect.driver, dsn)
+	if err != nil {
+		return CrawlerSQLRepository{}, err
+	}
+
+	return newCrawlerSQLRepository(ctx, db, postgresDialect)
+}
+
+func newCrawlerSQLRepository(ctx context.Context, db *sql.DB, dialect sqlDialect) (CrawlerSQLRepository, error) {
+	repository := CrawlerSQLRepository{db: db, dialect: dialect}
+	if err := repository.Ping(ctx); err != nil {
+		log.Error("error reaching database", logger.FieldError(err))
+		_ = db.Close()
+
+		return CrawlerSQLRepository{}, err
+	}
+
+	for _, statement := range dialect.schema() {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			log.Error("error creating database schema", logger.FieldError(err))
+			_ = db.Close()
+
+			return CrawlerSQLRepository{}, err
+		}
+	}
+	for _, column := range addedColumns {
+		if err := repository.addColumn(ctx, column); err != nil {
+			log.Error("error adding column to database schema", logger.FieldError(err))
+			_ = db.Close()
+
+			return CrawlerSQLRepository{}, err
+		}
+	}
+
+	return repository, nil
+}
+
+// addColumn adds the column to its table unless it is already there.
+func (c CrawlerSQLRepository) addColumn(ctx context.Context, column sqlColumn) error {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", column.name, column.table)
+	if rows, err := c.db.QueryContext(ctx, query); err == nil {
+		return rows.Close()
+	}
+
+	_, err := c.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", column.table, column.name, column.definition))
+
+	return err
+}
+
+// Insert stores the crawl as a new snapshot along with its pages and links, making it the current crawl.
+func (c CrawlerSQLRepository) Insert(ctx context.Context, crawl crawler.Crawl) error {
+	certificates, err := json.Marshal(crawl.Certificates)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("error starting transaction", logger.FieldError(err))
+
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var crawlID int64
+	err = tx.QueryRowContext(ctx, c.dialect.rebind(`INSERT INTO crawl_snapshots
+		(uri, depth, auth_profile, created_at, links, pages, hops, certificates)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		crawl.URI, crawl.Depth, crawl.Options.AuthProfile, toNanos(crawl.CrawledAt),
+		len(crawl.Links), len(crawl.Pages), crawl.Hops != nil, string(certificates),
+	).Scan(&crawlID)
+	if err != nil {
+		log.Error("error while inserting crawl snapshot", logger.FieldError(err))
+
+		return err
+	}
+
+	if err := c.insertPages(ctx, tx, crawlID, crawl); err != nil {
+		log.Error("error while inserting pages", logger.FieldError(err))
+
+		return err
+	}
+	if err := c.insertLinks(ctx, tx, crawlID, crawl); err != nil {
+		log.Error("error while inserting links", logger.FieldError(err))
+
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Error("error committing crawl", logger.FieldError(err))
+
+		return err
+	}
+
+	return c.pruneSnapshots(ctx, crawl, crawlID, time.Now().UTC())
+}
+
+func (c CrawlerSQLRepository) insertPages(ctx context.Context, tx *sql.Tx, crawlID int64, crawl crawler.Crawl) error {
+	statement, err := tx.PrepareContext(ctx, c.dialect.rebind(`INSERT INTO crawl_pages
+		(crawl_id, position, auth_profile, created_at, `+pageColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for position, page := range crawl.Pages {
+		redirects, err := json.Marshal(page.Redirects)
+		if err != nil {
+			return err
+		}
+		links, err := json.Marshal(page.Links)
+		if err != nil {
+			return err
+		}
+		metadata, err := marshalOptional(page.Metadata.IsZero(), page.Metadata)
+		if err != nil {
+			return err
+		}
+		structuredData, err := marshalOptional(len(page.StructuredData) == 0, page.StructuredData)
+		if err != nil {
+			return err
+		}
+		fields, err := marshalOptional(len(page.Fields) == 0, page.Fields)
+		if err != nil {
+			return err
+		}
+		content, err := marshalOptional(page.Content.IsZero(), page.Content)
+		if err != nil {
+			return err
+		}
+
+		_, err = statement.ExecContext(ctx,
+			crawlID, position, crawl.Options.AuthProfile, toNanos(crawl.CrawledAt),
+			page.URI, page.Depth, page.StatusCode, string(redirects), page.RedirectLoop, page.Error,
+			page.ETag, page.LastModified, page.ContentHash, page.NotModified, string(links), page.BodyKey, page.Charset,
+			metadata, structuredData, fields, content,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c CrawlerSQLRepository) insertLinks(ctx context.Context, tx *sql.Tx, crawlID int64, crawl crawler.Crawl) error {
+	statement, err := tx.PrepareContext(ctx, c.dialect.rebind(`INSERT INTO crawl_links
+		(crawl_id, position, source, uri, hop) VALUES (?, ?, ?, ?, ?)`))
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	sources := linkSources(crawl)
+	for position, link := range crawl.Links {
+		if _, err := statement.ExecContext(ctx, crawlID, position, sources[link], link, crawl.Hops[link]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pruneSnapshots applies the retention policy to the snapshots of the same URI, depth and options, keeping
+// the newest ones up to the configured count and age, where zero means unlimited, along with the current one.
+func (c CrawlerSQLRepository) pruneSnapshots(ctx context.Context, crawl crawler.Crawl, current int64, now time.Time) error {
+	rows, err := c.db.QueryContext(ctx, c.dialect.rebind(`SELECT id, created_at FROM crawl_snapshots
+		WHERE uri = ? AND depth = ? AND auth_profile = ? AND id <> ?
+		ORDER BY created_at DESC, id DESC`),
+		crawl.URI, crawl.Depth, crawl.Options.AuthProfile, current,
+	)
+	if err != nil {
+		log.Error("error while listing crawl snapshots to prune", logger.FieldError(err))
+
+		return err
+	}
+	defer rows.Close()
+
+	maxAge := viper.GetDuration("CRAWL_SNAPSHOT_MAX_AGE")
+	retention := viper.GetInt("CRAWL_SNAPSHOT_RETENTION")
+	pruned := make([]int64, 0)
+	// The current snapshot is left out of the query, so it takes one of the places retained.
+	for kept := 1; rows.Next(); {
+		var id, createdAt int64
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			return err
+		}
+
+		expired := maxAge > 0 && fromNanos(createdAt).Before(now.Add(-maxAge))
+		if expired || (retention > 0 && kept >= retention) {
+			pruned = append(pruned, id)
+
+			continue
+		}
+		kept++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range pruned {
+		if err := c.deleteSnapshot(ctx, id); err != nil {
+			log.Error("error while pruning crawl snapshots", logger.FieldError(err))
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c CrawlerSQLRepository) deleteSnapshot(ctx context.Context, id int64) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, query := range []string{
+		"DELETE FROM crawl_pages WHERE crawl_id = ?",
+		"DELETE FROM crawl_links WHERE crawl_id = ?",
+		"DELETE FROM crawl_snapshots WHERE id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, c.dialect.rebind(query), id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Find returns the latest crawl of the URI made with the depth or, when it has hop distances stored,
+// with a greater depth truncated to the depth asked.
+func (c CrawlerSQLRepository) Find(ctx context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, error) {
+	query := `SELECT ` + snapshotColumns + ` FROM crawl_snapshots
+		WHERE uri = ? AND auth_profile = ? AND (depth = ? OR (depth > ? AND hops = ?))`
+	args := []any{uri, opts.AuthProfile, depth, depth, true}
+	query, args = notExpired(query, args)
+	query += ` ORDER BY created_at DESC, id DESC LIMIT 1`
+
+	snapshot, err := c.scanSnapshot(c.db.QueryRowContext(ctx, c.dialect.rebind(query), args...))
+	if err != nil {
+		log.Error("error while fetching crawl from database", logger.FieldError(err))
+
+		return crawler.Crawl{}, err
+	}
+
+	crawl, err := c.load(ctx, snapshot, depth)
+	if err != nil {
+		log.Error("error while fetching pages and links from database", logger.FieldError(err))
+
+		return crawler.Crawl{}, err
+	}
+
+	return crawl.Truncate(depth), nil
+}
+
+// FindShallower returns the deepest crawl of the URI made with a smaller depth that has hop distances
+// stored, so it can be extended to the depth.
+func (c CrawlerSQLRepository) FindShallower(ctx context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, error) {
+	query := `SELECT ` + snapshotColumns + ` FROM crawl_snapshots
+		WHERE uri = ? AND auth_profile = ? AND depth < ? AND hops = ?`
+	query, args := notExpired(query, []any{uri, opts.AuthProfile, depth, true})
+	query += ` ORDER BY depth DESC, created_at DESC, id DESC LIMIT 1`
+
+	snapshot, err := c.scanSnapshot(c.db.QueryRowContext(ctx, c.dialect.rebind(query), args...))
+	if err != nil {
+		return crawler.Crawl{}, err
+	}
+
+	return c.load(ctx, snapshot, snapshot.crawl.Depth)
+}
+
+// FindPage returns the page as stored by the latest crawl that fetched it with the same options.
+func (c CrawlerSQLRepository) FindPage(ctx context.Context, uri string, opts crawler.Options) (crawler.Page, error) {
+	query := `SELECT ` + pageColumns + ` FROM crawl_pages WHERE uri = ? AND auth_profile = ?
+		ORDER BY created_at DESC, crawl_id DESC LIMIT 1`
+
+	return scanPage(c.db.QueryRowContext(ctx, c.dialect.rebind(query), uri, opts.AuthProfile))
+}
+
+// InsertDiff stores the changes found by an incremental crawl in their own table.
+func (c CrawlerSQLRepository) InsertDiff(ctx context.Context, diff crawler.CrawlDiff) error {
+	changes := make([]any, 0)
+	for _, uris := range [][]string{diff.AddedPages, diff.RemovedPages, diff.ChangedPages, diff.AddedLinks, diff.RemovedLinks} {
+		encoded, err := json.Marshal(uris)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, string(encoded))
+	}
+
+	_, err := c.db.ExecContext(ctx, c.dialect.rebind(`INSERT INTO crawl_diffs
+		(uri, depth, auth_profile, created_at, first_crawl, added_pages, removed_pages, changed_pages, added_links, removed_links)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		append([]any{diff.URI, diff.Depth, diff.Options.AuthProfile, toNanos(diff.CreatedAt), diff.FirstCrawl}, changes...)...,
+	)
+	if err != nil {
+		log.Error("error while inserting crawl diff into database", logger.FieldError(err))
+
+		return err
+	}
+
+	return nil
+}
+
+// History lists the snapshots of the crawls of the URI made with the options, newest first.
+func (c CrawlerSQLRepository) History(ctx context.Context, uri string, opts crawler.Options) ([]crawler.Snapshot, error) {
+	query := `SELECT ` + snapshotColumns + ` FROM crawl_snapshots WHERE uri = ? AND auth_profile = ?
+		ORDER BY created_at DESC, id DESC`
+	rows, err := c.db.QueryContext(ctx, c.dialect.rebind(query), uri, opts.AuthProfile)
+	if err != nil {
+		log.Error("error while fetching crawl snapshots from database", logger.FieldError(err))
+
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make([]crawler.Snapshot, 0)
+	for rows.Next() {
+		snapshot, err := c.scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, crawler.Snapshot{
+			ID:        strconv.FormatInt(snapshot.id, 10),
+			URI:       snapshot.crawl.URI,
+			Depth:     snapshot.crawl.Depth,
+			Options:   snapshot.crawl.Options,
+			CrawledAt: snapshot.crawl.CrawledAt,
+			Links:     snapshot.links,
+		})
+	}
+
+	return snapshots, rows.Err()
+}
+
+// FindSnapshot returns the crawl stored by the snapshot with the given id.
+func (c CrawlerSQLRepository) FindSnapshot(ctx context.Context, id string) (crawler.Crawl, error) {
+	snapshotID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return crawler.Crawl{}, crawler.ErrSnapshotNotFound
+	}
+
+	query := `SELECT ` + snapshotColumns + ` FROM crawl_snapshots WHERE id = ?`
+	snapshot, err := c.scanSnapshot(c.db.QueryRowContext(ctx, c.dialect.rebind(query), snapshotID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return crawler.Crawl{}, crawler.ErrSnapshotNotFound
+	}
+	if err != nil {
+		log.Error("error while fetching crawl snapshot from database", logger.FieldError(err))
+
+		return crawler.Crawl{}, err
+	}
+
+	return c.load(ctx, snapshot, snapshot.crawl.Depth)
+}
+
+// sqlSnapshot is a crawl as stored by a snapshot, before its pages and links are read.
+type sqlSnapshot struct {
+	id    int64
+	links int
+	pages int
+	hops  bool
+	crawl crawler.Crawl
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (c CrawlerSQLRepository) scanSnapshot(row scanner) (sqlSnapshot, error) {
+	snapshot := sqlSnapshot{}
+	var createdAt int64
+	var certificates string
+	err := row.Scan(
+		&snapshot.id, &snapshot.crawl.URI, &snapshot.crawl.Depth, &snapshot.crawl.Options.AuthProfile, &createdAt,
+		&snapshot.links, &snapshot.pages, &snapshot.hops, &certificates,
+	)
+	if err != nil {
+		return sqlSnapshot{}, err
+	}
+	snapshot.crawl.CrawledAt = fromNanos(createdAt)
+	if err := json.Unmarshal([]byte(certificates), &snapshot.crawl.Certificates); err != nil {
+		return sqlSnapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// load reads the pages and links of the crawl in the order they were found, leaving out the ones beyond
+// the depth when a deeper crawl is read.
+func (c CrawlerSQLRepository) load(ctx context.Context, snapshot sqlSnapshot, depth uint) (crawler.Crawl, error) {
+	crawl := snapshot.crawl
+	if snapshot.hops {
+		crawl.Hops = make(map[string]uint, snapshot.links)
+	}
+
+	pagesQuery := `SELECT ` + pageColumns + ` FROM crawl_pages WHERE crawl_id = ?`
+	linksQuery := `SELECT uri, hop FROM crawl_links WHERE crawl_id = ?`
+	args := []any{snapshot.id}
+	if snapshot.hops && depth < crawl.Depth {
+		pagesQuery += ` AND depth < ?`
+		linksQuery += ` AND hop <= ?`
+		args = append(args, depth)
+	}
+
+	err := c.each(ctx, pagesQuery+` ORDER BY position`, args, func(row scanner) error {
+		page, err := scanPage(row)
+		crawl.Pages = append(crawl.Pages, page)
+
+		return err
+	})
+	if err != nil {
+		return crawler.Crawl{}, err
+	}
+
+	err = c.each(ctx, linksQuery+` ORDER BY position`, args, func(row scanner) error {
+		var link string
+		var hop uint
+		if err := row.Scan(&link, &hop); err != nil {
+			return err
+		}
+		crawl.Links = append(crawl.Links, link)
+		if snapshot.hops {
+			crawl.Hops[link] = hop
+		}
+
+		return nil
+	})
+	if err != nil {
+		return crawler.Crawl{}, err
+	}
+
+	return crawl, nil
+}
+
+// each scans the rows of the query one at a time, releasing the connection before returning so the
+// next query can take it.
+func (c CrawlerSQLRepository) each(ctx context.Context, query string, args []any, fn func(scanner) error) error {
+	rows, err := c.db.QueryContext(ctx, c.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func scanPage(row scanner) (crawler.Page, error) {
+	page := crawler.Page{}
+	var redirects, links, metadata, structuredData, fields, content string
+	err := row.Scan(
+		&page.URI, &page.Depth, &page.StatusCode, &redirects, &page.RedirectLoop, &page.Error,
+		&page.ETag, &page.LastModified, &page.ContentHash, &page.NotModified, &links, &page.BodyKey, &page.Charset,
+		&metadata, &structuredData, &fields, &content,
+	)
+	if err != nil {
+		return crawler.Page{}, err
+	}
+	if err := json.Unmarshal([]byte(redirects), &page.Redirects); err != nil {
+		return crawler.Page{}, err
+	}
+	if err := json.Unmarshal([]byte(links), &page.Links); err != nil {
+		return crawler.Page{}, err
+	}
+	if err := unmarshalOptional(metadata, &page.Metadata); err != nil {
+		return crawler.Page{}, err
+	}
+	if err := unmarshalOptional(structuredData, &page.StructuredData); err != nil {
+		return crawler.Page{}, err
+	}
+	if err := unmarshalOptional(fields, &page.Fields); err != nil {
+		return crawler.Page{}, err
+	}
+	if err := unmarshalOptional(content, &page.Content); err != nil {
+		return crawler.Page{}, err
+	}
+
+	return page, nil
+}
+
+// Ping checks that the database can be reached.
+func (c CrawlerSQLRepository) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// Close releases the connections to the database.
+func (c CrawlerSQLRepository) Close() error {
+	return c.db.Close()
+}
+
+// marshalOptional stores the value as JSON, or as an empty text when there is nothing to store.
+func marshalOptional(empty bool, value any) (string, error) {
+	if empty {
+		return "", nil
+	}
+
+	data, err := json.Marshal(value)
+
+	return string(data), err
+}
+
+func unmarshalOptional(data string, value any) error {
+	if data == "" {
+		return nil
+	}
+
+	return json.Unmarshal([]byte(data), value)
+}
+
+// notExpired leaves the crawls older than the TTL out of the query, keeping the ones stored without
+// timestamp as crawler.Crawl.Expired does.
+func notExpired(query string, args []any) (string, []any) {
+	ttl := viper.GetDuration("CRAWL_TTL")
+	if ttl <= 0 {
+		return query, args
+	}
+
+	return query + ` AND (created_at = 0 OR created_at >= ?)`, append(args, toNanos(time.Now().UTC().Add(-ttl)))
+}
+
+// toNanos stores the time as nanoseconds so it sorts the same way in every database, keeping the zero
+// time as zero.
+func toNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+func fromNanos(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos).UTC()
+}
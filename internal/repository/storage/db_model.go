@@ -1,7 +1,394 @@
 package storage
 
-type pageDataInfo struct {
-	URI   string   `bson:"uri"`
-	Depth uint     `bson:"depth"`
-	URIs  []string `bson:"uris"`
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// crawlInfo holds the metadata of a crawl, while its pages and links are stored in their own collections
+// referencing the version of the crawl they belong to by the crawl_id.
+type crawlInfo struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	CrawlID      primitive.ObjectID `bson:"crawl_id"`
+	URI          string             `bson:"uri"`
+	Depth        uint               `bson:"depth"`
+	AuthProfile  string             `bson:"auth_profile,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+	Links        int                `bson:"links"`
+	Pages        int                `bson:"pages"`
+	Hops         bool               `bson:"hops,omitempty"`
+	Certificates []certificateInfo  `bson:"certificates,omitempty"`
+}
+
+// linkInfo is the edge from the page where a link was first found to the link, keeping the order the
+// links were discovered and their hop distance. The links with no page fetched are stored without source.
+// The time of the crawl is kept so the links expire along with it.
+type linkInfo struct {
+	CrawlID   primitive.ObjectID `bson:"crawl_id"`
+	Position  int                `bson:"position"`
+	Source    string             `bson:"source,omitempty"`
+	URI       string             `bson:"uri"`
+	Hop       uint               `bson:"hop"`
+	CreatedAt time.Time          `bson:"created_at,omitempty"`
+}
+
+// crawlPageInfo is a page fetched by a crawl, carrying the options and time of the crawl so the latest
+// version of a page can be found without going through the crawls.
+type crawlPageInfo struct {
+	CrawlID     primitive.ObjectID `bson:"crawl_id"`
+	Position    int                `bson:"position"`
+	AuthProfile string             `bson:"auth_profile,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at,omitempty"`
+	pageInfo    `bson:",inline"`
+}
+
+type pageInfo struct {
+	URI            string               `bson:"uri"`
+	Depth          uint                 `bson:"depth"`
+	StatusCode     int                  `bson:"status_code"`
+	Redirects      []redirectInfo       `bson:"redirects,omitempty"`
+	RedirectLoop   bool                 `bson:"redirect_loop,omitempty"`
+	Error          string               `bson:"error,omitempty"`
+	ETag           string               `bson:"etag,omitempty"`
+	LastModified   string               `bson:"last_modified,omitempty"`
+	ContentHash    string               `bson:"content_hash,omitempty"`
+	BodyKey        string               `bson:"body_key,omitempty"`
+	Charset        string               `bson:"charset,omitempty"`
+	NotModified    bool                 `bson:"not_modified,omitempty"`
+	Links          []string             `bson:"links,omitempty"`
+	Metadata       *metadataInfo        `bson:"metadata,omitempty"`
+	StructuredData []structuredDataInfo `bson:"structured_data,omitempty"`
+	Fields         map[string][]string  `bson:"fields,omitempty"`
+	Content        *contentInfo         `bson:"content,omitempty"`
+}
+
+type metadataInfo struct {
+	Title       string            `bson:"title,omitempty"`
+	Description string            `bson:"description,omitempty"`
+	H1          []string          `bson:"h1,omitempty"`
+	H2          []string          `bson:"h2,omitempty"`
+	H3          []string          `bson:"h3,omitempty"`
+	Canonical   string            `bson:"canonical,omitempty"`
+	Icon        string            `bson:"icon,omitempty"`
+	Lang        string            `bson:"lang,omitempty"`
+	Alternates  []alternateInfo   `bson:"alternates,omitempty"`
+	OpenGraph   map[string]string `bson:"open_graph,omitempty"`
+	Twitter     map[string]string `bson:"twitter,omitempty"`
+}
+
+// structuredDataInfo keeps the normalized JSON of the item as text, so its keys, as @type, are kept as found.
+type structuredDataInfo struct {
+	Format string   `bson:"format"`
+	Types  []string `bson:"types,omitempty"`
+	Data   string   `bson:"data,omitempty"`
+	Error  string   `bson:"error,omitempty"`
+}
+
+type contentInfo struct {
+	Text           string `bson:"text,omitempty"`
+	WordCount      int    `bson:"word_count"`
+	ReadingMinutes int    `bson:"reading_minutes"`
+}
+
+type alternateInfo struct {
+	Lang string `bson:"lang"`
+	URI  string `bson:"uri"`
+}
+
+type crawlDiffInfo struct {
+	URI          string    `bson:"uri"`
+	Depth        uint      `bson:"depth"`
+	AuthProfile  string    `bson:"auth_profile,omitempty"`
+	CreatedAt    time.Time `bson:"created_at"`
+	FirstCrawl   bool      `bson:"first_crawl,omitempty"`
+	AddedPages   []string  `bson:"added_pages"`
+	RemovedPages []string  `bson:"removed_pages"`
+	ChangedPages []string  `bson:"changed_pages"`
+	AddedLinks   []string  `bson:"added_links"`
+	RemovedLinks []string  `bson:"removed_links"`
+}
+
+type certificateInfo struct {
+	Host      string    `bson:"host"`
+	Subject   string    `bson:"subject"`
+	Issuer    string    `bson:"issuer"`
+	NotBefore time.Time `bson:"not_before"`
+	NotAfter  time.Time `bson:"not_after"`
+	SANs      []string  `bson:"sans"`
+}
+
+type redirectInfo struct {
+	URI        string `bson:"uri"`
+	StatusCode int    `bson:"status_code"`
+	Location   string `bson:"location"`
+}
+
+func newCrawlInfo(crawl crawler.Crawl, crawlID primitive.ObjectID) crawlInfo {
+	var certificates []certificateInfo
+	for _, certificate := range crawl.Certificates {
+		certificates = append(certificates, certificateInfo(certificate))
+	}
+
+	return crawlInfo{
+		CrawlID:      crawlID,
+		URI:          crawl.URI,
+		Depth:        crawl.Depth,
+		AuthProfile:  crawl.Options.AuthProfile,
+		CreatedAt:    crawl.CrawledAt,
+		Links:        len(crawl.Links),
+		Pages:        len(crawl.Pages),
+		Hops:         crawl.Hops != nil,
+		Certificates: certificates,
+	}
+}
+
+func newCrawlPageInfos(crawl crawler.Crawl, crawlID primitive.ObjectID) []crawlPageInfo {
+	pages := make([]crawlPageInfo, 0, len(crawl.Pages))
+	for position, page := range crawl.Pages {
+		pages = append(pages, crawlPageInfo{
+			CrawlID:     crawlID,
+			Position:    position,
+			AuthProfile: crawl.Options.AuthProfile,
+			CreatedAt:   crawl.CrawledAt,
+			pageInfo:    newPageInfo(page),
+		})
+	}
+
+	return pages
+}
+
+func newLinkInfos(crawl crawler.Crawl, crawlID primitive.ObjectID) []linkInfo {
+	sources := linkSources(crawl)
+	links := make([]linkInfo, 0, len(crawl.Links))
+	for position, link := range crawl.Links {
+		links = append(links, linkInfo{
+			CrawlID:   crawlID,
+			Position:  position,
+			Source:    sources[link],
+			URI:       link,
+			Hop:       crawl.Hops[link],
+			CreatedAt: crawl.CrawledAt,
+		})
+	}
+
+	return links
+}
+
+// linkSources takes as the source of each link the first page, in the order they were fetched, linking to it.
+func linkSources(crawl crawler.Crawl) map[string]string {
+	sources := make(map[string]string)
+	for _, page := range crawl.Pages {
+		for _, link := range page.Links {
+			if _, found := sources[link]; !found {
+				sources[link] = page.URI
+			}
+		}
+	}
+
+	return sources
+}
+
+func (c crawlInfo) toCrawl(pages []crawlPageInfo, links []linkInfo) crawler.Crawl {
+	crawl := crawler.Crawl{
+		URI:       c.URI,
+		Depth:     c.Depth,
+		Options:   crawler.Options{AuthProfile: c.AuthProfile},
+		CrawledAt: c.CreatedAt,
+	}
+	if c.Hops {
+		crawl.Hops = make(map[string]uint, len(links))
+	}
+
+	for _, link := range links {
+		crawl.Links = append(crawl.Links, link.URI)
+		if c.Hops {
+			crawl.Hops[link.URI] = link.Hop
+		}
+	}
+	for _, page := range pages {
+		crawl.Pages = append(crawl.Pages, page.toPage())
+	}
+	for _, certificate := range c.Certificates {
+		crawl.Certificates = append(crawl.Certificates, pager.Certificate(certificate))
+	}
+
+	return crawl
+}
+
+func (c crawlInfo) toSnapshot() crawler.Snapshot {
+	return crawler.Snapshot{
+		ID:        c.CrawlID.Hex(),
+		URI:       c.URI,
+		Depth:     c.Depth,
+		Options:   crawler.Options{AuthProfile: c.AuthProfile},
+		CrawledAt: c.CreatedAt,
+		Links:     c.Links,
+	}
+}
+
+func newPageInfo(page crawler.Page) pageInfo {
+	var redirects []redirectInfo
+	for _, redirect := range page.Redirects {
+		redirects = append(redirects, redirectInfo(redirect))
+	}
+
+	return pageInfo{
+		URI:            page.URI,
+		Depth:          page.Depth,
+		StatusCode:     page.StatusCode,
+		Redirects:      redirects,
+		RedirectLoop:   page.RedirectLoop,
+		Error:          page.Error,
+		ETag:           page.ETag,
+		LastModified:   page.LastModified,
+		ContentHash:    page.ContentHash,
+		BodyKey:        page.BodyKey,
+		Charset:        page.Charset,
+		NotModified:    page.NotModified,
+		Links:          page.Links,
+		Metadata:       newMetadataInfo(page.Metadata),
+		StructuredData: newStructuredDataInfo(page.StructuredData),
+		Fields:         page.Fields,
+		Content:        newContentInfo(page.Content),
+	}
+}
+
+func newContentInfo(content crawler.Content) *contentInfo {
+	if content.IsZero() {
+		return nil
+	}
+	info := contentInfo(content)
+
+	return &info
+}
+
+func (c *contentInfo) toContent() crawler.Content {
+	if c == nil {
+		return crawler.Content{}
+	}
+
+	return crawler.Content(*c)
+}
+
+func newStructuredDataInfo(items []crawler.StructuredData) []structuredDataInfo {
+	var infos []structuredDataInfo
+	for _, item := range items {
+		infos = append(infos, structuredDataInfo{
+			Format: item.Format,
+			Types:  item.Types,
+			Data:   string(item.Data),
+			Error:  item.Error,
+		})
+	}
+
+	return infos
+}
+
+func toStructuredData(infos []structuredDataInfo) []crawler.StructuredData {
+	var items []crawler.StructuredData
+	for _, info := range infos {
+		item := crawler.StructuredData{Format: info.Format, Types: info.Types, Error: info.Error}
+		if info.Data != "" {
+			item.Data = json.RawMessage(info.Data)
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
+
+func newMetadataInfo(metadata crawler.Metadata) *metadataInfo {
+	if metadata.IsZero() {
+		return nil
+	}
+
+	var alternates []alternateInfo
+	for _, alternate := range metadata.Alternates {
+		alternates = append(alternates, alternateInfo(alternate))
+	}
+
+	return &metadataInfo{
+		Title:       metadata.Title,
+		Description: metadata.Description,
+		H1:          metadata.H1,
+		H2:          metadata.H2,
+		H3:          metadata.H3,
+		Canonical:   metadata.Canonical,
+		Icon:        metadata.Icon,
+		Lang:        metadata.Lang,
+		Alternates:  alternates,
+		OpenGraph:   metadata.OpenGraph,
+		Twitter:     metadata.Twitter,
+	}
+}
+
+func (m *metadataInfo) toMetadata() crawler.Metadata {
+	if m == nil {
+		return crawler.Metadata{}
+	}
+
+	var alternates []crawler.Alternate
+	for _, alternate := range m.Alternates {
+		alternates = append(alternates, crawler.Alternate(alternate))
+	}
+
+	return crawler.Metadata{
+		Title:       m.Title,
+		Description: m.Description,
+		H1:          m.H1,
+		H2:          m.H2,
+		H3:          m.H3,
+		Canonical:   m.Canonical,
+		Icon:        m.Icon,
+		Lang:        m.Lang,
+		Alternates:  alternates,
+		OpenGraph:   m.OpenGraph,
+		Twitter:     m.Twitter,
+	}
+}
+
+func (p pageInfo) toPage() crawler.Page {
+	var redirects []pager.Redirect
+	for _, redirect := range p.Redirects {
+		redirects = append(redirects, pager.Redirect(redirect))
+	}
+
+	return crawler.Page{
+		URI:            p.URI,
+		Depth:          p.Depth,
+		StatusCode:     p.StatusCode,
+		Redirects:      redirects,
+		RedirectLoop:   p.RedirectLoop,
+		Error:          p.Error,
+		ETag:           p.ETag,
+		LastModified:   p.LastModified,
+		ContentHash:    p.ContentHash,
+		BodyKey:        p.BodyKey,
+		Charset:        p.Charset,
+		NotModified:    p.NotModified,
+		Links:          p.Links,
+		Metadata:       p.Metadata.toMetadata(),
+		StructuredData: toStructuredData(p.StructuredData),
+		Fields:         p.Fields,
+		Content:        p.Content.toContent(),
+	}
+}
+
+func newCrawlDiffInfo(diff crawler.CrawlDiff) crawlDiffInfo {
+	return crawlDiffInfo{
+		URI:          diff.URI,
+		Depth:        diff.Depth,
+		AuthProfile:  diff.Options.AuthProfile,
+		CreatedAt:    diff.CreatedAt,
+		FirstCrawl:   diff.FirstCrawl,
+		AddedPages:   diff.AddedPages,
+		RemovedPages: diff.RemovedPages,
+		ChangedPages: diff.ChangedPages,
+		AddedLinks:   diff.AddedLinks,
+		RemovedLinks: diff.RemovedLinks,
+	}
 }
@@ -0,0 +1,428 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
+	"github.com/hiago-balbino/web-crawler/v2/internal/pkg/logger"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// Migration is a versioned change of the schema of the stored crawls. Both directions are idempotent,
+// so a migration interrupted halfway can be run again.
+type Migration struct {
+	Version     int
+	Description string
+	up          func(ctx context.Context, repository CrawlerMongodbRepository) error
+	down        func(ctx context.Context, repository CrawlerMongodbRepository) error
+}
+
+// MigrationStatus tells whether a migration was applied to the database and when.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// migrations lists every migration in the order they are applied.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "move the pages and links of the crawls and snapshots into their own collections",
+		up:          normalizeCrawls,
+		down:        embedCrawls,
+	},
+}
+
+type migrationInfo struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// embeddedCrawlInfo is the format the crawls and snapshots were stored before the pages and links got
+// their own collections, embedded in a single document.
+type embeddedCrawlInfo struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	URI          string             `bson:"uri"`
+	Depth        uint               `bson:"depth"`
+	AuthProfile  string             `bson:"auth_profile,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+	URIs         []string           `bson:"uris"`
+	LinkHops     []linkHopInfo      `bson:"link_hops"`
+	Pages        []pageInfo         `bson:"pages,omitempty"`
+	Certificates []certificateInfo  `bson:"certificates,omitempty"`
+}
+
+// linkHopInfo keeps the hop distance of each link as a list, given that URIs are not suitable as keys.
+type linkHopInfo struct {
+	URI string `bson:"uri"`
+	Hop uint   `bson:"hop"`
+}
+
+// MongodbMigrator applies the migrations to the database, tracking the ones applied in their own collection.
+type MongodbMigrator struct {
+	repository CrawlerMongodbRepository
+	migrations []Migration
+}
+
+func NewMongodbMigrator(repository CrawlerMongodbRepository) MongodbMigrator {
+	return MongodbMigrator{repository: repository, migrations: migrations}
+}
+
+// Status lists every migration known, telling the ones already applied.
+func (m MongodbMigrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	cursor, err := m.getMigrationsCollection().Find(ctx, bson.D{})
+	if err != nil {
+		log.Error("error while fetching applied migrations", logger.FieldError(err))
+
+		return nil, err
+	}
+
+	applied := make([]migrationInfo, 0)
+	if err := cursor.All(ctx, &applied); err != nil {
+		log.Error("error while decoding applied migrations", logger.FieldError(err))
+
+		return nil, err
+	}
+	appliedAt := make(map[int]time.Time, len(applied))
+	for _, info := range applied {
+		appliedAt[info.Version] = info.AppliedAt
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		at, found := appliedAt[migration.Version]
+		statuses = append(statuses, MigrationStatus{Migration: migration, Applied: found, AppliedAt: at})
+	}
+
+	return statuses, nil
+}
+
+// Up applies the migrations pending in order, returning the ones applied. The dry run only returns
+// the ones that would be applied.
+func (m MongodbMigrator) Up(ctx context.Context, dryRun bool) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]Migration, 0)
+	for _, status := range statuses {
+		if status.Applied {
+			continue
+		}
+		if dryRun {
+			applied = append(applied, status.Migration)
+
+			continue
+		}
+
+		log.Info("applying migration", zap.Int("version", status.Version))
+		if err := status.up(ctx, m.repository); err != nil {
+			log.Error("error while applying migration", zap.Int("version", status.Version), logger.FieldError(err))
+
+			return applied, err
+		}
+
+		info := migrationInfo{Version: status.Version, Description: status.Description, AppliedAt: time.Now().UTC()}
+		_, err := m.getMigrationsCollection().
+			ReplaceOne(ctx, bson.D{{Key: "_id", Value: info.Version}}, info, options.Replace().SetUpsert(true))
+		if err != nil {
+			log.Error("error while recording migration", logger.FieldError(err))
+
+			return applied, err
+		}
+		applied = append(applied, status.Migration)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the given number of migrations applied, latest first, returning the ones reverted. The
+// dry run only returns the ones that would be reverted.
+func (m MongodbMigrator) Down(ctx context.Context, steps int, dryRun bool) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version > statuses[j].Version
+	})
+
+	reverted := make([]Migration, 0)
+	for _, status := range statuses {
+		if len(reverted) == steps {
+			break
+		}
+		if !status.Applied {
+			continue
+		}
+		if dryRun {
+			reverted = append(reverted, status.Migration)
+
+			continue
+		}
+
+		log.Info("reverting migration", zap.Int("version", status.Version))
+		if err := status.down(ctx, m.repository); err != nil {
+			log.Error("error while reverting migration", zap.Int("version", status.Version), logger.FieldError(err))
+
+			return reverted, err
+		}
+
+		if _, err := m.getMigrationsCollection().DeleteOne(ctx, bson.D{{Key: "_id", Value: status.Version}}); err != nil {
+			log.Error("error while recording migration reverted", logger.FieldError(err))
+
+			return reverted, err
+		}
+		reverted = append(reverted, status.Migration)
+	}
+
+	return reverted, nil
+}
+
+func (m MongodbMigrator) getMigrationsCollection() *mongo.Collection {
+	databaseName := viper.GetString("MONGODB_DATABASE")
+	collectionName := viper.GetString("MONGODB_MIGRATIONS_COLLECTION")
+
+	return m.repository.client.Database(databaseName).Collection(collectionName)
+}
+
+// normalizeCrawls moves the pages and links of the crawls and snapshots embedded in a single document
+// into their own collections, keeping the _id of each document as the id of its crawl. The crawls are
+// moved from the legacy collection when it is not the one configured, unless a newer crawl is there.
+func normalizeCrawls(ctx context.Context, repository CrawlerMongodbRepository) error {
+	embedded := bson.D{{Key: "uris", Value: bson.D{{Key: "$exists", Value: true}}}}
+
+	snapshots := repository.getSnapshotCollection()
+	err := each(ctx, snapshots, embedded, func(info embeddedCrawlInfo) error {
+		normalized, err := repository.replacePagesAndLinks(ctx, info.ID, info.toCrawl())
+		if err != nil {
+			return err
+		}
+		normalized.ID = info.ID
+		_, err = snapshots.ReplaceOne(ctx, bson.D{{Key: "_id", Value: info.ID}}, normalized)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	legacy, crawls := repository.getLegacyCollection(), repository.getCollection()
+
+	return each(ctx, legacy, embedded, func(info embeddedCrawlInfo) error {
+		byID := bson.D{{Key: "_id", Value: info.ID}}
+		if legacy.Name() == crawls.Name() {
+			normalized, err := repository.replacePagesAndLinks(ctx, info.ID, info.toCrawl())
+			if err != nil {
+				return err
+			}
+			normalized.ID = info.ID
+			_, err = crawls.ReplaceOne(ctx, byID, normalized)
+
+			return err
+		}
+
+		filter := bson.D{
+			{Key: "uri", Value: info.URI},
+			{Key: "depth", Value: info.Depth},
+			optionsFilter(crawler.Options{AuthProfile: info.AuthProfile}),
+		}
+		current := crawlInfo{}
+		err := crawls.FindOne(ctx, filter).Decode(&current)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		if err == nil && !current.CreatedAt.Before(info.CreatedAt) {
+			_, err = legacy.DeleteOne(ctx, byID)
+
+			return err
+		}
+
+		normalized, err := repository.replacePagesAndLinks(ctx, info.ID, info.toCrawl())
+		if err != nil {
+			return err
+		}
+		if _, err := crawls.ReplaceOne(ctx, filter, normalized, options.Replace().SetUpsert(true)); err != nil {
+			return err
+		}
+		_, err = legacy.DeleteOne(ctx, byID)
+
+		return err
+	})
+}
+
+// embedCrawls puts the pages and links of the crawls and snapshots back into their documents, moving the
+// crawls to the legacy collection when it is not the one configured, and drops the pages and links.
+func embedCrawls(ctx context.Context, repository CrawlerMongodbRepository) error {
+	normalized := bson.D{{Key: "crawl_id", Value: bson.D{{Key: "$exists", Value: true}}}}
+
+	snapshots := repository.getSnapshotCollection()
+	err := each(ctx, snapshots, normalized, func(info crawlInfo) error {
+		crawl, err := repository.load(ctx, info, info.Depth)
+		if err != nil {
+			return err
+		}
+		embedded := newEmbeddedCrawlInfo(crawl)
+		embedded.ID = info.ID
+		_, err = snapshots.ReplaceOne(ctx, bson.D{{Key: "_id", Value: info.ID}}, embedded)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	legacy, crawls := repository.getLegacyCollection(), repository.getCollection()
+	err = each(ctx, crawls, normalized, func(info crawlInfo) error {
+		crawl, err := repository.load(ctx, info, info.Depth)
+		if err != nil {
+			return err
+		}
+		embedded := newEmbeddedCrawlInfo(crawl)
+		byID := bson.D{{Key: "_id", Value: info.ID}}
+		if legacy.Name() == crawls.Name() {
+			embedded.ID = info.ID
+			_, err = crawls.ReplaceOne(ctx, byID, embedded)
+
+			return err
+		}
+
+		filter := bson.D{{Key: "uri", Value: crawl.URI}, {Key: "depth", Value: crawl.Depth}, optionsFilter(crawl.Options)}
+		if _, err := legacy.ReplaceOne(ctx, filter, embedded, options.Replace().SetUpsert(true)); err != nil {
+			return err
+		}
+		_, err = crawls.DeleteOne(ctx, byID)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := repository.getPagesCollection().Drop(ctx); err != nil {
+		return err
+	}
+
+	return repository.getLinksCollection().Drop(ctx)
+}
+
+// each decodes the documents found one at a time, so the collection is never loaded at once.
+func each[T any](ctx context.Context, collection *mongo.Collection, filter bson.D, fn func(T) error) error {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var info T
+		if err := cursor.Decode(&info); err != nil {
+			return err
+		}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// replacePagesAndLinks writes the pages and links of the crawl, replacing the ones left by a migration
+// interrupted, and returns the metadata to be stored for the crawl.
+func (c CrawlerMongodbRepository) replacePagesAndLinks(
+	ctx context.Context,
+	crawlID primitive.ObjectID,
+	crawl crawler.Crawl,
+) (crawlInfo, error) {
+	byCrawl := bson.D{{Key: "crawl_id", Value: crawlID}}
+	if _, err := c.getPagesCollection().DeleteMany(ctx, byCrawl); err != nil {
+		return crawlInfo{}, err
+	}
+	if _, err := c.getLinksCollection().DeleteMany(ctx, byCrawl); err != nil {
+		return crawlInfo{}, err
+	}
+
+	if err := insertMany(ctx, c.getPagesCollection(), newCrawlPageInfos(crawl, crawlID)); err != nil {
+		return crawlInfo{}, err
+	}
+	if err := insertMany(ctx, c.getLinksCollection(), newLinkInfos(crawl, crawlID)); err != nil {
+		return crawlInfo{}, err
+	}
+
+	return newCrawlInfo(crawl, crawlID), nil
+}
+
+func (c CrawlerMongodbRepository) getLegacyCollection() *mongo.Collection {
+	databaseName := viper.GetString("MONGODB_DATABASE")
+	collectionName := viper.GetString("MONGODB_LEGACY_COLLECTION")
+
+	return c.client.Database(databaseName).Collection(collectionName)
+}
+
+func newEmbeddedCrawlInfo(crawl crawler.Crawl) embeddedCrawlInfo {
+	pages := make([]pageInfo, 0, len(crawl.Pages))
+	for _, page := range crawl.Pages {
+		pages = append(pages, newPageInfo(page))
+	}
+
+	certificates := make([]certificateInfo, 0, len(crawl.Certificates))
+	for _, certificate := range crawl.Certificates {
+		certificates = append(certificates, certificateInfo(certificate))
+	}
+
+	var linkHops []linkHopInfo
+	if crawl.Hops != nil {
+		linkHops = make([]linkHopInfo, 0, len(crawl.Links))
+		for _, link := range crawl.Links {
+			linkHops = append(linkHops, linkHopInfo{URI: link, Hop: crawl.Hops[link]})
+		}
+	}
+
+	return embeddedCrawlInfo{
+		URI:          crawl.URI,
+		Depth:        crawl.Depth,
+		AuthProfile:  crawl.Options.AuthProfile,
+		CreatedAt:    crawl.CrawledAt,
+		URIs:         crawl.Links,
+		LinkHops:     linkHops,
+		Pages:        pages,
+		Certificates: certificates,
+	}
+}
+
+func (e embeddedCrawlInfo) toCrawl() crawler.Crawl {
+	crawl := crawler.Crawl{
+		URI:       e.URI,
+		Depth:     e.Depth,
+		Options:   crawler.Options{AuthProfile: e.AuthProfile},
+		CrawledAt: e.CreatedAt,
+		Links:     e.URIs,
+	}
+
+	if e.LinkHops != nil {
+		crawl.Hops = make(map[string]uint, len(e.LinkHops))
+		for _, linkHop := range e.LinkHops {
+			crawl.Hops[linkHop.URI] = linkHop.Hop
+		}
+	}
+	for _, page := range e.Pages {
+		crawl.Pages = append(crawl.Pages, page.toPage())
+	}
+	for _, certificate := range e.Certificates {
+		crawl.Certificates = append(crawl.Certificates, pager.Certificate(certificate))
+	}
+
+	return crawl
+}
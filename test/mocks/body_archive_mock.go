@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type BodyArchiveMock struct {
+	mock.Mock
+}
+
+func (b *BodyArchiveMock) Store(ctx context.Context, digest string, body []byte) error {
+	args := b.Called(ctx, digest, body)
+
+	return args.Error(0)
+}
+
+func (b *BodyArchiveMock) Load(ctx context.Context, digest string) ([]byte, error) {
+	args := b.Called(ctx, digest)
+
+	body, _ := args.Get(0).([]byte)
+
+	return body, args.Error(1)
+}
@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -10,14 +11,44 @@ type CrawlerDatabaseMock struct {
 	mock.Mock
 }
 
-func (c *CrawlerDatabaseMock) Insert(ctx context.Context, uri string, depth uint, uris []string) error {
-	args := c.Called(ctx, uri, depth, uris)
+func (c *CrawlerDatabaseMock) Insert(ctx context.Context, crawl crawler.Crawl) error {
+	args := c.Called(ctx, crawl)
 
 	return args.Error(0)
 }
 
-func (c *CrawlerDatabaseMock) Find(ctx context.Context, uri string, depth uint) ([]string, error) {
-	args := c.Called(ctx, uri, depth)
+func (c *CrawlerDatabaseMock) Find(ctx context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, error) {
+	args := c.Called(ctx, uri, depth, opts)
 
-	return args.Get(0).([]string), args.Error(1)
+	return args.Get(0).(crawler.Crawl), args.Error(1)
+}
+
+func (c *CrawlerDatabaseMock) FindShallower(ctx context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, error) {
+	args := c.Called(ctx, uri, depth, opts)
+
+	return args.Get(0).(crawler.Crawl), args.Error(1)
+}
+
+func (c *CrawlerDatabaseMock) FindPage(ctx context.Context, uri string, opts crawler.Options) (crawler.Page, error) {
+	args := c.Called(ctx, uri, opts)
+
+	return args.Get(0).(crawler.Page), args.Error(1)
+}
+
+func (c *CrawlerDatabaseMock) InsertDiff(ctx context.Context, diff crawler.CrawlDiff) error {
+	args := c.Called(ctx, diff)
+
+	return args.Error(0)
+}
+
+func (c *CrawlerDatabaseMock) History(ctx context.Context, uri string, opts crawler.Options) ([]crawler.Snapshot, error) {
+	args := c.Called(ctx, uri, opts)
+
+	return args.Get(0).([]crawler.Snapshot), args.Error(1)
+}
+
+func (c *CrawlerDatabaseMock) FindSnapshot(ctx context.Context, id string) (crawler.Crawl, error) {
+	args := c.Called(ctx, id)
+
+	return args.Get(0).(crawler.Crawl), args.Error(1)
 }
@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/crawler"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -10,8 +11,44 @@ type CrawlerUsecaseMock struct {
 	mock.Mock
 }
 
-func (c *CrawlerUsecaseMock) Craw(ctx context.Context, uri string, depth uint) ([]string, error) {
-	args := c.Called(ctx, uri, depth)
+func (c *CrawlerUsecaseMock) Craw(ctx context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, error) {
+	args := c.Called(ctx, uri, depth, opts)
 
-	return args.Get(0).([]string), args.Error(1)
+	return args.Get(0).(crawler.Crawl), args.Error(1)
+}
+
+func (c *CrawlerUsecaseMock) Refresh(ctx context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, error) {
+	args := c.Called(ctx, uri, depth, opts)
+
+	return args.Get(0).(crawler.Crawl), args.Error(1)
+}
+
+func (c *CrawlerUsecaseMock) Recraw(ctx context.Context, uri string, depth uint, opts crawler.Options) (crawler.Crawl, crawler.CrawlDiff, error) {
+	args := c.Called(ctx, uri, depth, opts)
+
+	return args.Get(0).(crawler.Crawl), args.Get(1).(crawler.CrawlDiff), args.Error(2)
+}
+
+func (c *CrawlerUsecaseMock) History(ctx context.Context, uri string, opts crawler.Options) ([]crawler.Snapshot, error) {
+	args := c.Called(ctx, uri, opts)
+
+	return args.Get(0).([]crawler.Snapshot), args.Error(1)
+}
+
+func (c *CrawlerUsecaseMock) Snapshot(ctx context.Context, id string) (crawler.Crawl, error) {
+	args := c.Called(ctx, id)
+
+	return args.Get(0).(crawler.Crawl), args.Error(1)
+}
+
+func (c *CrawlerUsecaseMock) Reprocess(ctx context.Context, id string) (crawler.Crawl, error) {
+	args := c.Called(ctx, id)
+
+	return args.Get(0).(crawler.Crawl), args.Error(1)
+}
+
+func (c *CrawlerUsecaseMock) Search(ctx context.Context, id, query string) ([]crawler.SearchResult, error) {
+	args := c.Called(ctx, id, query)
+
+	return args.Get(0).([]crawler.SearchResult), args.Error(1)
 }
@@ -1,16 +1,31 @@
 package mocks
 
 import (
+	"context"
+
+	"github.com/hiago-balbino/web-crawler/v2/internal/core/pager"
 	"github.com/stretchr/testify/mock"
-	"golang.org/x/net/html"
 )
 
 type PagerUsecaseMock struct {
 	mock.Mock
 }
 
-func (p *PagerUsecaseMock) GetNode(uri string) (*html.Node, error) {
-	args := p.Called(uri)
+func (p *PagerUsecaseMock) GetPage(ctx context.Context, uri string) (pager.Page, error) {
+	args := p.Called(ctx, uri)
+
+	return args.Get(0).(pager.Page), args.Error(1)
+}
+
+func (p *PagerUsecaseMock) GetPageIfModified(ctx context.Context, uri string, validators pager.Validators) (pager.Page, error) {
+	args := p.Called(ctx, uri, validators)
+
+	return args.Get(0).(pager.Page), args.Error(1)
+}
+
+func (p *PagerUsecaseMock) Authenticate(ctx context.Context, profileName string) (pager.PagerUsecase, error) {
+	args := p.Called(ctx, profileName)
+	authenticated, _ := args.Get(0).(pager.PagerUsecase)
 
-	return args.Get(0).(*html.Node), args.Error(1)
+	return authenticated, args.Error(1)
 }